@@ -0,0 +1,271 @@
+package blocktime
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
+	"code.vegaprotocol.io/vegatools/eventfile"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// intervalBuckets are the upper bounds, in milliseconds, of the buckets of the block interval histogram.
+var intervalBuckets = []int64{250, 500, 750, 1000, 1500, 2000, 3000, 5000, 10000}
+
+// block is the summary of one block.
+type block struct {
+	height   uint64
+	vegaTime time.Time
+	// interval is the vega time until the next block
+	interval time.Duration
+	events   uint64
+	bytes    uint64
+	types    map[eventspb.BusEventType]uint64
+}
+
+// topTypes returns the n event types with the most events in the block, most first.
+func (b *block) topTypes(n int) string {
+	types := make([]eventspb.BusEventType, 0, len(b.types))
+	for t := range b.types {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if b.types[types[i]] != b.types[types[j]] {
+			return b.types[types[i]] > b.types[types[j]]
+		}
+		return types[i] < types[j]
+	})
+	if len(types) > n {
+		types = types[:n]
+	}
+
+	parts := make([]string, 0, len(types))
+	for _, t := range types {
+		parts = append(parts, fmt.Sprintf("%s %d", strings.TrimPrefix(t.String(), "BUS_EVENT_TYPE_"), b.types[t]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// analyser splits the events it is given into blocks and keeps the interval, event count and size of every block,
+// along with the slowest blocks.
+type analyser struct {
+	mu        sync.Mutex
+	slowest   int
+	current   *block
+	last      *block
+	first     uint64
+	intervals []int64
+	events    []uint64
+	bytes     []uint64
+	histogram []uint64
+	outliers  []*block
+
+	// done is called with every complete block
+	done func(b *block)
+}
+
+func newAnalyser(slowest int, done func(b *block)) *analyser {
+	return &analyser{
+		slowest:   slowest,
+		histogram: make([]uint64, len(intervalBuckets)+1),
+		done:      done,
+	}
+}
+
+// add adds the next event of the stream.
+func (a *analyser) add(e *eventspb.BusEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	height, ok := eventfile.BlockHeight(e)
+	if !ok {
+		return
+	}
+	if a.current != nil && a.current.height != height {
+		// the END_BLOCK was missed, e.g. because the stream was reconnected
+		a.finish()
+	}
+	if a.current == nil {
+		a.current = &block{height: height, types: map[eventspb.BusEventType]uint64{}}
+	}
+
+	b := a.current
+	b.events++
+	b.bytes += uint64(proto.Size(e))
+	b.types[e.Type]++
+	if tu := e.GetTimeUpdate(); tu != nil {
+		b.vegaTime = time.Unix(0, tu.Timestamp)
+	}
+	if e.Type == eventspb.BusEventType_BUS_EVENT_TYPE_END_BLOCK {
+		a.finish()
+	}
+}
+
+// finish records the current block. The interval of a block is the vega time until the next block, the time the block
+// took, so the previous block is only complete, and passed to done, once the current one ends. The last block and
+// blocks followed by a gap have no interval.
+func (a *analyser) finish() {
+	b := a.current
+	a.current = nil
+
+	if last := a.last; last != nil {
+		if last.height+1 == b.height && !last.vegaTime.IsZero() && !b.vegaTime.IsZero() {
+			last.interval = b.vegaTime.Sub(last.vegaTime)
+			ms := last.interval.Milliseconds()
+			a.intervals = append(a.intervals, ms)
+			a.histogram[sort.Search(len(intervalBuckets), func(i int) bool { return intervalBuckets[i] >= ms })]++
+			a.outlier(last)
+		}
+		a.report(last)
+	}
+	if len(a.events) == 0 {
+		a.first = b.height
+	}
+	a.events = append(a.events, b.events)
+	a.bytes = append(a.bytes, b.bytes)
+	a.last = b
+}
+
+// close passes the last block to done once there are no more events.
+func (a *analyser) close() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.last != nil {
+		a.report(a.last)
+	}
+}
+
+func (a *analyser) report(b *block) {
+	if a.done != nil {
+		a.done(b)
+	}
+}
+
+// outlier keeps b if it is one of the slowest blocks seen.
+func (a *analyser) outlier(b *block) {
+	if a.slowest <= 0 {
+		return
+	}
+	i := sort.Search(len(a.outliers), func(i int) bool { return a.outliers[i].interval < b.interval })
+	if i >= a.slowest {
+		return
+	}
+	a.outliers = append(a.outliers, nil)
+	copy(a.outliers[i+1:], a.outliers[i:])
+	a.outliers[i] = b
+	if len(a.outliers) > a.slowest {
+		a.outliers = a.outliers[:a.slowest]
+	}
+}
+
+// Distribution is the spread of a value over all blocks.
+type Distribution struct {
+	Min uint64  `json:"min"`
+	P50 uint64  `json:"p50"`
+	P95 uint64  `json:"p95"`
+	P99 uint64  `json:"p99"`
+	Max uint64  `json:"max"`
+	Avg float64 `json:"avg"`
+}
+
+func newDistribution(values []uint64) Distribution {
+	if len(values) == 0 {
+		return Distribution{}
+	}
+	sorted := append([]uint64{}, values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	at := func(p float64) uint64 {
+		return sorted[int(p*float64(len(sorted)-1)+0.5)]
+	}
+
+	var total float64
+	for _, v := range sorted {
+		total += float64(v)
+	}
+	return Distribution{
+		Min: sorted[0],
+		P50: at(0.50),
+		P95: at(0.95),
+		P99: at(0.99),
+		Max: sorted[len(sorted)-1],
+		Avg: total / float64(len(sorted)),
+	}
+}
+
+func (a *analyser) summary() SummaryResult {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	intervals := make([]uint64, 0, len(a.intervals))
+	for _, ms := range a.intervals {
+		if ms < 0 {
+			ms = 0
+		}
+		intervals = append(intervals, uint64(ms))
+	}
+
+	s := SummaryResult{
+		Blocks:     uint64(len(a.events)),
+		IntervalMs: newDistribution(intervals),
+		Events:     newDistribution(a.events),
+		Bytes:      newDistribution(a.bytes),
+	}
+	if a.last != nil {
+		s.FromBlock, s.ToBlock = a.first, a.last.height
+	}
+	for i, count := range a.histogram {
+		upTo := int64(-1)
+		if i < len(intervalBuckets) {
+			upTo = intervalBuckets[i]
+		}
+		s.Histogram = append(s.Histogram, HistogramBucket{UpToMs: upTo, Blocks: count})
+	}
+	for _, b := range a.outliers {
+		s.Slowest = append(s.Slowest, newBlockResult(b))
+	}
+	return s
+}
+
+func printSummary(w io.Writer, s SummaryResult) {
+	fmt.Fprintf(w, "blocks %d-%d (%d blocks)\n", s.FromBlock, s.ToBlock, s.Blocks)
+	printDistribution(w, "interval (ms)", s.IntervalMs)
+	printDistribution(w, "events/block", s.Events)
+	printDistribution(w, "bytes/block", s.Bytes)
+
+	var intervals uint64
+	for _, h := range s.Histogram {
+		intervals += h.Blocks
+	}
+	if intervals > 0 {
+		fmt.Fprintln(w, "interval histogram")
+		for _, h := range s.Histogram {
+			label := fmt.Sprintf(">%dms", intervalBuckets[len(intervalBuckets)-1])
+			if h.UpToMs >= 0 {
+				label = fmt.Sprintf("<=%dms", h.UpToMs)
+			}
+			fmt.Fprintf(w, "  %8s %8d %s\n", label, h.Blocks, strings.Repeat("#", int(h.Blocks*50/intervals)))
+		}
+	}
+
+	if len(s.Slowest) > 0 {
+		fmt.Fprintln(w, "slowest blocks")
+		for _, b := range s.Slowest {
+			printBlock(w, "  ", b)
+		}
+	}
+}
+
+func printDistribution(w io.Writer, name string, d Distribution) {
+	fmt.Fprintf(w, "%-14s min:%d p50:%d p95:%d p99:%d max:%d avg:%.1f\n", name, d.Min, d.P50, d.P95, d.P99, d.Max, d.Avg)
+}
+
+func printBlock(w io.Writer, prefix string, b BlockResult) {
+	fmt.Fprintf(w, "%sblock %d at %s took %dms, %d events, %d bytes: %s\n", prefix,
+		b.Height, b.VegaTime.Format(time.RFC3339Nano), b.IntervalMs, b.Events, b.Bytes, b.Types)
+}
@@ -0,0 +1,86 @@
+package blocktime
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// addBlock adds the events of a block at height with the vega time at seconds.
+func addBlock(a *analyser, height uint64, seconds float64, orders int) {
+	id := func(seq int) string { return fmt.Sprintf("%d-%d", height, seq) }
+	a.add(&eventspb.BusEvent{Id: id(0), Type: eventspb.BusEventType_BUS_EVENT_TYPE_TIME_UPDATE, Event: &eventspb.BusEvent_TimeUpdate{
+		TimeUpdate: &eventspb.TimeUpdate{Timestamp: int64(seconds * float64(time.Second))},
+	}})
+	for i := 0; i < orders; i++ {
+		a.add(&eventspb.BusEvent{Id: id(1 + i), Type: eventspb.BusEventType_BUS_EVENT_TYPE_ORDER})
+	}
+	a.add(&eventspb.BusEvent{Id: id(1 + orders), Type: eventspb.BusEventType_BUS_EVENT_TYPE_END_BLOCK, Event: &eventspb.BusEvent_EndBlock{
+		EndBlock: &eventspb.EndBlock{Height: height},
+	}})
+}
+
+func TestIntervalIsTheTimeABlockTook(t *testing.T) {
+	done := []BlockResult{}
+	a := newAnalyser(2, func(b *block) { done = append(done, newBlockResult(b)) })
+
+	addBlock(a, 1, 1, 1)
+	addBlock(a, 2, 2, 5)
+	// block 2 took 3 seconds
+	addBlock(a, 3, 5, 1)
+	addBlock(a, 4, 5.5, 1)
+	assert.Len(t, done, 3, "a block is only done once the next one ends")
+	a.close()
+
+	require.Len(t, done, 4)
+	intervals := []int64{}
+	for _, b := range done {
+		intervals = append(intervals, b.IntervalMs)
+	}
+	assert.Equal(t, []int64{1000, 3000, 500, 0}, intervals)
+
+	s := a.summary()
+	assert.Equal(t, uint64(1), s.FromBlock)
+	assert.Equal(t, uint64(4), s.ToBlock)
+	assert.Equal(t, uint64(4), s.Blocks)
+	assert.Equal(t, uint64(500), s.IntervalMs.Min)
+	assert.Equal(t, uint64(3000), s.IntervalMs.Max)
+	assert.Equal(t, uint64(7), s.Events.Max)
+
+	require.Len(t, s.Slowest, 2)
+	assert.Equal(t, uint64(2), s.Slowest[0].Height)
+	assert.Equal(t, int64(3000), s.Slowest[0].IntervalMs)
+	assert.Equal(t, "ORDER 5, TIME_UPDATE 1, END_BLOCK 1", s.Slowest[0].Types)
+	assert.Equal(t, uint64(1), s.Slowest[1].Height)
+
+	var histogram uint64
+	for _, h := range s.Histogram {
+		histogram += h.Blocks
+		if h.UpToMs == 500 || h.UpToMs == 1000 || h.UpToMs == 3000 {
+			assert.Equal(t, uint64(1), h.Blocks, "bucket %d", h.UpToMs)
+		}
+	}
+	assert.Equal(t, uint64(3), histogram)
+}
+
+func TestNoIntervalAcrossGaps(t *testing.T) {
+	a := newAnalyser(5, nil)
+	addBlock(a, 1, 1, 0)
+	// block 4 starts without the END_BLOCK of block 3, as after a reconnect
+	a.add(&eventspb.BusEvent{Id: "3-0", Type: eventspb.BusEventType_BUS_EVENT_TYPE_TIME_UPDATE, Event: &eventspb.BusEvent_TimeUpdate{
+		TimeUpdate: &eventspb.TimeUpdate{Timestamp: int64(10 * time.Second)},
+	}})
+	addBlock(a, 4, 12, 0)
+	a.close()
+
+	s := a.summary()
+	assert.Equal(t, uint64(3), s.Blocks)
+	require.Len(t, s.Slowest, 1)
+	assert.Equal(t, uint64(3), s.Slowest[0].Height)
+	assert.Equal(t, int64(2000), s.Slowest[0].IntervalMs)
+}
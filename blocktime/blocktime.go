@@ -0,0 +1,172 @@
+package blocktime
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
+	"code.vegaprotocol.io/vega/vegatools/stream"
+	"code.vegaprotocol.io/vegatools/eventfile"
+	"code.vegaprotocol.io/vegatools/output"
+)
+
+// Opts are the command line options passed to the sub command
+type Opts struct {
+	ServerAddr string
+	Reconnect  bool
+	File       string
+	FromBlock  uint64
+	ToBlock    uint64
+	// Slow is the interval above which a block is reported as it is seen, 0 disables it
+	Slow time.Duration
+	// Slowest is the number of slowest blocks listed in the summary
+	Slowest int
+	// Output is the format results are written in, text, json or csv
+	Output string
+}
+
+// BlockResult is written for every block with --output json or csv, and for the slow blocks in the summary.
+type BlockResult struct {
+	Height     uint64    `json:"height"`
+	VegaTime   time.Time `json:"vega_time"`
+	IntervalMs int64     `json:"interval_ms"`
+	Events     uint64    `json:"events"`
+	Bytes      uint64    `json:"bytes"`
+	Types      string    `json:"types"`
+}
+
+// SummaryResult is written once the stream or file ends. The distributions and histogram are only written as JSON.
+type SummaryResult struct {
+	FromBlock  uint64            `json:"from_block"`
+	ToBlock    uint64            `json:"to_block"`
+	Blocks     uint64            `json:"blocks"`
+	IntervalMs Distribution      `json:"interval_ms"`
+	Events     Distribution      `json:"events"`
+	Bytes      Distribution      `json:"bytes"`
+	Histogram  []HistogramBucket `json:"histogram"`
+	Slowest    []BlockResult     `json:"slowest,omitempty"`
+}
+
+// HistogramBucket is the number of blocks with an interval of at most UpToMs, or above the last bucket if UpToMs
+// is -1.
+type HistogramBucket struct {
+	UpToMs int64  `json:"up_to_ms"`
+	Blocks uint64 `json:"blocks"`
+}
+
+// topTypes is the number of event types listed for a block.
+const topTypes = 5
+
+func newBlockResult(b *block) BlockResult {
+	return BlockResult{
+		Height:     b.height,
+		VegaTime:   b.vegaTime,
+		IntervalMs: b.interval.Milliseconds(),
+		Events:     b.events,
+		Bytes:      b.bytes,
+		Types:      b.topTypes(topTypes),
+	}
+}
+
+// Run is the main function of `blocktime` package
+func Run(opts Opts) error {
+	if len(opts.ServerAddr) == 0 && len(opts.File) == 0 {
+		return fmt.Errorf("error: one of a grpc server address or an events file is required")
+	}
+	if len(opts.ServerAddr) > 0 && len(opts.File) > 0 {
+		return fmt.Errorf("error: only one of a grpc server address or an events file can be given")
+	}
+
+	out, err := output.NewWriter(os.Stdout, opts.Output)
+	if err != nil {
+		return err
+	}
+
+	var writeErr error
+	a := newAnalyser(opts.Slowest, func(b *block) {
+		switch {
+		case out != nil:
+			if err := out.Write(newBlockResult(b)); err != nil && writeErr == nil {
+				writeErr = fmt.Errorf("failed to write result: %w", err)
+			}
+		case opts.Slow > 0 && b.interval > opts.Slow:
+			printBlock(os.Stdout, "slow ", newBlockResult(b))
+		}
+	})
+
+	if len(opts.File) > 0 {
+		err = readFile(opts, a)
+	} else {
+		err = readStream(opts, a)
+	}
+	if err != nil {
+		return err
+	}
+	a.close()
+	if writeErr != nil {
+		return writeErr
+	}
+
+	summary := a.summary()
+	if out != nil {
+		return out.Write(summary)
+	}
+	printSummary(os.Stdout, summary)
+	return nil
+}
+
+func readFile(opts Opts, a *analyser) error {
+	filter := eventfile.Filter{FromHeight: opts.FromBlock, ToHeight: opts.ToBlock}
+
+	in, err := eventfile.Open(opts.File)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if opts.FromBlock > 0 {
+		if err := in.SeekHeight(opts.FromBlock); err != nil {
+			if err == io.EOF {
+				return fmt.Errorf("block %d not found in %s", opts.FromBlock, opts.File)
+			}
+			return err
+		}
+	}
+
+	for {
+		e, err := in.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if filter.PastEnd(e) {
+			return nil
+		}
+		if filter.Match(e) {
+			a.add(e)
+		}
+	}
+}
+
+// readStream analyses the unfiltered event bus stream, as the events of every type are needed to size the blocks,
+// until interrupted.
+func readStream(opts Opts, a *analyser) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	wg := sync.WaitGroup{}
+	handleEvent := func(e *eventspb.BusEvent) { a.add(e) }
+	if err := stream.ReadEvents(ctx, cancel, &wg, 0, "", "", opts.ServerAddr, handleEvent, opts.Reconnect, nil); err != nil {
+		return fmt.Errorf("error reading events: %v", err)
+	}
+
+	stream.WaitSig(ctx, cancel)
+	wg.Wait()
+	return nil
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"code.vegaprotocol.io/vegatools/blocktime"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	blockTimeOpts blocktime.Opts
+	blockTimeCmd  = &cobra.Command{
+		Use:   "blocktime",
+		Short: "Analyse the interval, number of events and size of blocks from the event bus or an events file",
+		RunE:  runBlockTime,
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(blockTimeCmd)
+	blockTimeCmd.Flags().StringVarP(&blockTimeOpts.ServerAddr, "address", "a", "", "address of the grpc server")
+	blockTimeCmd.Flags().BoolVarP(&blockTimeOpts.Reconnect, "reconnect", "r", false, "if connection dies, attempt to reconnect")
+	blockTimeCmd.Flags().StringVarP(&blockTimeOpts.File, "file", "f", "", "events file to analyse instead of the event bus")
+	blockTimeCmd.Flags().Uint64Var(&blockTimeOpts.FromBlock, "from-block", 0, "first block of the file to analyse")
+	blockTimeCmd.Flags().Uint64Var(&blockTimeOpts.ToBlock, "to-block", 0, "last block of the file to analyse")
+	blockTimeCmd.Flags().DurationVar(&blockTimeOpts.Slow, "slow", 0, "print every block that takes longer than this (e.g. 2s)")
+	blockTimeCmd.Flags().IntVar(&blockTimeOpts.Slowest, "slowest", 10, "number of slowest blocks to list in the summary")
+	addOutputFlag(blockTimeCmd, &blockTimeOpts.Output)
+}
+
+func runBlockTime(cmd *cobra.Command, args []string) error {
+	return blocktime.Run(blockTimeOpts)
+}
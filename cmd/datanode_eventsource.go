@@ -1,9 +1,11 @@
 package cmd
 
 import (
+	"fmt"
+	"time"
+
 	"code.vegaprotocol.io/vegatools/eventsource"
 	"github.com/spf13/cobra"
-	"time"
 )
 
 var (
@@ -13,6 +15,18 @@ var (
 		intervalBetweenBlocks uint
 		closeConnection       bool
 		logFormat             string
+		fromBlock             uint64
+		toBlock               uint64
+		fromTime              string
+		toTime                string
+		realTime              bool
+		speed                 float64
+		paused                bool
+		controlStdin          bool
+		controlListen         string
+		batchSize             int
+		flushOnBlock          bool
+		reportInterval        time.Duration
 	}
 
 	dataNodeEventSourceCmd = &cobra.Command{
@@ -24,14 +38,63 @@ var (
 
 func init() {
 	rootCmd.AddCommand(dataNodeEventSourceCmd)
-	dataNodeEventSourceCmd.Flags().StringVarP(&dataNodeEventSourceOpts.file, "file", "f", "vega.evt", "name of the file to read events from")
+	dataNodeEventSourceCmd.Flags().StringVarP(&dataNodeEventSourceOpts.file, "file", "f", "vega.evt", "name of the file, or directory of rotated files, to read events from")
 	dataNodeEventSourceCmd.Flags().UintVarP(&dataNodeEventSourceOpts.port, "port", "p", 3005, "the datanode's listening port ")
 	dataNodeEventSourceCmd.Flags().UintVarP(&dataNodeEventSourceOpts.intervalBetweenBlocks, "intervalBetweenBlocks", "i", 1000, "the time interval in milli secs between events being published for each block")
 	dataNodeEventSourceCmd.Flags().BoolVarP(&dataNodeEventSourceOpts.closeConnection, "closeConnection", "c", false, "close the connection after all events are sent")
-	dataNodeEventSourceCmd.Flags().StringVar(&dataNodeEventSourceOpts.logFormat, "log-format", "raw", "console data logged in specified format. Allowed values: raw (default), text, json")
+	dataNodeEventSourceCmd.Flags().StringVar(&dataNodeEventSourceOpts.logFormat, "log-format", "raw", "console data logged in specified format. Allowed values: raw (default), text, json, none")
+	dataNodeEventSourceCmd.Flags().Uint64Var(&dataNodeEventSourceOpts.fromBlock, "from-block", 0, "first block height to send")
+	dataNodeEventSourceCmd.Flags().Uint64Var(&dataNodeEventSourceOpts.toBlock, "to-block", 0, "last block height to send")
+	dataNodeEventSourceCmd.Flags().StringVar(&dataNodeEventSourceOpts.fromTime, "from-time", "", "send blocks from this vega time onwards (RFC3339)")
+	dataNodeEventSourceCmd.Flags().StringVar(&dataNodeEventSourceOpts.toTime, "to-time", "", "send blocks up to this vega time (RFC3339)")
+	dataNodeEventSourceCmd.Flags().IntVarP(&dataNodeEventSourceOpts.batchSize, "batch-size", "b", 1, "number of events sent to the data node in each batch")
+	dataNodeEventSourceCmd.Flags().BoolVar(&dataNodeEventSourceOpts.flushOnBlock, "flush-on-block", true, "send the pending batch at the end of every block so that batches never span blocks")
+	dataNodeEventSourceCmd.Flags().DurationVar(&dataNodeEventSourceOpts.reportInterval, "report-interval", 10*time.Second, "how often to report the send throughput, 0 to only report it at the end")
+	dataNodeEventSourceCmd.Flags().BoolVar(&dataNodeEventSourceOpts.realTime, "realtime", false, "follow the original block timing from the TIME_UPDATE events instead of a fixed interval")
+	dataNodeEventSourceCmd.Flags().Float64Var(&dataNodeEventSourceOpts.speed, "speed", 1, "speed multiplier for realtime replay, e.g. 10 or 0.5, 0 sends blocks as fast as possible")
+	dataNodeEventSourceCmd.Flags().BoolVar(&dataNodeEventSourceOpts.paused, "paused", false, "start the replay paused")
+	dataNodeEventSourceCmd.Flags().BoolVar(&dataNodeEventSourceOpts.controlStdin, "control-stdin", false, "read pause, resume, step and speed <multiplier> commands from stdin")
+	dataNodeEventSourceCmd.Flags().StringVar(&dataNodeEventSourceOpts.controlListen, "control-listen", "", "address of an HTTP endpoint to control the replay, e.g. localhost:8090")
 }
 
 func runDatanodeEventSource(cmd *cobra.Command, args []string) error {
+	eventRange := eventsource.Range{
+		FromHeight: dataNodeEventSourceOpts.fromBlock,
+		ToHeight:   dataNodeEventSourceOpts.toBlock,
+	}
+
+	var err error
+	if len(dataNodeEventSourceOpts.fromTime) > 0 {
+		if eventRange.FromTime, err = time.Parse(time.RFC3339, dataNodeEventSourceOpts.fromTime); err != nil {
+			return fmt.Errorf("invalid from-time: %w", err)
+		}
+	}
+	if len(dataNodeEventSourceOpts.toTime) > 0 {
+		if eventRange.ToTime, err = time.Parse(time.RFC3339, dataNodeEventSourceOpts.toTime); err != nil {
+			return fmt.Errorf("invalid to-time: %w", err)
+		}
+	}
+
+	if dataNodeEventSourceOpts.batchSize < 0 {
+		return fmt.Errorf("batch-size must not be negative")
+	}
+	if dataNodeEventSourceOpts.speed < 0 {
+		return fmt.Errorf("speed must not be negative")
+	}
+	pacing := eventsource.Pacing{
+		TimeBetweenBlocks: time.Duration(dataNodeEventSourceOpts.intervalBetweenBlocks) * time.Millisecond,
+		RealTime:          dataNodeEventSourceOpts.realTime,
+		Speed:             dataNodeEventSourceOpts.speed,
+		Paused:            dataNodeEventSourceOpts.paused,
+		ControlStdin:      dataNodeEventSourceOpts.controlStdin,
+		ControlListen:     dataNodeEventSourceOpts.controlListen,
+	}
+
+	batching := eventsource.Batching{
+		Size:         dataNodeEventSourceOpts.batchSize,
+		FlushOnBlock: dataNodeEventSourceOpts.flushOnBlock,
+	}
+
 	return eventsource.RunDatanodeEventSource(dataNodeEventSourceOpts.file, dataNodeEventSourceOpts.port, dataNodeEventSourceOpts.closeConnection,
-		time.Duration(dataNodeEventSourceOpts.intervalBetweenBlocks)*time.Millisecond, dataNodeEventSourceOpts.logFormat)
+		pacing, batching, dataNodeEventSourceOpts.reportInterval, dataNodeEventSourceOpts.logFormat, eventRange)
 }
@@ -1,8 +1,12 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"code.vegaprotocol.io/vegatools/difftool/diff"
 	"github.com/spf13/cobra"
@@ -10,28 +14,119 @@ import (
 
 var (
 	diffToolOpts struct {
-		snapshotDatabasePath string
-		heightToOutput       int64
-		datanode             string
+		snapshotDatabasePath        string
+		heightToOutput              int64
+		datanode                    string
+		compareSnapshotDatabasePath string
+		compareHeight               int64
+		report                      string
+		reportFile                  string
+		knownDifferences            string
+		only                        []string
+		skip                        []string
+		datanodeConcurrency         int
+		datanodeTimeout             time.Duration
+		datanodeRetries             int
+		waitForHeight               time.Duration
+		failOnStale                 bool
+		watch                       bool
+		watchInterval               time.Duration
+		workDir                     string
+		keepSnapshots               int
+		historyFile                 string
+		webhook                     string
+		exitOnDivergence            bool
 	}
 
 	diffToolCmd = &cobra.Command{
 		Use:   "difftool",
-		Short: "Compare the state of a core snapshot with datanode API",
+		Short: "Compare the state of a core snapshot with datanode API or with another core snapshot",
 		RunE:  runDiffToolCmd,
 	}
+
+	diffToolDomainsCmd = &cobra.Command{
+		Use:   "domains",
+		Short: "List the domains that can be compared with --only and --skip",
+		Run: func(cmd *cobra.Command, args []string) {
+			for _, name := range diff.DomainNames() {
+				fmt.Println(name)
+			}
+		},
+	}
 )
 
 func init() {
 	rootCmd.AddCommand(diffToolCmd)
+	diffToolCmd.AddCommand(diffToolDomainsCmd)
 	diffToolCmd.Flags().StringVarP(&diffToolOpts.snapshotDatabasePath, "snap-db-path", "s", "", "path to the goleveldb database folder")
 	diffToolCmd.Flags().Int64VarP(&diffToolOpts.heightToOutput, "block-height", "r", 0, "block-height of the snapshot to dump")
 	diffToolCmd.Flags().StringVarP(&diffToolOpts.datanode, "datanode", "d", "", "datanode url")
+	diffToolCmd.Flags().StringVar(&diffToolOpts.compareSnapshotDatabasePath, "compare-snap-db-path", "", "path to the goleveldb database folder of a second snapshot to compare with, defaults to --snap-db-path")
+	diffToolCmd.Flags().Int64Var(&diffToolOpts.compareHeight, "compare-block-height", 0, "block-height of the second snapshot to compare with")
+	diffToolCmd.Flags().StringVar(&diffToolOpts.report, "report", diff.FormatText, "format of the report, text, json or junit")
+	diffToolCmd.Flags().StringVar(&diffToolOpts.reportFile, "report-file", "", "file the json or junit report is written to, defaults to stdout")
+	diffToolCmd.Flags().StringVar(&diffToolOpts.knownDifferences, "known-differences", "", "JSON file of the differences accepted per key, which do not fail the run")
+	diffToolCmd.Flags().StringSliceVar(&diffToolOpts.only, "only", nil, "comma separated domains to compare, all if not given (see difftool domains)")
+	diffToolCmd.Flags().StringSliceVar(&diffToolOpts.skip, "skip", nil, "comma separated domains not to compare")
+	diffToolCmd.Flags().IntVar(&diffToolOpts.datanodeConcurrency, "datanode-concurrency", 4, "number of datanode calls in flight at once")
+	diffToolCmd.Flags().DurationVar(&diffToolOpts.datanodeTimeout, "datanode-timeout", 30*time.Second, "timeout of each datanode call")
+	diffToolCmd.Flags().IntVar(&diffToolOpts.datanodeRetries, "datanode-retries", 5, "number of times a datanode call is retried, with backoff, when rate limited or unavailable")
+	diffToolCmd.Flags().DurationVar(&diffToolOpts.waitForHeight, "wait-for-height", 5*time.Minute, "how long to wait for datanode to reach the height of the snapshot")
+	diffToolCmd.Flags().BoolVar(&diffToolOpts.failOnStale, "fail-on-stale", false, "fail the run on mismatches that are possibly stale as datanode has moved past the snapshot")
+	diffToolCmd.Flags().BoolVar(&diffToolOpts.watch, "watch", false, "keep comparing every new snapshot written by core with datanode")
+	diffToolCmd.Flags().DurationVar(&diffToolOpts.watchInterval, "watch-interval", 10*time.Second, "how often to check for new snapshots with --watch")
+	diffToolCmd.Flags().StringVar(&diffToolOpts.workDir, "work-dir", "", "directory the snapshots compared with --watch are written to, defaults to a difftool directory in the temp directory")
+	diffToolCmd.Flags().IntVar(&diffToolOpts.keepSnapshots, "keep-snapshots", 3, "number of the latest snapshots compared with --watch kept in --work-dir")
+	diffToolCmd.Flags().StringVar(&diffToolOpts.historyFile, "history-file", "", "file the result of every comparison with --watch is appended to as a line of JSON")
+	diffToolCmd.Flags().StringVar(&diffToolOpts.webhook, "webhook", "", "URL the result of a comparison with --watch is posted to as JSON when core and datanode diverge")
+	diffToolCmd.Flags().BoolVar(&diffToolOpts.exitOnDivergence, "exit-on-divergence", false, "stop watching with a non zero exit code when core and datanode diverge")
 	diffToolCmd.MarkFlagRequired("snap-db-path")
-	diffToolCmd.MarkFlagRequired("datanode")
 }
 
 func runDiffToolCmd(cmd *cobra.Command, args []string) error {
+	compare := len(diffToolOpts.compareSnapshotDatabasePath) > 0 || diffToolOpts.compareHeight > 0
+	if compare == (len(diffToolOpts.datanode) > 0) {
+		return errors.New("one of --datanode or a second snapshot to compare with (--compare-snap-db-path, --compare-block-height) is required")
+	}
+
+	opts := diff.Opts{
+		Only:   diffToolOpts.only,
+		Skip:   diffToolOpts.skip,
+		Format: diffToolOpts.report,
+		File:   diffToolOpts.reportFile,
+
+		DatanodeConcurrency: diffToolOpts.datanodeConcurrency,
+		DatanodeTimeout:     diffToolOpts.datanodeTimeout,
+		DatanodeRetries:     diffToolOpts.datanodeRetries,
+		WaitForHeight:       diffToolOpts.waitForHeight,
+		FailOnStale:         diffToolOpts.failOnStale,
+	}
+	if len(diffToolOpts.knownDifferences) > 0 {
+		known, err := diff.LoadKnownDifferences(diffToolOpts.knownDifferences)
+		if err != nil {
+			return err
+		}
+		opts.Known = known
+	}
+
+	if diffToolOpts.watch {
+		if compare {
+			return errors.New("--watch compares with --datanode, not with a second snapshot")
+		}
+		workDir := diffToolOpts.workDir
+		if len(workDir) == 0 {
+			workDir = filepath.Join(os.TempDir(), "difftool")
+		}
+		return diff.Watch(diffToolOpts.snapshotDatabasePath, diffToolOpts.datanode, opts, diff.WatchOpts{
+			Interval:         diffToolOpts.watchInterval,
+			WorkDir:          workDir,
+			Keep:             diffToolOpts.keepSnapshots,
+			HistoryFile:      diffToolOpts.historyFile,
+			Webhook:          diffToolOpts.webhook,
+			ExitOnDivergence: diffToolOpts.exitOnDivergence,
+		})
+	}
+
 	temp := os.TempDir()
 	if !strings.HasSuffix(temp, string(os.PathSeparator)) {
 		temp = temp + string(os.PathSeparator)
@@ -45,5 +140,20 @@ func runDiffToolCmd(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	return diff.Run(snapshotPath, diffToolOpts.datanode)
+	if !compare {
+		return diff.Run(snapshotPath, diffToolOpts.datanode, opts)
+	}
+
+	compareDatabasePath := diffToolOpts.compareSnapshotDatabasePath
+	if len(compareDatabasePath) == 0 {
+		compareDatabasePath = diffToolOpts.snapshotDatabasePath
+	}
+	compareSnapshotPath := temp + "snapshot-compare.dat"
+	err = diff.SnapshotRun(compareDatabasePath, false, compareSnapshotPath, diffToolOpts.compareHeight, "proto")
+	defer os.Remove(compareSnapshotPath)
+	if err != nil {
+		return err
+	}
+
+	return diff.RunSnapshots(snapshotPath, compareSnapshotPath, opts)
 }
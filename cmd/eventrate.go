@@ -23,6 +23,13 @@ func init() {
 	eventRateCmd.Flags().IntVarP(&eventRateOpts.EventCountDump, "eventcountdump", "e", 0, "dump total event count every x seconds")
 	eventRateCmd.Flags().IntVarP(&eventRateOpts.FinalReportRowCount, "finalreport", "f", 0, "generate a report after x number of calculations")
 	eventRateCmd.Flags().BoolVarP(&eventRateOpts.ReportStyle, "reportstyle", "r", false, "print output on a new line")
+	eventRateCmd.Flags().BoolVar(&eventRateOpts.CheckStream, "check-stream", false, "report missing blocks and events, and unpaired BEGIN_BLOCK/END_BLOCK events, in the stream")
+	eventRateCmd.Flags().StringVar(&eventRateOpts.MetricsListen, "metrics-listen", "", "serve Prometheus metrics on this address (e.g. localhost:2112) instead of printing to the terminal")
+	eventRateCmd.Flags().BoolVarP(&eventRateOpts.TypeRates, "by-type", "t", false, "display a table of the events and bytes per second of each event type")
+	eventRateCmd.Flags().IntVar(&eventRateOpts.Top, "top", 0, "only display the event types using the most bandwidth")
+	eventRateCmd.Flags().StringVar(&eventRateOpts.Market, "market", "", "only count events referencing this market in the event type table")
+	eventRateCmd.Flags().StringVar(&eventRateOpts.Party, "party", "", "only count events referencing this party in the event type table")
+	addOutputFlag(eventRateCmd, &eventRateOpts.Output)
 	eventRateCmd.MarkFlagRequired("address")
 }
 
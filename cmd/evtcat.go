@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"code.vegaprotocol.io/vegatools/evtcat"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	evtCatOpts evtcat.Opts
+	evtCatCmd  = &cobra.Command{
+		Use:   "evtcat",
+		Short: "Print the events stored in an events file",
+		RunE:  runEvtCat,
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(evtCatCmd)
+	evtCatCmd.Flags().StringVarP(&evtCatOpts.File, "file", "f", "vega.evt", "name of the file, or directory of rotated files, to read events from")
+	evtCatCmd.Flags().StringVar(&evtCatOpts.Format, "format", "json", "print events in specified format. Allowed values: json, text, raw, none")
+	evtCatCmd.Flags().StringSliceVarP(&evtCatOpts.Types, "type", "t", nil, "one or more event types to print (default=ALL)")
+	evtCatCmd.Flags().StringVarP(&evtCatOpts.Party, "party", "p", "", "only print events referencing this party")
+	evtCatCmd.Flags().StringVarP(&evtCatOpts.Market, "market", "m", "", "only print events referencing this market")
+	evtCatCmd.Flags().Uint64Var(&evtCatOpts.FromBlock, "from-block", 0, "first block height to print")
+	evtCatCmd.Flags().Uint64Var(&evtCatOpts.ToBlock, "to-block", 0, "last block height to print")
+	evtCatCmd.Flags().BoolVarP(&evtCatOpts.Stats, "stats", "s", false, "print the count and total size of each event type")
+}
+
+func runEvtCat(cmd *cobra.Command, args []string) error {
+	return evtcat.Run(evtCatOpts)
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"code.vegaprotocol.io/vegatools/evtexport"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	evtExportOpts evtexport.Opts
+	evtExportCmd  = &cobra.Command{
+		Use:   "evtexport",
+		Short: "Export the events in an events file to one Parquet or CSV table per event type",
+		RunE:  runEvtExport,
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(evtExportCmd)
+	evtExportCmd.Flags().StringVarP(&evtExportOpts.File, "file", "f", "vega.evt", "name of the file, or directory of rotated files, to read events from")
+	evtExportCmd.Flags().StringVarP(&evtExportOpts.OutDir, "out-dir", "o", "", "directory to write the tables to")
+	evtExportCmd.Flags().StringVar(&evtExportOpts.Format, "format", evtexport.FormatParquet, "format of the tables. Allowed values: parquet, csv")
+	evtExportCmd.Flags().StringSliceVarP(&evtExportOpts.Types, "type", "t", nil, "one or more event types to export (default=ALL)")
+	evtExportCmd.Flags().Uint64Var(&evtExportOpts.FromBlock, "from-block", 0, "first block height to export")
+	evtExportCmd.Flags().Uint64Var(&evtExportOpts.ToBlock, "to-block", 0, "last block height to export")
+	evtExportCmd.MarkFlagRequired("out-dir")
+}
+
+func runEvtExport(cmd *cobra.Command, args []string) error {
+	return evtexport.Run(evtExportOpts)
+}
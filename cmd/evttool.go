@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"code.vegaprotocol.io/vegatools/evttool"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	evtToolCmd = &cobra.Command{
+		Use:   "evttool",
+		Short: "Slice, split, merge, validate, verify, repair and transform events files",
+	}
+
+	evtSliceOpts evttool.SliceOpts
+	evtSliceCmd  = &cobra.Command{
+		Use:   "slice",
+		Short: "Copy a range of blocks or a subset of events to a new events file",
+		RunE:  runEvtSlice,
+	}
+
+	evtSplitOpts evttool.SplitOpts
+	evtSplitCmd  = &cobra.Command{
+		Use:   "split",
+		Short: "Split an events file into files of a fixed number of blocks or one file per event type",
+		RunE:  runEvtSplit,
+	}
+
+	evtMergeOpts evttool.MergeOpts
+	evtMergeCmd  = &cobra.Command{
+		Use:   "merge [files...]",
+		Short: "Merge events files in block order, skipping blocks and events already merged",
+		Args:  cobra.MinimumNArgs(1),
+		RunE:  runEvtMerge,
+	}
+
+	evtValidateOpts evttool.ValidateOpts
+	evtValidateCmd  = &cobra.Command{
+		Use:   "validate",
+		Short: "Check an events file holds a contiguous sequence of blocks",
+		RunE:  runEvtValidate,
+	}
+
+	evtVerifyOpts evttool.VerifyOpts
+	evtVerifyCmd  = &cobra.Command{
+		Use:   "verify",
+		Short: "Check the checksum of every record in an events file and report corrupt or missing data",
+		RunE:  runEvtVerify,
+	}
+
+	evtRepairOpts evttool.RepairOpts
+	evtRepairCmd  = &cobra.Command{
+		Use:   "repair",
+		Short: "Remove corrupt and partially written records from an events file",
+		RunE:  runEvtRepair,
+	}
+
+	evtTransformOpts evttool.TransformOpts
+	evtTransformCmd  = &cobra.Command{
+		Use:   "transform",
+		Short: "Rewrite an events file, pseudonymising parties, remapping markets and dropping event types",
+		RunE:  runEvtTransform,
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(evtToolCmd)
+	evtToolCmd.AddCommand(evtSliceCmd, evtSplitCmd, evtMergeCmd, evtValidateCmd, evtVerifyCmd, evtRepairCmd, evtTransformCmd)
+
+	evtSliceCmd.Flags().StringVarP(&evtSliceOpts.File, "file", "f", "vega.evt", "name of the file, or directory of rotated files, to read events from")
+	evtSliceCmd.Flags().StringVarP(&evtSliceOpts.Out, "out", "o", "", "name of the file to write events to")
+	evtSliceCmd.Flags().StringVar(&evtSliceOpts.Compression, "compression", "", "compress the output. Allowed values: gzip, zstd")
+	evtSliceCmd.Flags().Uint64Var(&evtSliceOpts.FromBlock, "from-block", 0, "first block height to copy")
+	evtSliceCmd.Flags().Uint64Var(&evtSliceOpts.ToBlock, "to-block", 0, "last block height to copy")
+	evtSliceCmd.Flags().StringSliceVarP(&evtSliceOpts.Types, "type", "t", nil, "one or more event types to copy (default=ALL)")
+	evtSliceCmd.Flags().StringVarP(&evtSliceOpts.Party, "party", "p", "", "only copy events referencing this party")
+	evtSliceCmd.Flags().StringVarP(&evtSliceOpts.Market, "market", "m", "", "only copy events referencing this market")
+	evtSliceCmd.MarkFlagRequired("out")
+
+	evtSplitCmd.Flags().StringVarP(&evtSplitOpts.File, "file", "f", "vega.evt", "name of the file, or directory of rotated files, to read events from")
+	evtSplitCmd.Flags().StringVarP(&evtSplitOpts.OutDir, "out-dir", "o", "", "directory to write the split files to")
+	evtSplitCmd.Flags().StringVar(&evtSplitOpts.Compression, "compression", "", "compress the output. Allowed values: gzip, zstd")
+	evtSplitCmd.Flags().Uint64VarP(&evtSplitOpts.Blocks, "blocks", "b", 0, "number of blocks in each file")
+	evtSplitCmd.Flags().BoolVar(&evtSplitOpts.ByType, "by-type", false, "write each event type to its own file")
+	evtSplitCmd.MarkFlagRequired("out-dir")
+
+	evtMergeCmd.Flags().StringVarP(&evtMergeOpts.Out, "out", "o", "", "name of the file to write events to")
+	evtMergeCmd.Flags().StringVar(&evtMergeOpts.Compression, "compression", "", "compress the output. Allowed values: gzip, zstd")
+	evtMergeCmd.MarkFlagRequired("out")
+
+	evtValidateCmd.Flags().StringVarP(&evtValidateOpts.File, "file", "f", "vega.evt", "name of the file, or directory of rotated files, to validate")
+
+	evtVerifyCmd.Flags().StringVarP(&evtVerifyOpts.File, "file", "f", "vega.evt", "name of the file, or directory of rotated files, to verify")
+
+	evtRepairCmd.Flags().StringVarP(&evtRepairOpts.File, "file", "f", "vega.evt", "name of the file to repair")
+
+	evtTransformCmd.Flags().StringVarP(&evtTransformOpts.File, "file", "f", "vega.evt", "name of the file, or directory of rotated files, to read events from")
+	evtTransformCmd.Flags().StringVarP(&evtTransformOpts.Out, "out", "o", "", "name of the file to write events to")
+	evtTransformCmd.Flags().StringVar(&evtTransformOpts.Compression, "compression", "", "compress the output. Allowed values: gzip, zstd")
+	evtTransformCmd.Flags().BoolVar(&evtTransformOpts.Pseudonymise, "pseudonymise", false, "replace party IDs and public keys with pseudonyms")
+	evtTransformCmd.Flags().StringVar(&evtTransformOpts.Salt, "salt", "", "secret used to derive the pseudonyms, the same salt always gives the same pseudonyms")
+	evtTransformCmd.Flags().StringSliceVar(&evtTransformOpts.DropTypes, "drop-type", nil, "one or more event types to drop")
+	evtTransformCmd.Flags().StringToStringVar(&evtTransformOpts.MarketMap, "market-map", nil, "market IDs to replace, as old=new pairs")
+	evtTransformCmd.MarkFlagRequired("out")
+}
+
+func runEvtSlice(cmd *cobra.Command, args []string) error {
+	return evttool.Slice(evtSliceOpts)
+}
+
+func runEvtSplit(cmd *cobra.Command, args []string) error {
+	return evttool.Split(evtSplitOpts)
+}
+
+func runEvtMerge(cmd *cobra.Command, args []string) error {
+	evtMergeOpts.Files = args
+	return evttool.Merge(evtMergeOpts)
+}
+
+func runEvtValidate(cmd *cobra.Command, args []string) error {
+	return evttool.Validate(evtValidateOpts)
+}
+
+func runEvtVerify(cmd *cobra.Command, args []string) error {
+	return evttool.Verify(evtVerifyOpts)
+}
+
+func runEvtRepair(cmd *cobra.Command, args []string) error {
+	return evttool.Repair(evtRepairOpts)
+}
+
+func runEvtTransform(cmd *cobra.Command, args []string) error {
+	return evttool.Transform(evtTransformOpts)
+}
@@ -0,0 +1,12 @@
+package cmd
+
+import (
+	"code.vegaprotocol.io/vegatools/output"
+
+	"github.com/spf13/cobra"
+)
+
+// addOutputFlag adds the --output flag shared by the tools that can write machine readable results.
+func addOutputFlag(cmd *cobra.Command, p *string) {
+	cmd.Flags().StringVarP(p, "output", "o", output.FormatText, "format of the results, one of text, json or csv")
+}
@@ -1,20 +1,31 @@
 package cmd
 
 import (
+	"fmt"
+	"time"
+
+	"code.vegaprotocol.io/vegatools/eventfile"
 	"code.vegaprotocol.io/vegatools/eventpersister"
 	"github.com/spf13/cobra"
 )
 
 var (
 	persistEventOpts struct {
-		file       string
-		batchSize  uint
-		party      string
-		market     string
-		serverAddr string
-		logFormat  string
-		reconnect  bool
-		types      []string
+		file        string
+		batchSize   uint
+		party       string
+		market      string
+		serverAddr  string
+		logFormat   string
+		reconnect   bool
+		resume      bool
+		checkStream bool
+		types       []string
+
+		compression    string
+		rotateSize     int64
+		rotateBlocks   uint64
+		rotateInterval time.Duration
 	}
 
 	persistEventsCmd = &cobra.Command{
@@ -33,11 +44,21 @@ func init() {
 	persistEventsCmd.Flags().StringVarP(&persistEventOpts.serverAddr, "address", "a", "", "address of the grpc server")
 	persistEventsCmd.Flags().StringVar(&persistEventOpts.logFormat, "log-format", "raw", "output stream data in specified format. Allowed values: raw (default), text, json")
 	persistEventsCmd.Flags().BoolVarP(&persistEventOpts.reconnect, "reconnect", "r", false, "if connection dies, attempt to reconnect")
+	persistEventsCmd.Flags().BoolVar(&persistEventOpts.resume, "resume", false, "append to an existing file, continuing after the last complete block, instead of truncating it")
+	persistEventsCmd.Flags().BoolVar(&persistEventOpts.checkStream, "check-stream", false, "report missing blocks and events, and unpaired BEGIN_BLOCK/END_BLOCK events, in the stream")
 	persistEventsCmd.Flags().StringSliceVarP(&persistEventOpts.types, "type", "t", nil, "one or more event types to subscribe to (default=ALL)")
+	persistEventsCmd.Flags().StringVar(&persistEventOpts.compression, "compression", "", "compress the persisted events. Allowed values: gzip, zstd")
+	persistEventsCmd.Flags().Int64Var(&persistEventOpts.rotateSize, "rotate-size", 0, "start a new file once the current one reaches this many megabytes")
+	persistEventsCmd.Flags().Uint64Var(&persistEventOpts.rotateBlocks, "rotate-blocks", 0, "start a new file once the current one holds this many blocks")
+	persistEventsCmd.Flags().DurationVar(&persistEventOpts.rotateInterval, "rotate-interval", 0, "start a new file once the current one has been open this long (e.g. 1h)")
 	persistEventsCmd.MarkFlagRequired("address")
 }
 
 func runPersistEvents(cmd *cobra.Command, args []string) error {
+	// checked before the file is opened, which truncates it unless resuming
+	if persistEventOpts.checkStream && (len(persistEventOpts.types) > 0 || len(persistEventOpts.party) > 0 || len(persistEventOpts.market) > 0) {
+		return fmt.Errorf("--check-stream cannot be used with --type, --party or --market")
+	}
 	return eventpersister.Run(
 		persistEventOpts.file,
 		persistEventOpts.batchSize,
@@ -46,6 +67,14 @@ func runPersistEvents(cmd *cobra.Command, args []string) error {
 		persistEventOpts.serverAddr,
 		persistEventOpts.logFormat,
 		persistEventOpts.reconnect,
+		persistEventOpts.resume,
+		persistEventOpts.checkStream,
 		persistEventOpts.types,
+		eventfile.WriterOpts{
+			Compression: persistEventOpts.compression,
+			MaxBytes:    persistEventOpts.rotateSize * 1024 * 1024,
+			MaxBlocks:   persistEventOpts.rotateBlocks,
+			MaxAge:      persistEventOpts.rotateInterval,
+		},
 	)
 }
@@ -23,6 +23,7 @@ func init() {
 	powRateCmd.Flags().IntVarP(&powRateOpts.SecondsPerBlock, "secondsperblock", "p", 2, "average block time of the network")
 	powRateCmd.Flags().IntVarP(&powRateOpts.TxPerBlock, "txperblock", "t", 2, "maximum number of transactions linked to each historic block")
 	powRateCmd.Flags().IntVarP(&powRateOpts.DefaultDifficulty, "difficulty", "d", 15, "default network difficulty level")
+	addOutputFlag(powRateCmd, &powRateOpts.Output)
 }
 
 func runPowRate(cmd *cobra.Command, args []string) error {
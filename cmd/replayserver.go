@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"code.vegaprotocol.io/vegatools/replayserver"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	replayServerOpts replayserver.Opts
+	replayServerCmd  = &cobra.Command{
+		Use:   "replay-server",
+		Short: "Serve the events of an events file over the gRPC event bus API of a node",
+		RunE:  runReplayServer,
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(replayServerCmd)
+	replayServerCmd.Flags().StringVarP(&replayServerOpts.File, "file", "f", "vega.evt", "name of the file, or directory of rotated files, to read events from")
+	replayServerCmd.Flags().StringVarP(&replayServerOpts.Address, "address", "a", "localhost:3007", "address to serve the event bus on")
+	replayServerCmd.Flags().Uint64Var(&replayServerOpts.FromBlock, "from-block", 0, "first block height to send")
+	replayServerCmd.Flags().Uint64Var(&replayServerOpts.ToBlock, "to-block", 0, "last block height to send")
+	replayServerCmd.Flags().Float64Var(&replayServerOpts.Speed, "speed", 1, "speed multiplier applied to the original time between blocks, 0 sends events as fast as possible")
+}
+
+func runReplayServer(cmd *cobra.Command, args []string) error {
+	return replayserver.Run(replayServerOpts)
+}
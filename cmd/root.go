@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -17,6 +18,13 @@ var rootCmd = &cobra.Command{
 // Usually called by the `main.main()`
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
+		// tools can return an error with its own exit code, e.g. to tell apart the kinds of failure in scripts. It
+		// is printed to stderr as stdout may hold a machine readable report
+		var exitErr interface{ ExitCode() int }
+		if errors.As(err, &exitErr) {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitErr.ExitCode())
+		}
 		fmt.Println(err)
 		os.Exit(1)
 	}
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"code.vegaprotocol.io/vegatools/streamcheck"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	streamCheckOpts streamcheck.Opts
+	streamCheckCmd  = &cobra.Command{
+		Use:   "streamcheck",
+		Short: "Check the event bus stream of a node for missing blocks and events",
+		RunE:  runStreamCheck,
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(streamCheckCmd)
+	streamCheckCmd.Flags().StringVarP(&streamCheckOpts.ServerAddr, "address", "a", "", "address of the grpc server")
+	streamCheckCmd.Flags().BoolVarP(&streamCheckOpts.Reconnect, "reconnect", "r", false, "if connection dies, attempt to reconnect")
+	streamCheckCmd.Flags().BoolVar(&streamCheckOpts.Bell, "bell", false, "ring the terminal bell when a problem is found")
+	streamCheckCmd.Flags().BoolVar(&streamCheckOpts.ExitOnError, "exit-on-error", false, "stop with a non zero exit code at the first problem")
+	streamCheckCmd.Flags().DurationVar(&streamCheckOpts.ReportInterval, "report-interval", 0, "how often to print the totals seen so far (e.g. 1m)")
+	streamCheckCmd.MarkFlagRequired("address")
+}
+
+func runStreamCheck(cmd *cobra.Command, args []string) error {
+	return streamcheck.Run(streamCheckOpts)
+}
@@ -6,23 +6,37 @@ import (
 )
 
 var (
-	streamLatencyOpts streamlatency.Opts
-	streamLatencyCmd  = &cobra.Command{
+	streamLatencyOpts struct {
+		streamlatency.Opts
+		serverAddr1 string
+		serverAddr2 string
+	}
+	streamLatencyCmd = &cobra.Command{
 		Use:   "streamlatency",
-		Short: "Display the latency difference between two event streams",
+		Short: "Display the latency difference between event streams",
 		RunE:  runStreamLatency,
 	}
 )
 
 func init() {
 	rootCmd.AddCommand(streamLatencyCmd)
-	streamLatencyCmd.Flags().StringVarP(&streamLatencyOpts.ServerAddr1, "address1", "a", "", "address of the first grpc server")
-	streamLatencyCmd.Flags().StringVarP(&streamLatencyOpts.ServerAddr2, "address2", "b", "", "address of the second grpc server")
+	streamLatencyCmd.Flags().StringVarP(&streamLatencyOpts.serverAddr1, "address1", "a", "", "address of the first grpc server")
+	streamLatencyCmd.Flags().StringVarP(&streamLatencyOpts.serverAddr2, "address2", "b", "", "address of the second grpc server")
+	streamLatencyCmd.Flags().StringSliceVar(&streamLatencyOpts.ServerAddrs, "address", nil, "addresses of the grpc servers to compare, can be repeated")
 	streamLatencyCmd.Flags().BoolVarP(&streamLatencyOpts.ReportMode, "reportmode", "r", false, "generate report style output")
-	streamLatencyCmd.MarkFlagRequired("address1")
-	streamLatencyCmd.MarkFlagRequired("address2")
+	streamLatencyCmd.Flags().IntVarP(&streamLatencyOpts.Window, "window", "w", 100, "number of recent blocks the percentiles are calculated over")
+	streamLatencyCmd.Flags().DurationVarP(&streamLatencyOpts.Duration, "duration", "d", 0, "stop after this long (e.g. 10m), by default runs until interrupted")
+	streamLatencyCmd.Flags().StringVar(&streamLatencyOpts.CSVFile, "csv", "", "write the skew of every server for every block to this file")
+	streamLatencyCmd.Flags().StringVar(&streamLatencyOpts.MetricsListen, "metrics-listen", "", "serve Prometheus metrics on this address (e.g. localhost:2112) instead of printing to the terminal")
+	addOutputFlag(streamLatencyCmd, &streamLatencyOpts.Output)
 }
 
 func runStreamLatency(cmd *cobra.Command, args []string) error {
-	return streamlatency.Run(streamLatencyOpts)
+	opts := streamLatencyOpts.Opts
+	for _, addr := range []string{streamLatencyOpts.serverAddr1, streamLatencyOpts.serverAddr2} {
+		if len(addr) > 0 {
+			opts.ServerAddrs = append(opts.ServerAddrs, addr)
+		}
+	}
+	return streamlatency.Run(opts)
 }
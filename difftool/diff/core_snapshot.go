@@ -3,6 +3,7 @@ package diff
 import (
 	"io/ioutil"
 	"os"
+	"sort"
 
 	"code.vegaprotocol.io/vega/libs/crypto"
 	dn "code.vegaprotocol.io/vega/protos/data-node/api/v2"
@@ -18,28 +19,14 @@ type snap struct {
 	chunk *snapshot.Chunk
 }
 
-// Collect returns a dataset for comparison from core snapshot.
+// Collect returns a dataset for comparison from core snapshot. Every domain is read, as it is cheap and some
+// comparisons use other domains of the core snapshot, e.g. accounts are filtered by the markets.
 func (s *snap) Collect() *Result {
-	return &Result{
-		Accounts:    s.getAccounts(),
-		Orders:      s.getOrders(),
-		Markets:     s.getMarkets(),
-		Parties:     s.getParties(),
-		Limits:      s.getNetLimits(),
-		Assets:      s.getAssets(),
-		VegaTime:    s.getVegaTime(),
-		Delegations: s.getDelegations(),
-		Epoch:       s.getEpoch(),
-		Nodes:       s.getValidators(),
-		NetParams:   s.getNetParams(),
-		Proposals:   s.getProposals(),
-		Deposits:    s.getDeposits(),
-		Withdrawals: s.getWithdrawals(),
-		Transfers:   s.getTransfers(),
-		Positions:   s.getPositions(),
-		Lps:         s.getLps(),
-		Stake:       s.getStake(),
+	res := &Result{}
+	for _, d := range domains {
+		d.fromSnapshot(s, res)
 	}
+	return res
 }
 
 // getNetParams returns the network parmeters from the core snapshot.
@@ -221,10 +208,24 @@ func (s *snap) getOrders() []*vega.Order {
 			continue
 		}
 	}
+	dpFactors := s.getPriceFactors()
+	for _, o := range orders {
+		o.CreatedAt = (o.CreatedAt / 1000) * 1000
+		o.ExpiresAt = (o.ExpiresAt / 1000) * 1000
+		o.UpdatedAt = (o.UpdatedAt / 1000) * 1000
+		price, _ := decimal.NewFromString(o.Price)
+		o.Price = price.Div(dpFactors[o.MarketId]).Truncate(0).String()
+	}
+
+	return orders
+}
+
+// getPriceFactors returns the factor by market that prices in the core snapshot, which are in asset decimals, are
+// divided by to be in the market decimals used by data node.
+func (s *snap) getPriceFactors() map[string]decimal.Decimal {
 	assets := s.getAssets()
-	markets := s.getMarkets()
 	dpFactors := map[string]decimal.Decimal{}
-	for _, m := range markets {
+	for _, m := range s.getMarkets() {
 		marketDecimals := m.DecimalPlaces
 		asset, _ := m.GetAsset()
 		for _, a := range assets {
@@ -233,15 +234,7 @@ func (s *snap) getOrders() []*vega.Order {
 			}
 		}
 	}
-	for _, o := range orders {
-		o.CreatedAt = (o.CreatedAt / 1000) * 1000
-		o.ExpiresAt = (o.ExpiresAt / 1000) * 1000
-		o.UpdatedAt = (o.UpdatedAt / 1000) * 1000
-		price, _ := decimal.NewFromString(o.Price)
-		o.Price = price.Div(dpFactors[o.MarketId]).Truncate(0).String()
-	}
-
-	return orders
+	return dpFactors
 }
 
 // getMarkets returns active markets from the core snapshot.
@@ -349,6 +342,19 @@ func (s *snap) getVegaTime() int64 {
 	return 0
 }
 
+// getHeight returns the block height the core snapshot was taken at.
+func (s *snap) getHeight() uint64 {
+	for _, c := range s.chunk.Data {
+		switch c.Data.(type) {
+		case *snapshot.Payload_AppState:
+			return c.GetAppState().Height
+		default:
+			continue
+		}
+	}
+	return 0
+}
+
 // getDelegations returns the delegations from the core snapshot.
 func (s *snap) getDelegations() []*vega.Delegation {
 	for _, c := range s.chunk.Data {
@@ -463,10 +469,254 @@ func (s *snap) getValidators() []*vega.Node {
 	return []*vega.Node{}
 }
 
-// getPositions is currently unsupported as the core snapshot and datanode have very different abstractions.
-// TODO
-func (s *snap) getPositions() []*vega.Position {
-	return []*vega.Position{}
+// getExecutionMarkets returns the execution engine state of the active markets from the core snapshot.
+func (s *snap) getExecutionMarkets() []*snapshot.Market {
+	for _, c := range s.chunk.Data {
+		switch c.Data.(type) {
+		case *snapshot.Payload_ExecutionMarkets:
+			return c.GetExecutionMarkets().Markets
+		default:
+			continue
+		}
+	}
+	return []*snapshot.Market{}
+}
+
+// getMarketData returns the mark price, trading mode, auction trigger and start, and open interest of the active
+// markets from the core snapshot. The open interest is the sum of the long positions. To make it compatible with
+// datanode the mark price is scaled to the market decimals and the auction start has microsecond resolution, and the
+// auction details are only set while in auction.
+func (s *snap) getMarketData() []*vega.MarketData {
+	openInterest := map[string]uint64{}
+	for _, c := range s.chunk.Data {
+		switch c.Data.(type) {
+		case *snapshot.Payload_MarketPositions:
+			mp := c.GetMarketPositions()
+			for _, p := range mp.Positions {
+				if p.Size > 0 {
+					openInterest[mp.MarketId] += uint64(p.Size)
+				}
+			}
+		default:
+			continue
+		}
+	}
+
+	dpFactors := s.getPriceFactors()
+	marketData := []*vega.MarketData{}
+	for _, m := range s.getExecutionMarkets() {
+		md := &vega.MarketData{
+			Market:       m.Market.Id,
+			OpenInterest: openInterest[m.Market.Id],
+		}
+		if price, err := decimal.NewFromString(m.CurrentMarkPrice); err == nil {
+			md.MarkPrice = price.Div(dpFactors[m.Market.Id]).Truncate(0).String()
+		}
+		if as := m.AuctionState; as != nil {
+			md.MarketTradingMode = as.Mode
+			if as.Mode != vega.Market_TRADING_MODE_CONTINUOUS {
+				md.Trigger = as.Trigger
+				md.AuctionStart = (as.Begin / 1000) * 1000
+			}
+		}
+		marketData = append(marketData, md)
+	}
+	return marketData
+}
+
+// getStopOrders returns the pending stop orders of the active markets from the core snapshot. To make it compatible
+// with datanode, the timestamps are converted to have microsecond resolution.
+func (s *snap) getStopOrders() []*vega.StopOrder {
+	stopOrders := []*vega.StopOrder{}
+	for _, m := range s.getExecutionMarkets() {
+		if m.StopOrders == nil {
+			continue
+		}
+		for _, e := range m.StopOrders.StopOrderEvents {
+			so := e.StopOrder
+			so.CreatedAt = (so.CreatedAt / 1000) * 1000
+			if so.UpdatedAt != nil {
+				updatedAt := (*so.UpdatedAt / 1000) * 1000
+				so.UpdatedAt = &updatedAt
+			}
+			if so.ExpiresAt != nil {
+				expiresAt := (*so.ExpiresAt / 1000) * 1000
+				so.ExpiresAt = &expiresAt
+			}
+			stopOrders = append(stopOrders, so)
+		}
+	}
+	return stopOrders
+}
+
+// getFeesStats returns the trading fees paid and received in each active market so far in the current epoch from the
+// core snapshot.
+func (s *snap) getFeesStats() []*events.FeesStats {
+	epoch := s.getEpoch().Seq
+	feesStats := []*events.FeesStats{}
+	for _, m := range s.getExecutionMarkets() {
+		if m.FeesStats == nil {
+			continue
+		}
+		fs := proto.Clone(m.FeesStats).(*events.FeesStats)
+		fs.EpochSeq = epoch
+		feesStats = append(feesStats, fs)
+	}
+	return feesStats
+}
+
+// getVestingBalances returns the locked and vesting reward balances of each party from the core snapshot, sorted as
+// they are by datanode.
+func (s *snap) getVestingBalances() []*dn.GetVestingBalancesSummaryResponse {
+	balances := []*dn.GetVestingBalancesSummaryResponse{}
+	for _, c := range s.chunk.Data {
+		switch c.Data.(type) {
+		case *snapshot.Payload_Vesting:
+			for _, pr := range c.GetVesting().PartiesReward {
+				b := &dn.GetVestingBalancesSummaryResponse{PartyId: pr.Party}
+				for _, al := range pr.AssetLocked {
+					for _, eb := range al.EpochBalances {
+						b.LockedBalances = append(b.LockedBalances, &dn.PartyLockedBalance{Asset: al.Asset, Balance: eb.Balance, UntilEpoch: eb.Epoch})
+					}
+				}
+				for _, iv := range pr.InVesting {
+					b.VestingBalances = append(b.VestingBalances, &dn.PartyVestingBalance{Asset: iv.Asset, Balance: iv.Balance})
+				}
+				sortVestingBalances(b)
+				balances = append(balances, b)
+			}
+		default:
+			continue
+		}
+	}
+	return balances
+}
+
+// sortVestingBalances sorts the balances of a party by asset, and the locked balances by epoch, so that they can be
+// compared.
+func sortVestingBalances(b *dn.GetVestingBalancesSummaryResponse) {
+	sort.Slice(b.LockedBalances, func(i, j int) bool {
+		if b.LockedBalances[i].Asset != b.LockedBalances[j].Asset {
+			return b.LockedBalances[i].Asset < b.LockedBalances[j].Asset
+		}
+		return b.LockedBalances[i].UntilEpoch < b.LockedBalances[j].UntilEpoch
+	})
+	sort.Slice(b.VestingBalances, func(i, j int) bool { return b.VestingBalances[i].Asset < b.VestingBalances[j].Asset })
+}
+
+// getReferralPrograms returns the current referral program, if any, from the core snapshot.
+func (s *snap) getReferralPrograms() []*vega.ReferralProgram {
+	for _, c := range s.chunk.Data {
+		switch c.Data.(type) {
+		case *snapshot.Payload_ReferralProgram:
+			if p := c.GetReferralProgram().CurrentProgram; p != nil {
+				return []*vega.ReferralProgram{p}
+			}
+		default:
+			continue
+		}
+	}
+	return []*vega.ReferralProgram{}
+}
+
+// getReferralSets returns the referral sets and their referees from the core snapshot. To make it compatible with
+// datanode, the timestamps are converted to have microsecond resolution.
+func (s *snap) getReferralSets() ([]*dn.ReferralSet, []*dn.ReferralSetReferee) {
+	sets := []*dn.ReferralSet{}
+	referees := []*dn.ReferralSetReferee{}
+	for _, c := range s.chunk.Data {
+		switch c.Data.(type) {
+		case *snapshot.Payload_ReferralProgram:
+			for _, rs := range c.GetReferralProgram().Sets {
+				set := &dn.ReferralSet{
+					Id:        rs.Id,
+					CreatedAt: (rs.CreatedAt / 1000) * 1000,
+					UpdatedAt: (rs.UpdatedAt / 1000) * 1000,
+				}
+				if rs.Referrer != nil {
+					set.Referrer = rs.Referrer.PartyId
+				}
+				sets = append(sets, set)
+				for _, r := range rs.Referees {
+					referees = append(referees, &dn.ReferralSetReferee{
+						ReferralSetId: rs.Id,
+						Referee:       r.PartyId,
+						JoinedAt:      (r.JoinedAt / 1000) * 1000,
+						AtEpoch:       r.StartedAtEpoch,
+					})
+				}
+			}
+		default:
+			continue
+		}
+	}
+	return sets, referees
+}
+
+// getTeams returns the teams and their referees from the core snapshot. To make it compatible with datanode, the
+// timestamps are converted to have microsecond resolution.
+func (s *snap) getTeams() ([]*dn.Team, []*dn.TeamReferee) {
+	teams := []*dn.Team{}
+	referees := []*dn.TeamReferee{}
+	for _, c := range s.chunk.Data {
+		switch c.Data.(type) {
+		case *snapshot.Payload_Teams:
+			for _, t := range c.GetTeams().Teams {
+				team := &dn.Team{
+					TeamId:    t.Id,
+					Name:      t.Name,
+					TeamUrl:   t.TeamUrl,
+					AvatarUrl: t.AvatarUrl,
+					CreatedAt: (t.CreatedAt / 1000) * 1000,
+					Closed:    t.Closed,
+				}
+				if t.Referrer != nil {
+					team.Referrer = t.Referrer.PartyId
+				}
+				teams = append(teams, team)
+				for _, m := range t.Referees {
+					referees = append(referees, &dn.TeamReferee{
+						TeamId:        t.Id,
+						Referee:       m.PartyId,
+						JoinedAt:      (m.JoinedAt / 1000) * 1000,
+						JoinedAtEpoch: m.StartedAtEpoch,
+					})
+				}
+			}
+		default:
+			continue
+		}
+	}
+	return teams, referees
+}
+
+// getOracleSpecs returns the data source specs used by the active markets from the core snapshot, as the oracle
+// engine is rebuilt from the markets when restoring. Only the ID and definition are compared as the status and
+// timestamps are not kept in the market.
+func (s *snap) getOracleSpecs() []*vega.DataSourceSpec {
+	specs := map[string]*vega.DataSourceSpec{}
+	add := func(ds ...*vega.DataSourceSpec) {
+		for _, spec := range ds {
+			if spec != nil {
+				specs[spec.Id] = &vega.DataSourceSpec{Id: spec.Id, Data: spec.Data}
+			}
+		}
+	}
+	for _, m := range s.getMarkets() {
+		instrument := m.GetTradableInstrument().GetInstrument()
+		if f := instrument.GetFuture(); f != nil {
+			add(f.DataSourceSpecForSettlementData, f.DataSourceSpecForTradingTermination)
+		}
+		if p := instrument.GetPerpetual(); p != nil {
+			add(p.DataSourceSpecForSettlementData, p.DataSourceSpecForSettlementSchedule)
+		}
+	}
+
+	oracleSpecs := make([]*vega.DataSourceSpec, 0, len(specs))
+	for _, spec := range specs {
+		oracleSpecs = append(oracleSpecs, spec)
+	}
+	return oracleSpecs
 }
 
 // NewSnapshotData deserealises a proto file into snap.
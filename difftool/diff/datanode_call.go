@@ -0,0 +1,226 @@
+package diff
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	dn "code.vegaprotocol.io/vega/protos/data-node/api/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// defaultConcurrency is the number of data node calls in flight if not set
+	defaultConcurrency = 4
+	// defaultTimeout is the timeout of data node calls if not set
+	defaultTimeout = 30 * time.Second
+	// pageSize is the number of entities requested per page of a list call
+	pageSize = int32(1000)
+	// firstRetryDelay is how long to wait before the first retry of a call, doubled for every other retry
+	firstRetryDelay = 500 * time.Millisecond
+	// progressInterval is how often progress is reported while collecting
+	progressInterval = 5 * time.Second
+)
+
+// call runs a data node call once a slot is free, with a timeout, retrying with backoff if data node is rate limiting
+// or unavailable.
+func (dnc *dataNodeClient) call(f func(ctx context.Context) error) error {
+	delay := firstRetryDelay
+	for attempt := 0; ; attempt++ {
+		dnc.slots <- struct{}{}
+		ctx, cancel := context.WithTimeout(context.Background(), dnc.timeout)
+		err := f(ctx)
+		cancel()
+		<-dnc.slots
+
+		if err == nil || attempt >= dnc.retries {
+			return err
+		}
+		switch status.Code(err) {
+		case codes.ResourceExhausted, codes.Unavailable, codes.DeadlineExceeded:
+		default:
+			return err
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// blockHeightHeader is the metadata data node adds to every response with the height of its latest block.
+const blockHeightHeader = "x-block-height"
+
+// getHeight returns the height of the latest block processed by data node.
+func (dnc *dataNodeClient) getHeight() (uint64, error) {
+	var header metadata.MD
+	err := dnc.call(func(ctx context.Context) error {
+		_, err := dnc.datanode.GetVegaTime(ctx, &dn.GetVegaTimeRequest{}, grpc.Header(&header))
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	values := header.Get(blockHeightHeader)
+	if len(values) == 0 {
+		return 0, fmt.Errorf("datanode did not report its block height")
+	}
+	height, err := strconv.ParseUint(values[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid datanode block height %s: %w", values[0], err)
+	}
+	return height, nil
+}
+
+// waitForHeight waits until data node has processed the block at height, for at most timeout.
+func (dnc *dataNodeClient) waitForHeight(height uint64, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		current, err := dnc.getHeight()
+		if err != nil {
+			return err
+		}
+		if current >= height {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("datanode is at height %d, still behind the snapshot at %d after %s", current, height, timeout)
+		}
+		fmt.Fprintf(os.Stderr, "waiting for datanode at height %d to reach the snapshot height %d\n", current, height)
+		time.Sleep(progressInterval)
+	}
+}
+
+// listAll walks all the pages of a list call. fetch is given the pagination to request and returns the entities of
+// the page and its page info.
+func listAll[N any](dnc *dataNodeClient, name string, fetch func(ctx context.Context, pagination *dn.Pagination) ([]N, *dn.PageInfo, error)) ([]N, error) {
+	result := []N{}
+
+	lastRecordCursor := ""
+	for {
+		first := pageSize
+		pagination := &dn.Pagination{First: &first}
+		if len(lastRecordCursor) > 0 {
+			after := lastRecordCursor
+			pagination.After = &after
+		}
+
+		var nodes []N
+		var pageInfo *dn.PageInfo
+		err := dnc.call(func(ctx context.Context) (err error) {
+			nodes, pageInfo, err = fetch(ctx, pagination)
+			return
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, nodes...)
+		dnc.progress.add(name, len(nodes))
+
+		if pageInfo == nil || !pageInfo.HasNextPage {
+			break
+		}
+
+		lastRecordCursor = pageInfo.EndCursor
+	}
+
+	return result, nil
+}
+
+// forEach runs fetch for each of the items, e.g. to get the stake of each party, on as many workers as the client
+// has slots, and returns all the results in the order of the items. It stops handing out items after the first error.
+func forEach[I, N any](dnc *dataNodeClient, items []I, fetch func(item I) ([]N, error)) ([]N, error) {
+	results := make([][]N, len(items))
+	errs := make([]error, len(items))
+
+	next := make(chan int)
+	failed := make(chan struct{})
+	var once sync.Once
+	var wg sync.WaitGroup
+	for w := 0; w < min(cap(dnc.slots), len(items)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i], errs[i] = fetch(items[i])
+				if errs[i] != nil {
+					once.Do(func() { close(failed) })
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := range items {
+		select {
+		case next <- i:
+		case <-failed:
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	all := []N{}
+	for i, r := range results {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		all = append(all, r...)
+	}
+	return all, nil
+}
+
+// progress counts the entities fetched by each list call so that long collections can be followed.
+type progress struct {
+	mu      sync.Mutex
+	fetched map[string]int
+}
+
+func newProgress() *progress {
+	return &progress{fetched: map[string]int{}}
+}
+
+func (p *progress) add(name string, n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.fetched[name] += n
+}
+
+func (p *progress) String() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	names := make([]string, 0, len(p.fetched))
+	for name := range p.fetched {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s=%d", name, p.fetched[name]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// report prints the progress to stderr every progressInterval until done is closed.
+func (p *progress) report(done <-chan struct{}) {
+	start := time.Now()
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			fmt.Fprintf(os.Stderr, "collecting from datanode for %s: %s\n", time.Since(start).Round(time.Second), p)
+		}
+	}
+}
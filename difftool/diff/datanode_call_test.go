@@ -0,0 +1,62 @@
+package diff
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForEachRunsOnAFixedNumberOfWorkers(t *testing.T) {
+	dnc := newTestClient(nil)
+	items := make([]int, 50)
+	for i := range items {
+		items[i] = i
+	}
+
+	var running, most atomic.Int32
+	results, err := forEach(dnc, items, func(i int) ([]int, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := most.Load()
+			if n <= m || most.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		return []int{i, i}, nil
+	})
+	require.NoError(t, err)
+
+	assert.LessOrEqual(t, most.Load(), int32(cap(dnc.slots)))
+	require.Len(t, results, 2*len(items))
+	for i := range items {
+		assert.Equal(t, []int{i, i}, results[2*i:2*i+2])
+	}
+}
+
+func TestForEachStopsOnError(t *testing.T) {
+	dnc := newTestClient(nil)
+	items := make([]int, 1000)
+
+	var fetched atomic.Int32
+	failure := errors.New("failed")
+	_, err := forEach(dnc, items, func(int) ([]int, error) {
+		fetched.Add(1)
+		return nil, failure
+	})
+	assert.ErrorIs(t, err, failure)
+	assert.Less(t, fetched.Load(), int32(len(items)))
+}
+
+func TestForEachOfNoItems(t *testing.T) {
+	results, err := forEach(newTestClient(nil), []int{}, func(int) ([]int, error) {
+		return nil, errors.New("not called")
+	})
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
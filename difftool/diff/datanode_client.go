@@ -2,513 +2,671 @@ package diff
 
 import (
 	"context"
+	"fmt"
+	"strconv"
 	"sync"
+	"time"
 
 	"code.vegaprotocol.io/vega/libs/crypto"
 	dn "code.vegaprotocol.io/vega/protos/data-node/api/v2"
 	"code.vegaprotocol.io/vega/protos/vega"
 	v1 "code.vegaprotocol.io/vega/protos/vega/events/v1"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type dataNodeClient struct {
+	conn     *grpc.ClientConn
 	datanode dn.TradingDataServiceClient
+	// slots bounds the number of calls in flight
+	slots    chan struct{}
+	timeout  time.Duration
+	retries  int
+	progress *progress
+	// height is the block height the calls that allow it are pinned to, the latest state if 0
+	height uint64
+
+	// parties, markets and teams are used by several domains so are only fetched once
+	parties func() ([]*vega.Party, error)
+	markets func() ([]*vega.Market, error)
+	teams   func() ([]*dn.Team, error)
 }
 
-func newDataNodeClient(dataNodeAddr string) *dataNodeClient {
+func newDataNodeClient(dataNodeAddr string, opts Opts) (*dataNodeClient, error) {
+	if opts.DatanodeConcurrency <= 0 {
+		opts.DatanodeConcurrency = defaultConcurrency
+	}
+	if opts.DatanodeTimeout <= 0 {
+		opts.DatanodeTimeout = defaultTimeout
+	}
+
 	connection, err := grpc.Dial(dataNodeAddr, grpc.WithInsecure())
 	if err != nil {
-		return nil
+		return nil, fmt.Errorf("failed to connect to datanode: %w", err)
 	}
 
-	return &dataNodeClient{
-		datanode: dn.NewTradingDataServiceClient(connection),
-	}
+	dnc := newClient(dn.NewTradingDataServiceClient(connection), opts)
+	dnc.conn = connection
+	return dnc, nil
+}
+
+func newClient(datanode dn.TradingDataServiceClient, opts Opts) *dataNodeClient {
+	dnc := &dataNodeClient{
+		datanode: datanode,
+		slots:    make(chan struct{}, opts.DatanodeConcurrency),
+		timeout:  opts.DatanodeTimeout,
+		retries:  opts.DatanodeRetries,
+		progress: newProgress(),
+	}
+	dnc.parties = sync.OnceValues(dnc.fetchParties)
+	dnc.markets = sync.OnceValues(dnc.fetchMarkets)
+	dnc.teams = sync.OnceValues(dnc.fetchTeams)
+	return dnc
 }
 
-func (dnc *dataNodeClient) Collect() (*Result, error) {
+// Collect fetches the given domains from data node, concurrently, and returns the last error if any fail.
+func (dnc *dataNodeClient) Collect(domains []domain) (*Result, error) {
 	res := &Result{}
 	var wg sync.WaitGroup
-	wg.Add(17)
-
-	errors := make(chan error)
+	wg.Add(len(domains))
+
+	done := make(chan struct{})
+	go dnc.progress.report(done)
+	defer close(done)
+
+	errors := make(chan error, len(domains))
+	for _, d := range domains {
+		go func(d domain) {
+			defer wg.Done()
+			if err := d.fromDatanode(dnc, res); err != nil {
+				errors <- fmt.Errorf("failed to get %s from datanode: %w", d.name, err)
+			}
+		}(d)
+	}
+	wg.Wait()
+	close(errors)
 
-	go func() {
-		defer wg.Done()
-		var err error
-		res.Accounts, err = dnc.listAccounts()
-		if err != nil {
-			errors <- err
-		}
-	}()
+	var resErr error
+	for err := range errors {
+		resErr = err
+	}
+	return res, resErr
+}
 
-	go func() {
-		defer wg.Done()
-		var err error
-		res.Orders, err = dnc.listOrders()
-		if err != nil {
-			errors <- err
+func (dnc *dataNodeClient) listAccounts() ([]*dn.AccountBalance, error) {
+	return listAll(dnc, "accounts", func(ctx context.Context, pagination *dn.Pagination) ([]*dn.AccountBalance, *dn.PageInfo, error) {
+		resp, err := dnc.datanode.ListAccounts(ctx, &dn.ListAccountsRequest{Pagination: pagination})
+		if err != nil || resp.Accounts == nil {
+			return nil, nil, err
 		}
-	}()
-
-	go func() {
-		defer wg.Done()
-		var err error
-		res.Markets, err = dnc.listMarkets()
-		if err != nil {
-			errors <- err
-		} else {
-			for _, m := range res.Markets {
-				var lps []*vega.LiquidityProvision
-				lps, err = dnc.listLiquidityProvisions(m.Id)
-				if err != nil {
-					errors <- err
-				}
-				res.Lps = append(res.Lps, lps...)
-			}
+		accounts := make([]*dn.AccountBalance, 0, len(resp.Accounts.Edges))
+		for _, ae := range resp.Accounts.Edges {
+			accounts = append(accounts, ae.Node)
 		}
-	}()
+		return accounts, resp.Accounts.PageInfo, nil
+	})
+}
 
-	go func() {
-		defer wg.Done()
-		var err error
-		res.Parties, err = dnc.listParties()
-		if err != nil {
-			errors <- err
+func (dnc *dataNodeClient) listOrders() ([]*vega.Order, error) {
+	liveOnly := true
+	return listAll(dnc, "orders", func(ctx context.Context, pagination *dn.Pagination) ([]*vega.Order, *dn.PageInfo, error) {
+		resp, err := dnc.datanode.ListOrders(ctx, &dn.ListOrdersRequest{
+			Filter: &dn.OrderFilter{
+				LiveOnly: &liveOnly},
+			Pagination: pagination,
+		})
+		if err != nil || resp.Orders == nil {
+			return nil, nil, err
 		}
-	}()
-
-	go func() {
-		defer wg.Done()
-		var err error
-		res.Limits, err = dnc.getNetworkLimits()
-		if err != nil {
-			errors <- err
+		orders := make([]*vega.Order, 0, len(resp.Orders.Edges))
+		for _, oe := range resp.Orders.Edges {
+			if oe.Node.Status != vega.Order_STATUS_PARKED {
+				orders = append(orders, oe.Node)
+			}
 		}
-	}()
+		return orders, resp.Orders.PageInfo, nil
+	})
+}
 
-	go func() {
-		defer wg.Done()
-		var err error
-		res.Assets, err = dnc.listAssets()
-		if err != nil {
-			errors <- err
-		}
-	}()
+func (dnc *dataNodeClient) listMarkets() ([]*vega.Market, error) {
+	return dnc.markets()
+}
 
-	go func() {
-		defer wg.Done()
-		var err error
-		res.VegaTime, err = dnc.getVegaTime()
-		if err != nil {
-			errors <- err
+func (dnc *dataNodeClient) fetchMarkets() ([]*vega.Market, error) {
+	return listAll(dnc, "markets", func(ctx context.Context, pagination *dn.Pagination) ([]*vega.Market, *dn.PageInfo, error) {
+		resp, err := dnc.datanode.ListMarkets(ctx, &dn.ListMarketsRequest{Pagination: pagination})
+		if err != nil || resp.Markets == nil {
+			return nil, nil, err
 		}
-	}()
-
-	go func() {
-		defer wg.Done()
-		var err error
-		res.Delegations, err = dnc.listDelegations()
-		if err != nil {
-			errors <- err
+		markets := make([]*vega.Market, 0, len(resp.Markets.Edges))
+		for _, me := range resp.Markets.Edges {
+			markets = append(markets, me.Node)
 		}
-	}()
+		return markets, resp.Markets.PageInfo, nil
+	})
+}
 
-	go func() {
-		defer wg.Done()
-		var err error
-		res.Epoch, err = dnc.getEpoch()
-		if err != nil {
-			errors <- err
-		}
-	}()
+func (dnc *dataNodeClient) listParties() ([]*vega.Party, error) {
+	return dnc.parties()
+}
 
-	go func() {
-		defer wg.Done()
-		var err error
-		res.Nodes, err = dnc.listNodes()
-		if err != nil {
-			errors <- err
+func (dnc *dataNodeClient) fetchParties() ([]*vega.Party, error) {
+	return listAll(dnc, "parties", func(ctx context.Context, pagination *dn.Pagination) ([]*vega.Party, *dn.PageInfo, error) {
+		resp, err := dnc.datanode.ListParties(ctx, &dn.ListPartiesRequest{Pagination: pagination})
+		if err != nil || resp.Parties == nil {
+			return nil, nil, err
 		}
-	}()
-
-	go func() {
-		defer wg.Done()
-		var err error
-		res.NetParams, err = dnc.listNetworkParameters()
-		if err != nil {
-			errors <- err
+		parties := make([]*vega.Party, 0, len(resp.Parties.Edges))
+		for _, pe := range resp.Parties.Edges {
+			parties = append(parties, pe.Node)
 		}
-	}()
+		return parties, resp.Parties.PageInfo, nil
+	})
+}
 
-	go func() {
-		defer wg.Done()
-		var err error
-		res.Proposals, err = dnc.listGovernanceData()
+func (dnc *dataNodeClient) getNetworkLimits() (*vega.NetworkLimits, error) {
+	var limits *vega.NetworkLimits
+	err := dnc.call(func(ctx context.Context) error {
+		resp, err := dnc.datanode.GetNetworkLimits(ctx, &dn.GetNetworkLimitsRequest{})
 		if err != nil {
-			errors <- err
+			return err
 		}
-	}()
+		limits = resp.Limits
+		return nil
+	})
+	return limits, err
+}
 
-	go func() {
-		defer wg.Done()
-		var err error
-		res.Deposits, err = dnc.listDeposits()
-		if err != nil {
-			errors <- err
+func (dnc *dataNodeClient) listAssets() ([]*vega.Asset, error) {
+	return listAll(dnc, "assets", func(ctx context.Context, pagination *dn.Pagination) ([]*vega.Asset, *dn.PageInfo, error) {
+		resp, err := dnc.datanode.ListAssets(ctx, &dn.ListAssetsRequest{Pagination: pagination})
+		if err != nil || resp.Assets == nil {
+			return nil, nil, err
+		}
+		assets := make([]*vega.Asset, 0, len(resp.Assets.Edges))
+		for _, a := range resp.Assets.Edges {
+			if a.Node.Status != vega.Asset_STATUS_REJECTED {
+				assets = append(assets, a.Node)
+			}
 		}
-	}()
+		return assets, resp.Assets.PageInfo, nil
+	})
+}
 
-	go func() {
-		defer wg.Done()
-		var err error
-		res.Withdrawals, err = dnc.listWithdrawals()
+func (dnc *dataNodeClient) getVegaTime() (int64, error) {
+	var vegaTime int64
+	err := dnc.call(func(ctx context.Context) error {
+		resp, err := dnc.datanode.GetVegaTime(ctx, &dn.GetVegaTimeRequest{})
 		if err != nil {
-			errors <- err
+			return err
 		}
-	}()
+		vegaTime = resp.Timestamp
+		return nil
+	})
+	return vegaTime, err
+}
 
-	go func() {
-		defer wg.Done()
-		var err error
-		res.Positions, err = dnc.listPositions()
-		if err != nil {
-			errors <- err
+func (dnc *dataNodeClient) listDelegations() ([]*vega.Delegation, error) {
+	epoch, err := dnc.pinnedEpoch()
+	if err != nil {
+		return nil, err
+	}
+	return listAll(dnc, "delegations", func(ctx context.Context, pagination *dn.Pagination) ([]*vega.Delegation, *dn.PageInfo, error) {
+		req := &dn.ListDelegationsRequest{Pagination: pagination}
+		if epoch != nil {
+			epochID := strconv.FormatUint(epoch.Seq, 10)
+			req.EpochId = &epochID
 		}
-	}()
-
-	go func() {
-		defer wg.Done()
-		var err error
-		res.Transfers, err = dnc.listTransfers()
-		if err != nil {
-			errors <- err
+		resp, err := dnc.datanode.ListDelegations(ctx, req)
+		if err != nil || resp.Delegations == nil {
+			return nil, nil, err
 		}
-	}()
-
-	go func() {
-		defer wg.Done()
-		var err error
-		res.Stake, err = dnc.getStake()
-		if err != nil {
-			errors <- err
+		delegations := make([]*vega.Delegation, 0, len(resp.Delegations.Edges))
+		for _, d := range resp.Delegations.Edges {
+			delegations = append(delegations, d.Node)
 		}
-	}()
+		return delegations, resp.Delegations.PageInfo, nil
+	})
+}
 
-	var resErr error
-	go func() {
-		for r := range errors {
-			resErr = r
-		}
-	}()
-	wg.Wait()
+// pinnedEpoch returns the epoch at the pinned height, or nil if not pinned.
+func (dnc *dataNodeClient) pinnedEpoch() (*vega.Epoch, error) {
+	if dnc.height == 0 {
+		return nil, nil
+	}
+	return dnc.getEpoch()
+}
 
-	return res, resErr
+func (dnc *dataNodeClient) getEpoch() (*vega.Epoch, error) {
+	return dnc.getEpochAt(dnc.height)
 }
 
-func (dnc *dataNodeClient) listAccounts() ([]*dn.AccountBalance, error) {
-	result := []*dn.AccountBalance{}
-
-	lastRecordCursor := ""
-	for {
-		requestInput := dn.ListAccountsRequest{}
-		if len(lastRecordCursor) > 0 {
-			requestInput = dn.ListAccountsRequest{
-				Pagination: &dn.Pagination{
-					After: &lastRecordCursor,
-				},
-			}
+// getEpochAt returns the epoch at a block height, the current one if 0.
+func (dnc *dataNodeClient) getEpochAt(height uint64) (*vega.Epoch, error) {
+	var epoch *vega.Epoch
+	err := dnc.call(func(ctx context.Context) error {
+		req := &dn.GetEpochRequest{}
+		if height > 0 {
+			req.Block = &height
 		}
-
-		accResp, err := dnc.datanode.ListAccounts(context.Background(), &requestInput)
+		resp, err := dnc.datanode.GetEpoch(ctx, req)
 		if err != nil {
-			return nil, err
+			return err
 		}
-
-		for _, ae := range accResp.Accounts.Edges {
-			result = append(result, ae.Node)
-		}
-
-		if accResp.Accounts == nil || accResp.Accounts.PageInfo == nil || !accResp.Accounts.PageInfo.HasNextPage {
-			break
+		epoch = &vega.Epoch{
+			Seq:        resp.Epoch.Seq,
+			Timestamps: resp.Epoch.Timestamps,
 		}
-
-		lastRecordCursor = accResp.Accounts.PageInfo.EndCursor
-	}
-
-	return result, nil
+		return nil
+	})
+	return epoch, err
 }
 
-func (dnc *dataNodeClient) listOrders() ([]*vega.Order, error) {
-	liveOnly := true
-	orderResp, err := dnc.datanode.ListOrders(context.Background(), &dn.ListOrdersRequest{
-		Filter: &dn.OrderFilter{
-			LiveOnly: &liveOnly},
-	})
+func (dnc *dataNodeClient) listNodes() ([]*vega.Node, error) {
+	epoch, err := dnc.pinnedEpoch()
 	if err != nil {
 		return nil, err
 	}
-	orders := make([]*vega.Order, 0, len(orderResp.Orders.Edges))
-	for _, oe := range orderResp.Orders.Edges {
-		if oe.Node.Status != vega.Order_STATUS_PARKED {
-			orders = append(orders, oe.Node)
-		}
-	}
-	return orders, nil
+	return listAll(dnc, "nodes", func(ctx context.Context, pagination *dn.Pagination) ([]*vega.Node, *dn.PageInfo, error) {
+		req := &dn.ListNodesRequest{Pagination: pagination}
+		if epoch != nil {
+			req.EpochSeq = &epoch.Seq
+		}
+		resp, err := dnc.datanode.ListNodes(ctx, req)
+		if err != nil || resp.Nodes == nil {
+			return nil, nil, err
+		}
+		nodes := make([]*vega.Node, 0, len(resp.Nodes.Edges))
+		for _, ne := range resp.Nodes.Edges {
+			nodes = append(nodes, &vega.Node{
+				Id:              ne.Node.Id,
+				PubKey:          ne.Node.PubKey,
+				TmPubKey:        ne.Node.TmPubKey,
+				EthereumAddress: crypto.EthereumChecksumAddress(ne.Node.EthereumAddress),
+				InfoUrl:         ne.Node.InfoUrl,
+				Location:        ne.Node.Location,
+				Status:          ne.Node.Status,
+				RankingScore:    ne.Node.RankingScore,
+				Name:            ne.Node.Name,
+				AvatarUrl:       ne.Node.AvatarUrl,
+			})
+		}
+		return nodes, resp.Nodes.PageInfo, nil
+	})
 }
 
-func (dnc *dataNodeClient) listMarkets() ([]*vega.Market, error) {
-	marketResp, err := dnc.datanode.ListMarkets(context.Background(), &dn.ListMarketsRequest{})
-	if err != nil {
-		return nil, err
-	}
-	markets := make([]*vega.Market, 0, len(marketResp.Markets.Edges))
-	for _, me := range marketResp.Markets.Edges {
-		markets = append(markets, me.Node)
-	}
-	return markets, nil
+func (dnc *dataNodeClient) listNetworkParameters() ([]*vega.NetworkParameter, error) {
+	return listAll(dnc, "netparams", func(ctx context.Context, pagination *dn.Pagination) ([]*vega.NetworkParameter, *dn.PageInfo, error) {
+		resp, err := dnc.datanode.ListNetworkParameters(ctx, &dn.ListNetworkParametersRequest{Pagination: pagination})
+		if err != nil || resp.NetworkParameters == nil {
+			return nil, nil, err
+		}
+		params := make([]*vega.NetworkParameter, 0, len(resp.NetworkParameters.Edges))
+		for _, npe := range resp.NetworkParameters.Edges {
+			params = append(params, npe.Node)
+		}
+		return params, resp.NetworkParameters.PageInfo, nil
+	})
 }
 
-func (dnc *dataNodeClient) listParties() ([]*vega.Party, error) {
-	response := []*vega.Party{}
-
-	lastRecordCursor := ""
-	for {
-		requestInput := &dn.ListPartiesRequest{}
-		if len(lastRecordCursor) > 0 {
-			requestInput = &dn.ListPartiesRequest{
-				Pagination: &dn.Pagination{
-					After: &lastRecordCursor,
-				},
+func (dnc *dataNodeClient) listGovernanceData() ([]*vega.Proposal, error) {
+	return listAll(dnc, "proposals", func(ctx context.Context, pagination *dn.Pagination) ([]*vega.Proposal, *dn.PageInfo, error) {
+		resp, err := dnc.datanode.ListGovernanceData(ctx, &dn.ListGovernanceDataRequest{Pagination: pagination})
+		if err != nil || resp.Connection == nil {
+			return nil, nil, err
+		}
+		proposals := make([]*vega.Proposal, 0, len(resp.Connection.Edges))
+		for _, gde := range resp.Connection.Edges {
+			if gde.Node.Proposal.State != vega.Proposal_STATE_DECLINED && gde.Node.Proposal.State != vega.Proposal_STATE_REJECTED {
+				proposals = append(proposals, gde.Node.Proposal)
 			}
 		}
+		return proposals, resp.Connection.PageInfo, nil
+	})
+}
 
-		partiesResp, err := dnc.datanode.ListParties(context.Background(), requestInput)
-		if err != nil {
-			return nil, err
+func (dnc *dataNodeClient) listDeposits() ([]*vega.Deposit, error) {
+	return listAll(dnc, "deposits", func(ctx context.Context, pagination *dn.Pagination) ([]*vega.Deposit, *dn.PageInfo, error) {
+		resp, err := dnc.datanode.ListDeposits(ctx, &dn.ListDepositsRequest{Pagination: pagination})
+		if err != nil || resp.Deposits == nil {
+			return nil, nil, err
 		}
-
-		for _, pe := range partiesResp.Parties.Edges {
-			response = append(response, pe.Node)
+		deposits := make([]*vega.Deposit, 0, len(resp.Deposits.Edges))
+		for _, de := range resp.Deposits.Edges {
+			deposits = append(deposits, de.Node)
 		}
+		return deposits, resp.Deposits.PageInfo, nil
+	})
+}
 
-		if partiesResp.Parties == nil || partiesResp.Parties.PageInfo == nil || !partiesResp.Parties.PageInfo.HasNextPage {
-			break
+func (dnc *dataNodeClient) listWithdrawals() ([]*vega.Withdrawal, error) {
+	return listAll(dnc, "withdrawals", func(ctx context.Context, pagination *dn.Pagination) ([]*vega.Withdrawal, *dn.PageInfo, error) {
+		resp, err := dnc.datanode.ListWithdrawals(ctx, &dn.ListWithdrawalsRequest{Pagination: pagination})
+		if err != nil || resp.Withdrawals == nil {
+			return nil, nil, err
 		}
+		withdrawals := make([]*vega.Withdrawal, 0, len(resp.Withdrawals.Edges))
+		for _, we := range resp.Withdrawals.Edges {
+			we.Node.Ext = nil
+			withdrawals = append(withdrawals, we.Node)
+		}
+		return withdrawals, resp.Withdrawals.PageInfo, nil
+	})
+}
 
-		lastRecordCursor = partiesResp.Parties.PageInfo.EndCursor
-	}
-
-	return response, nil
+func (dnc *dataNodeClient) listTransfers() ([]*v1.Transfer, error) {
+	return listAll(dnc, "transfers", func(ctx context.Context, pagination *dn.Pagination) ([]*v1.Transfer, *dn.PageInfo, error) {
+		resp, err := dnc.datanode.ListTransfers(ctx, &dn.ListTransfersRequest{Pagination: pagination})
+		if err != nil || resp.Transfers == nil {
+			return nil, nil, err
+		}
+		transfers := make([]*v1.Transfer, 0, len(resp.Transfers.Edges))
+		for _, te := range resp.Transfers.Edges {
+			transfers = append(transfers, te.Node.Transfer)
+		}
+		return transfers, resp.Transfers.PageInfo, nil
+	})
 }
 
-func (dnc *dataNodeClient) getNetworkLimits() (*vega.NetworkLimits, error) {
-	limitsResp, err := dnc.datanode.GetNetworkLimits(context.Background(), &dn.GetNetworkLimitsRequest{})
+// listAllLiquidityProvisions returns the live liquidity provisions of every market.
+func (dnc *dataNodeClient) listAllLiquidityProvisions() ([]*vega.LiquidityProvision, error) {
+	markets, err := dnc.listMarkets()
 	if err != nil {
 		return nil, err
 	}
-	return limitsResp.Limits, nil
+	return forEach(dnc, markets, func(m *vega.Market) ([]*vega.LiquidityProvision, error) {
+		return dnc.listLiquidityProvisions(m.Id)
+	})
 }
 
-func (dnc *dataNodeClient) listAssets() ([]*vega.Asset, error) {
-	assetResp, err := dnc.datanode.ListAssets(context.Background(), &dn.ListAssetsRequest{})
-	if err != nil {
-		return nil, err
-	}
-	assets := make([]*vega.Asset, 0, len(assetResp.Assets.Edges))
-	for _, a := range assetResp.Assets.Edges {
-		if a.Node.Status != vega.Asset_STATUS_REJECTED {
-			assets = append(assets, a.Node)
+func (dnc *dataNodeClient) listLiquidityProvisions(market string) ([]*vega.LiquidityProvision, error) {
+	live := true
+	return listAll(dnc, "liquidityProvisions", func(ctx context.Context, pagination *dn.Pagination) ([]*vega.LiquidityProvision, *dn.PageInfo, error) {
+		resp, err := dnc.datanode.ListLiquidityProvisions(ctx, &dn.ListLiquidityProvisionsRequest{MarketId: &market, Live: &live, Pagination: pagination})
+		if err != nil || resp.LiquidityProvisions == nil {
+			return nil, nil, err
+		}
+		lps := make([]*vega.LiquidityProvision, 0, len(resp.LiquidityProvisions.Edges))
+		for _, lpe := range resp.LiquidityProvisions.Edges {
+			if lpe.Node.Status == vega.LiquidityProvision_STATUS_PENDING || lpe.Node.Status == vega.LiquidityProvision_STATUS_ACTIVE || lpe.Node.Status == vega.LiquidityProvision_STATUS_UNDEPLOYED {
+				lps = append(lps, lpe.Node)
+			}
 		}
-	}
-	return assets, nil
+		return lps, resp.LiquidityProvisions.PageInfo, nil
+	})
 }
 
-func (dnc *dataNodeClient) getVegaTime() (int64, error) {
-	vegaTimeResp, err := dnc.datanode.GetVegaTime(context.Background(), &dn.GetVegaTimeRequest{})
+func (dnc *dataNodeClient) getStake() ([]*v1.StakeLinking, error) {
+	parties, err := dnc.listParties()
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	return vegaTimeResp.Timestamp, nil
-}
 
-func (dnc *dataNodeClient) listDelegations() ([]*vega.Delegation, error) {
-	result := []*vega.Delegation{}
-
-	lastRecordCursor := ""
-	for {
-		requestInput := dn.ListDelegationsRequest{}
-		if len(lastRecordCursor) > 0 {
-			requestInput = dn.ListDelegationsRequest{
-				Pagination: &dn.Pagination{
-					After: &lastRecordCursor,
-				},
+	return forEach(dnc, parties, func(p *vega.Party) ([]*v1.StakeLinking, error) {
+		return listAll(dnc, "stake", func(ctx context.Context, pagination *dn.Pagination) ([]*v1.StakeLinking, *dn.PageInfo, error) {
+			resp, err := dnc.datanode.GetStake(ctx, &dn.GetStakeRequest{PartyId: p.Id, Pagination: pagination})
+			if err != nil || resp.StakeLinkings == nil {
+				return nil, nil, err
 			}
-		}
+			stake := []*v1.StakeLinking{}
+			for _, sle := range resp.StakeLinkings.Edges {
+				// ignore 0 amounts
+				if sle.Node.Amount != "0" {
+					stake = append(stake, sle.Node)
+				}
+			}
+			return stake, resp.StakeLinkings.PageInfo, nil
+		})
+	})
+}
 
-		delegationResp, err := dnc.datanode.ListDelegations(context.Background(), &requestInput)
-		if err != nil {
-			return nil, err
+func (dnc *dataNodeClient) listLatestMarketData() ([]*vega.MarketData, error) {
+	var resp *dn.ListLatestMarketDataResponse
+	err := dnc.call(func(ctx context.Context) (err error) {
+		resp, err = dnc.datanode.ListLatestMarketData(ctx, &dn.ListLatestMarketDataRequest{})
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	marketData := make([]*vega.MarketData, 0, len(resp.MarketsData))
+	for _, md := range resp.MarketsData {
+		if md.MarketState != vega.Market_STATE_ACTIVE && md.MarketState != vega.Market_STATE_SUSPENDED &&
+			md.MarketState != vega.Market_STATE_PENDING && md.MarketState != vega.Market_STATE_SUSPENDED_VIA_GOVERNANCE {
+			continue
 		}
-
-		for _, d := range delegationResp.Delegations.Edges {
-			result = append(result, d.Node)
+		d := &vega.MarketData{
+			Market:            md.Market,
+			MarkPrice:         md.MarkPrice,
+			MarketTradingMode: md.MarketTradingMode,
+			OpenInterest:      md.OpenInterest,
 		}
-
-		if delegationResp.Delegations == nil || delegationResp.Delegations.PageInfo == nil || !delegationResp.Delegations.PageInfo.HasNextPage {
-			break
+		if md.MarketTradingMode != vega.Market_TRADING_MODE_CONTINUOUS {
+			d.Trigger = md.Trigger
+			d.AuctionStart = md.AuctionStart
 		}
-
-		lastRecordCursor = delegationResp.Delegations.PageInfo.EndCursor
+		marketData = append(marketData, d)
 	}
+	return marketData, nil
+}
 
-	return result, nil
+func (dnc *dataNodeClient) listStopOrders() ([]*vega.StopOrder, error) {
+	return listAll(dnc, "stopOrders", func(ctx context.Context, pagination *dn.Pagination) ([]*vega.StopOrder, *dn.PageInfo, error) {
+		resp, err := dnc.datanode.ListStopOrders(ctx, &dn.ListStopOrdersRequest{
+			Filter:     &dn.StopOrderFilter{Statuses: []vega.StopOrder_Status{vega.StopOrder_STATUS_PENDING}},
+			Pagination: pagination,
+		})
+		if err != nil || resp.Orders == nil {
+			return nil, nil, err
+		}
+		stopOrders := make([]*vega.StopOrder, 0, len(resp.Orders.Edges))
+		for _, e := range resp.Orders.Edges {
+			stopOrders = append(stopOrders, e.Node.StopOrder)
+		}
+		return stopOrders, resp.Orders.PageInfo, nil
+	})
 }
 
-func (dnc *dataNodeClient) getEpoch() (*vega.Epoch, error) {
-	epochResp, err := dnc.datanode.GetEpoch(context.Background(), &dn.GetEpochRequest{})
+// listFeesStats returns the fees stats of each market for the epoch of the snapshot height. Data node only has the
+// stats of an epoch once it has ended, until then pending is set and none are returned.
+func (dnc *dataNodeClient) listFeesStats() (feesStats []*v1.FeesStats, pending bool, err error) {
+	epoch, err := dnc.getEpoch()
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
-
-	return &vega.Epoch{
-		Seq:        epochResp.Epoch.Seq,
-		Timestamps: epochResp.Epoch.Timestamps,
-	}, nil
-}
-
-func (dnc *dataNodeClient) listNodes() ([]*vega.Node, error) {
-	nodeResp, err := dnc.datanode.ListNodes(context.Background(), &dn.ListNodesRequest{})
+	latest, err := dnc.getEpochAt(0)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
-	nodes := make([]*vega.Node, 0, len(nodeResp.Nodes.Edges))
-	for _, ne := range nodeResp.Nodes.Edges {
-		nodes = append(nodes, &vega.Node{
-			Id:              ne.Node.Id,
-			PubKey:          ne.Node.PubKey,
-			TmPubKey:        ne.Node.TmPubKey,
-			EthereumAddress: crypto.EthereumChecksumAddress(ne.Node.EthereumAddress),
-			InfoUrl:         ne.Node.InfoUrl,
-			Location:        ne.Node.Location,
-			Status:          ne.Node.Status,
-			RankingScore:    ne.Node.RankingScore,
-			Name:            ne.Node.Name,
-			AvatarUrl:       ne.Node.AvatarUrl,
-		})
+	if latest.Seq <= epoch.Seq {
+		return nil, true, nil
 	}
-	return nodes, nil
-}
-
-func (dnc *dataNodeClient) listNetworkParameters() ([]*vega.NetworkParameter, error) {
-	resp, err := dnc.datanode.ListNetworkParameters(context.Background(), &dn.ListNetworkParametersRequest{})
+	markets, err := dnc.listMarkets()
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
-	params := make([]*vega.NetworkParameter, 0, len(resp.NetworkParameters.Edges))
-	for _, npe := range resp.NetworkParameters.Edges {
-		params = append(params, npe.Node)
-	}
-	return params, nil
+	feesStats, err = forEach(dnc, markets, func(m *vega.Market) ([]*v1.FeesStats, error) {
+		marketID := m.Id
+		var resp *dn.GetFeesStatsResponse
+		err := dnc.call(func(ctx context.Context) (err error) {
+			resp, err = dnc.datanode.GetFeesStats(ctx, &dn.GetFeesStatsRequest{MarketId: &marketID, EpochSeq: &epoch.Seq})
+			return
+		})
+		// markets without any trade in the epoch have no stats
+		if status.Code(err) == codes.NotFound {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []*v1.FeesStats{resp.FeesStats}, nil
+	})
+	return feesStats, false, err
 }
 
-func (dnc *dataNodeClient) listGovernanceData() ([]*vega.Proposal, error) {
-	resp, err := dnc.datanode.ListGovernanceData(context.Background(), &dn.ListGovernanceDataRequest{})
+// listVestingBalances returns the locked and vesting reward balances of every party that has any.
+func (dnc *dataNodeClient) listVestingBalances() ([]*dn.GetVestingBalancesSummaryResponse, error) {
+	parties, err := dnc.listParties()
 	if err != nil {
 		return nil, err
 	}
-	proposals := make([]*vega.Proposal, 0, len(resp.Connection.Edges))
-	for _, gde := range resp.Connection.Edges {
-		if gde.Node.Proposal.State != vega.Proposal_STATE_DECLINED && gde.Node.Proposal.State != vega.Proposal_STATE_REJECTED {
-			proposals = append(proposals, gde.Node.Proposal)
+
+	return forEach(dnc, parties, func(p *vega.Party) ([]*dn.GetVestingBalancesSummaryResponse, error) {
+		var resp *dn.GetVestingBalancesSummaryResponse
+		err := dnc.call(func(ctx context.Context) (err error) {
+			resp, err = dnc.datanode.GetVestingBalancesSummary(ctx, &dn.GetVestingBalancesSummaryRequest{PartyId: p.Id})
+			return
+		})
+		if err != nil {
+			return nil, err
 		}
-	}
-	return proposals, nil
+		dnc.progress.add("vestingBalances", 1)
+		if len(resp.LockedBalances) == 0 && len(resp.VestingBalances) == 0 {
+			return nil, nil
+		}
+		b := &dn.GetVestingBalancesSummaryResponse{
+			PartyId:         p.Id,
+			LockedBalances:  resp.LockedBalances,
+			VestingBalances: resp.VestingBalances,
+		}
+		sortVestingBalances(b)
+		return []*dn.GetVestingBalancesSummaryResponse{b}, nil
+	})
 }
 
-func (dnc *dataNodeClient) listDeposits() ([]*vega.Deposit, error) {
-	resp, err := dnc.datanode.ListDeposits(context.Background(), &dn.ListDepositsRequest{})
+// getReferralPrograms returns the current referral program, if there is one that has not ended.
+func (dnc *dataNodeClient) getReferralPrograms() ([]*vega.ReferralProgram, error) {
+	var resp *dn.GetCurrentReferralProgramResponse
+	err := dnc.call(func(ctx context.Context) (err error) {
+		resp, err = dnc.datanode.GetCurrentReferralProgram(ctx, &dn.GetCurrentReferralProgramRequest{})
+		return
+	})
+	if status.Code(err) == codes.NotFound {
+		return []*vega.ReferralProgram{}, nil
+	}
 	if err != nil {
 		return nil, err
 	}
-	deposits := make([]*vega.Deposit, 0, len(resp.Deposits.Edges))
-	for _, de := range resp.Deposits.Edges {
-		deposits = append(deposits, de.Node)
-	}
-	return deposits, nil
+	p := resp.CurrentReferralProgram
+	if p == nil || p.EndedAt != nil {
+		return []*vega.ReferralProgram{}, nil
+	}
+	return []*vega.ReferralProgram{{
+		Version:               p.Version,
+		Id:                    p.Id,
+		BenefitTiers:          p.BenefitTiers,
+		EndOfProgramTimestamp: p.EndOfProgramTimestamp,
+		WindowLength:          p.WindowLength,
+		StakingTiers:          p.StakingTiers,
+	}}, nil
 }
 
-func (dnc *dataNodeClient) listWithdrawals() ([]*vega.Withdrawal, error) {
-	resp, err := dnc.datanode.ListWithdrawals(context.Background(), &dn.ListWithdrawalsRequest{})
-	if err != nil {
-		return nil, err
-	}
-	withdrawals := make([]*vega.Withdrawal, 0, len(resp.Withdrawals.Edges))
-	for _, we := range resp.Withdrawals.Edges {
-		we.Node.Ext = nil
-		withdrawals = append(withdrawals, we.Node)
-	}
-	return withdrawals, nil
+func (dnc *dataNodeClient) listReferralSets() ([]*dn.ReferralSet, error) {
+	return listAll(dnc, "referralSets", func(ctx context.Context, pagination *dn.Pagination) ([]*dn.ReferralSet, *dn.PageInfo, error) {
+		resp, err := dnc.datanode.ListReferralSets(ctx, &dn.ListReferralSetsRequest{Pagination: pagination})
+		if err != nil || resp.ReferralSets == nil {
+			return nil, nil, err
+		}
+		sets := make([]*dn.ReferralSet, 0, len(resp.ReferralSets.Edges))
+		for _, e := range resp.ReferralSets.Edges {
+			sets = append(sets, &dn.ReferralSet{
+				Id:        e.Node.Id,
+				Referrer:  e.Node.Referrer,
+				CreatedAt: e.Node.CreatedAt,
+				UpdatedAt: e.Node.UpdatedAt,
+			})
+		}
+		return sets, resp.ReferralSets.PageInfo, nil
+	})
 }
 
-func (dnc *dataNodeClient) listTransfers() ([]*v1.Transfer, error) {
-	resp, err := dnc.datanode.ListTransfers(context.Background(), &dn.ListTransfersRequest{})
-	if err != nil {
-		return nil, err
-	}
-	transfers := make([]*v1.Transfer, 0, len(resp.Transfers.Edges))
-	for _, te := range resp.Transfers.Edges {
-		transfers = append(transfers, te.Node.Transfer)
-	}
-	return transfers, nil
+func (dnc *dataNodeClient) listReferralSetReferees() ([]*dn.ReferralSetReferee, error) {
+	return listAll(dnc, "referralSetReferees", func(ctx context.Context, pagination *dn.Pagination) ([]*dn.ReferralSetReferee, *dn.PageInfo, error) {
+		resp, err := dnc.datanode.ListReferralSetReferees(ctx, &dn.ListReferralSetRefereesRequest{Pagination: pagination})
+		if err != nil || resp.ReferralSetReferees == nil {
+			return nil, nil, err
+		}
+		referees := make([]*dn.ReferralSetReferee, 0, len(resp.ReferralSetReferees.Edges))
+		for _, e := range resp.ReferralSetReferees.Edges {
+			referees = append(referees, &dn.ReferralSetReferee{
+				ReferralSetId: e.Node.ReferralSetId,
+				Referee:       e.Node.Referee,
+				JoinedAt:      e.Node.JoinedAt,
+				AtEpoch:       e.Node.AtEpoch,
+			})
+		}
+		return referees, resp.ReferralSetReferees.PageInfo, nil
+	})
 }
 
-func (dnc *dataNodeClient) listPositions() ([]*vega.Position, error) {
-	resp, err := dnc.datanode.ListPositions(context.Background(), &dn.ListPositionsRequest{})
-	if err != nil {
-		return nil, err
-	}
-	positions := make([]*vega.Position, 0, len(resp.Positions.Edges))
-	for _, pe := range resp.Positions.Edges {
-		positions = append(positions, pe.Node)
-	}
-	return positions, nil
+func (dnc *dataNodeClient) listTeams() ([]*dn.Team, error) {
+	return dnc.teams()
 }
 
-func (dnc *dataNodeClient) listLiquidityProvisions(market string) ([]*vega.LiquidityProvision, error) {
-	live := true
-	resp, err := dnc.datanode.ListLiquidityProvisions(context.Background(), &dn.ListLiquidityProvisionsRequest{MarketId: &market, Live: &live})
-	if err != nil {
-		return nil, err
-	}
-	lps := make([]*vega.LiquidityProvision, 0, len(resp.LiquidityProvisions.Edges))
-	for _, lpe := range resp.LiquidityProvisions.Edges {
-		if lpe.Node.Status == vega.LiquidityProvision_STATUS_PENDING || lpe.Node.Status == vega.LiquidityProvision_STATUS_ACTIVE || lpe.Node.Status == vega.LiquidityProvision_STATUS_UNDEPLOYED {
-			lps = append(lps, lpe.Node)
-		}
-	}
-	return lps, nil
+func (dnc *dataNodeClient) fetchTeams() ([]*dn.Team, error) {
+	return listAll(dnc, "teams", func(ctx context.Context, pagination *dn.Pagination) ([]*dn.Team, *dn.PageInfo, error) {
+		resp, err := dnc.datanode.ListTeams(ctx, &dn.ListTeamsRequest{Pagination: pagination})
+		if err != nil || resp.Teams == nil {
+			return nil, nil, err
+		}
+		teams := make([]*dn.Team, 0, len(resp.Teams.Edges))
+		for _, e := range resp.Teams.Edges {
+			teams = append(teams, &dn.Team{
+				TeamId:    e.Node.TeamId,
+				Referrer:  e.Node.Referrer,
+				Name:      e.Node.Name,
+				TeamUrl:   e.Node.TeamUrl,
+				AvatarUrl: e.Node.AvatarUrl,
+				CreatedAt: e.Node.CreatedAt,
+				Closed:    e.Node.Closed,
+			})
+		}
+		return teams, resp.Teams.PageInfo, nil
+	})
 }
 
-func (dnc *dataNodeClient) getStake() ([]*v1.StakeLinking, error) {
-	parties, err := dnc.listParties()
+func (dnc *dataNodeClient) listTeamReferees() ([]*dn.TeamReferee, error) {
+	teams, err := dnc.listTeams()
 	if err != nil {
 		return nil, err
 	}
 
-	stake := []*v1.StakeLinking{}
-	for _, p := range parties {
-		resp, err := dnc.datanode.GetStake(context.Background(), &dn.GetStakeRequest{PartyId: p.Id})
-		if err != nil {
-			return stake, err
-		}
-		for _, sle := range resp.StakeLinkings.Edges {
-			// ignore 0 amounts
-			if sle.Node.Amount != "0" {
-				stake = append(stake, sle.Node)
+	return forEach(dnc, teams, func(t *dn.Team) ([]*dn.TeamReferee, error) {
+		return listAll(dnc, "teamReferees", func(ctx context.Context, pagination *dn.Pagination) ([]*dn.TeamReferee, *dn.PageInfo, error) {
+			resp, err := dnc.datanode.ListTeamReferees(ctx, &dn.ListTeamRefereesRequest{TeamId: t.TeamId, Pagination: pagination})
+			if err != nil || resp.TeamReferees == nil {
+				return nil, nil, err
+			}
+			referees := make([]*dn.TeamReferee, 0, len(resp.TeamReferees.Edges))
+			for _, e := range resp.TeamReferees.Edges {
+				referees = append(referees, &dn.TeamReferee{
+					TeamId:        e.Node.TeamId,
+					Referee:       e.Node.Referee,
+					JoinedAt:      e.Node.JoinedAt,
+					JoinedAtEpoch: e.Node.JoinedAtEpoch,
+				})
+			}
+			return referees, resp.TeamReferees.PageInfo, nil
+		})
+	})
+}
+
+// listOracleSpecs returns the ID and definition of the active data source specs.
+func (dnc *dataNodeClient) listOracleSpecs() ([]*vega.DataSourceSpec, error) {
+	return listAll(dnc, "oracleSpecs", func(ctx context.Context, pagination *dn.Pagination) ([]*vega.DataSourceSpec, *dn.PageInfo, error) {
+		resp, err := dnc.datanode.ListOracleSpecs(ctx, &dn.ListOracleSpecsRequest{Pagination: pagination})
+		if err != nil || resp.OracleSpecs == nil {
+			return nil, nil, err
+		}
+		specs := make([]*vega.DataSourceSpec, 0, len(resp.OracleSpecs.Edges))
+		for _, e := range resp.OracleSpecs.Edges {
+			spec := e.Node.GetExternalDataSourceSpec().GetSpec()
+			if spec != nil && spec.Status == vega.DataSourceSpec_STATUS_ACTIVE {
+				specs = append(specs, &vega.DataSourceSpec{Id: spec.Id, Data: spec.Data})
 			}
 		}
-	}
-	return stake, nil
+		return specs, resp.OracleSpecs.PageInfo, nil
+	})
 }
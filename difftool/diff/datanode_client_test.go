@@ -0,0 +1,109 @@
+package diff
+
+import (
+	"context"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	dn "code.vegaprotocol.io/vega/protos/data-node/api/v2"
+	"code.vegaprotocol.io/vega/protos/vega"
+	v1 "code.vegaprotocol.io/vega/protos/vega/events/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeDatanode serves the teams and their referees, the markets, the epochs and the fees stats, the calls it does
+// not implement panic.
+type fakeDatanode struct {
+	dn.TradingDataServiceClient
+	teams     []string
+	teamCalls atomic.Int32
+	markets   []string
+	// epoch is the current epoch, and snapshotEpoch the epoch at any earlier block
+	epoch, snapshotEpoch uint64
+	// feesStats are the markets with fees stats for snapshotEpoch
+	feesStats []string
+}
+
+func (f *fakeDatanode) ListTeams(_ context.Context, _ *dn.ListTeamsRequest, _ ...grpc.CallOption) (*dn.ListTeamsResponse, error) {
+	f.teamCalls.Add(1)
+	edges := []*dn.TeamEdge{}
+	for _, id := range f.teams {
+		edges = append(edges, &dn.TeamEdge{Node: &dn.Team{TeamId: id}})
+	}
+	return &dn.ListTeamsResponse{Teams: &dn.TeamConnection{Edges: edges, PageInfo: &dn.PageInfo{}}}, nil
+}
+
+func (f *fakeDatanode) ListTeamReferees(_ context.Context, req *dn.ListTeamRefereesRequest, _ ...grpc.CallOption) (*dn.ListTeamRefereesResponse, error) {
+	edges := []*dn.TeamRefereeEdge{{Node: &dn.TeamReferee{TeamId: req.TeamId, Referee: "referee-of-" + req.TeamId}}}
+	return &dn.ListTeamRefereesResponse{TeamReferees: &dn.TeamRefereeConnection{Edges: edges, PageInfo: &dn.PageInfo{}}}, nil
+}
+
+func (f *fakeDatanode) ListMarkets(_ context.Context, _ *dn.ListMarketsRequest, _ ...grpc.CallOption) (*dn.ListMarketsResponse, error) {
+	edges := []*dn.MarketEdge{}
+	for _, id := range f.markets {
+		edges = append(edges, &dn.MarketEdge{Node: &vega.Market{Id: id}})
+	}
+	return &dn.ListMarketsResponse{Markets: &dn.MarketConnection{Edges: edges, PageInfo: &dn.PageInfo{}}}, nil
+}
+
+func (f *fakeDatanode) GetEpoch(_ context.Context, req *dn.GetEpochRequest, _ ...grpc.CallOption) (*dn.GetEpochResponse, error) {
+	if req.Block != nil {
+		return &dn.GetEpochResponse{Epoch: &vega.Epoch{Seq: f.snapshotEpoch}}, nil
+	}
+	return &dn.GetEpochResponse{Epoch: &vega.Epoch{Seq: f.epoch}}, nil
+}
+
+func (f *fakeDatanode) GetFeesStats(_ context.Context, req *dn.GetFeesStatsRequest, _ ...grpc.CallOption) (*dn.GetFeesStatsResponse, error) {
+	if *req.EpochSeq == f.snapshotEpoch && slices.Contains(f.feesStats, *req.MarketId) {
+		return &dn.GetFeesStatsResponse{FeesStats: &v1.FeesStats{Market: *req.MarketId, EpochSeq: *req.EpochSeq}}, nil
+	}
+	return nil, status.Error(codes.NotFound, "no fees stats")
+}
+
+func newTestClient(datanode dn.TradingDataServiceClient) *dataNodeClient {
+	return newClient(datanode, Opts{DatanodeConcurrency: 2, DatanodeTimeout: time.Second})
+}
+
+func TestTeamsAreFetchedOnce(t *testing.T) {
+	datanode := &fakeDatanode{teams: []string{"t1", "t2"}}
+	dnc := newTestClient(datanode)
+
+	teams, err := dnc.listTeams()
+	require.NoError(t, err)
+	require.Len(t, teams, 2)
+
+	referees, err := dnc.listTeamReferees()
+	require.NoError(t, err)
+	require.Len(t, referees, 2)
+	assert.Equal(t, "referee-of-t1", referees[0].Referee)
+	assert.Equal(t, "referee-of-t2", referees[1].Referee)
+
+	assert.Equal(t, int32(1), datanode.teamCalls.Load())
+}
+
+func TestFeesStatsArePendingUntilTheEpochEnds(t *testing.T) {
+	datanode := &fakeDatanode{markets: []string{"m1", "m2"}, epoch: 7, snapshotEpoch: 7, feesStats: []string{"m1"}}
+	dnc := newTestClient(datanode)
+	dnc.height = 100
+
+	feesStats, pending, err := dnc.listFeesStats()
+	require.NoError(t, err)
+	assert.True(t, pending)
+	assert.Empty(t, feesStats)
+
+	// once the epoch has ended the stats of the markets traded in it are returned
+	datanode.epoch = 8
+	feesStats, pending, err = dnc.listFeesStats()
+	require.NoError(t, err)
+	assert.False(t, pending)
+	require.Len(t, feesStats, 1)
+	assert.Equal(t, "m1", feesStats[0].Market)
+	assert.Equal(t, uint64(7), feesStats[0].EpochSeq)
+}
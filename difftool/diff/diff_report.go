@@ -7,40 +7,27 @@ import (
 	dnproto "code.vegaprotocol.io/vega/protos/data-node/api/v2"
 	"code.vegaprotocol.io/vega/protos/vega"
 	v1 "code.vegaprotocol.io/vega/protos/vega/events/v1"
+	"google.golang.org/protobuf/proto"
 )
 
-func newDiffReport(coreResult *Result, datanodeResult *Result) *Report {
+func newDiffReport(coreResult *Result, datanodeResult *Result, domains []domain) *Report {
 	d := &Report{
 		coreResult:     coreResult,
 		datanodeResult: datanodeResult,
+		sides:          coreDatanodeSides,
 		DiffResult:     []Status{},
 		Success:        true,
 	}
-	d.diff()
+	diffFuncs := make([]func(*Result, *Result) Status, 0, len(domains))
+	for _, dom := range domains {
+		diffFuncs = append(diffFuncs, dom.diff)
+	}
+	d.run(diffFuncs)
 	return d
 }
 
-func (dr *Report) diff() {
-	diffFuncs := []func(*Result, *Result) Status{
-		diffAccountBalances,
-		diffOrders,
-		diffMarkets,
-		diffParties,
-		diffLimits,
-		diffAssets,
-		diffDelegations,
-		diffEpoch,
-		diffVegaTime,
-		diffNodes,
-		diffNetParams,
-		diffProposals,
-		diffDeposits,
-		diffWithdrawals,
-		diffLPs,
-		diffStake,
-		diffTransfers,
-	}
-
+// run runs each of the diff functions and collects their results.
+func (dr *Report) run(diffFuncs []func(*Result, *Result) Status) {
 	for _, v := range diffFuncs {
 		r := v(dr.coreResult, dr.datanodeResult)
 		if r.MatchResult != FullMatch {
@@ -86,7 +73,7 @@ func diffAccountBalances(coreSnapshot *Result, dn *Result) Status {
 		id := a.Owner + a.MarketId + a.Asset + a.Type.String()
 		if d, ok := dnData[id]; ok {
 			if a.String() != d.String() && a.Type != vega.AccountType_ACCOUNT_TYPE_EXTERNAL {
-				return getValueMismatchStatus("accounts", core, datanode)
+				return getValueMismatchStatus("accounts", core, datanode, accountKey)
 			}
 		}
 	}
@@ -103,7 +90,7 @@ func diffOrders(coreSnapshot *Result, dn *Result) Status {
 	sort.Slice(datanode, func(i, j int) bool { return datanode[i].Id < datanode[j].Id })
 
 	if len(core) != len(datanode) {
-		return getSizeMismatchStatus("orders", core, datanode)
+		return getSizeMismatchStatus("orders", core, datanode, orderKey)
 	}
 
 	for i, a := range core {
@@ -111,7 +98,7 @@ func diffOrders(coreSnapshot *Result, dn *Result) Status {
 		// core may increment UpdatedAt, but if nothing changes it doesn't send an event
 		d.UpdatedAt = a.UpdatedAt
 		if a.String() != d.String() {
-			return getValueMismatchStatus("orders", core, datanode)
+			return getValueMismatchStatus("orders", core, datanode, orderKey)
 		}
 	}
 
@@ -136,13 +123,13 @@ func diffMarkets(coreSnapshot *Result, dn *Result) Status {
 	sort.Slice(core, func(i, j int) bool { return core[i].Id < core[j].Id })
 	sort.Slice(datanode, func(i, j int) bool { return datanode[i].Id < datanode[j].Id })
 	if len(core) != len(datanode) {
-		return getSizeMismatchStatus("markets", core, datanode)
+		return getSizeMismatchStatus("markets", core, datanode, marketKey)
 	}
 
 	for i, a := range core {
 		d := datanode[i]
 		if a.String() != d.String() {
-			return getValueMismatchStatus("markets", core, datanode)
+			return getValueMismatchStatus("markets", core, datanode, marketKey)
 		}
 	}
 
@@ -158,12 +145,12 @@ func diffParties(coreSnapshot *Result, dn *Result) Status {
 	sort.Slice(datanode, func(i, j int) bool { return datanode[i].Id < datanode[j].Id })
 
 	if len(core) != len(datanode) {
-		return getSizeMismatchStatus("parties", core, datanode)
+		return getSizeMismatchStatus("parties", core, datanode, partyKey)
 	}
 
 	for i, a := range core {
 		if a.String() != datanode[i].String() {
-			return getValueMismatchStatus("parties", core, datanode)
+			return getValueMismatchStatus("parties", core, datanode, partyKey)
 		}
 	}
 
@@ -176,7 +163,7 @@ func diffLimits(coreSnapshot *Result, dn *Result) Status {
 	datanode := dn.Limits
 
 	if core.String() != datanode.String() {
-		return getSimpleValueMismatchStatus("limits", core.String(), datanode.String())
+		return getMessageMismatchStatus("limits", core, datanode)
 	}
 
 	return getSimpleSuccessStatus("limits")
@@ -191,12 +178,12 @@ func diffAssets(coreSnapshot *Result, dn *Result) Status {
 	sort.Slice(datanode, func(i, j int) bool { return datanode[i].Id < datanode[j].Id })
 
 	if len(core) != len(datanode) {
-		return getSizeMismatchStatus("assets", core, datanode)
+		return getSizeMismatchStatus("assets", core, datanode, assetKey)
 	}
 
 	for i, a := range core {
 		if a.String() != datanode[i].String() {
-			return getValueMismatchStatus("assets", core, datanode)
+			return getValueMismatchStatus("assets", core, datanode, assetKey)
 		}
 	}
 
@@ -231,12 +218,12 @@ func diffDelegations(coreSnapshot *Result, dn *Result) Status {
 		return ai.EpochSeq+"_"+ai.NodeId+"_"+ai.Party < aj.EpochSeq+"_"+aj.NodeId+"_"+aj.Party
 	})
 	if len(core) != len(datanode) {
-		return getSizeMismatchStatus("delegations", core, datanode)
+		return getSizeMismatchStatus("delegations", core, datanode, delegationKey)
 	}
 	for i, a := range core {
 		d := datanode[i]
 		if a.String() != d.String() {
-			getValueMismatchStatus("delegations", core, datanode)
+			getValueMismatchStatus("delegations", core, datanode, delegationKey)
 		}
 	}
 
@@ -249,7 +236,7 @@ func diffEpoch(coreSnapshot *Result, dn *Result) Status {
 	datanode := dn.Epoch
 
 	if core.Seq != datanode.Seq || core.Timestamps.StartTime != datanode.Timestamps.StartTime || core.Timestamps.ExpiryTime != datanode.Timestamps.ExpiryTime {
-		return getSimpleValueMismatchStatus("epoch", core.String(), datanode.String())
+		return getMessageMismatchStatus("epoch", core, datanode)
 	}
 
 	return getSimpleSuccessStatus("epoch")
@@ -277,12 +264,12 @@ func diffNodes(coreSnapshot *Result, dn *Result) Status {
 	sort.Slice(datanode, func(i, j int) bool { return datanode[i].Id < datanode[j].Id })
 
 	if len(core) != len(datanode) {
-		return getSizeMismatchStatus("nodes", core, datanode)
+		return getSizeMismatchStatus("nodes", core, datanode, nodeKey)
 	}
 	for i, a := range core {
 		d := datanode[i]
 		if a.String() != d.String() {
-			return getValueMismatchStatus("nodes", core, datanode)
+			return getValueMismatchStatus("nodes", core, datanode, nodeKey)
 		}
 	}
 	return getSuccessStatus("nodes", core, datanode)
@@ -296,12 +283,12 @@ func diffNetParams(coreSnapshot *Result, dn *Result) Status {
 	sort.Slice(datanode, func(i, j int) bool { return datanode[i].Key < datanode[j].Key })
 
 	if len(core) != len(datanode) {
-		return getSizeMismatchStatus("netparams", core, datanode)
+		return getSizeMismatchStatus("netparams", core, datanode, netParamKey)
 	}
 
 	for i, a := range core {
 		if a.String() != datanode[i].String() {
-			return getValueMismatchStatus("netparams", core, datanode)
+			return getValueMismatchStatus("netparams", core, datanode, netParamKey)
 		}
 	}
 
@@ -316,13 +303,13 @@ func diffProposals(coreSnapshot *Result, dn *Result) Status {
 	sort.Slice(datanode, func(i, j int) bool { return datanode[i].Id < datanode[j].Id })
 
 	if len(core) != len(datanode) {
-		return getSizeMismatchStatus("proposals", core, datanode)
+		return getSizeMismatchStatus("proposals", core, datanode, proposalKey)
 	}
 
 	for i, a := range core {
 		d := datanode[i]
 		if a.String() != d.String() {
-			return getValueMismatchStatus("proposals", core, datanode)
+			return getValueMismatchStatus("proposals", core, datanode, proposalKey)
 		}
 		// if a.Id != d.Id ||
 		// 	a.Reference != d.Reference ||
@@ -394,13 +381,13 @@ func diffDeposits(coreSnapshot *Result, dn *Result) Status {
 	sort.Slice(core, func(i, j int) bool { return core[i].Id < core[j].Id })
 	sort.Slice(datanode, func(i, j int) bool { return datanode[i].Id < datanode[j].Id })
 	if len(core) != len(datanode) {
-		return getSizeMismatchStatus("deposits", core, datanode)
+		return getSizeMismatchStatus("deposits", core, datanode, depositKey)
 	}
 
 	for i, a := range core {
 		d := datanode[i]
 		if a.String() != d.String() {
-			return getValueMismatchStatus("deposits", core, datanode)
+			return getValueMismatchStatus("deposits", core, datanode, depositKey)
 		}
 	}
 
@@ -440,7 +427,15 @@ func diffWithdrawals(coreSnapshot *Result, dn *Result) Status {
 
 	for _, id := range intersection {
 		if coreByID[id].String() != datanodeByID[id].String() {
-			return getValueMismatchStatus("withdrawals", core, datanode)
+			coreBoth := make([]*vega.Withdrawal, 0, len(intersection))
+			datanodeBoth := make([]*vega.Withdrawal, 0, len(intersection))
+			for _, id := range intersection {
+				coreBoth = append(coreBoth, coreByID[id])
+				datanodeBoth = append(datanodeBoth, datanodeByID[id])
+			}
+			status := getValueMismatchStatus("withdrawals", coreBoth, datanodeBoth, withdrawalKey)
+			status.CoreResLen, status.DataNodeLen = len(core), len(datanode)
+			return status
 		}
 	}
 
@@ -465,11 +460,11 @@ func diffLPs(coreSnapshot *Result, dn *Result) Status {
 	sort.Slice(datanode, func(i, j int) bool { return datanode[i].Id < datanode[j].Id })
 
 	if len(core) != len(datanode) {
-		return getSizeMismatchStatus("liquidityProvisions", core, datanode)
+		return getSizeMismatchStatus("liquidityProvisions", core, datanode, lpKey)
 	}
 	for i, a := range core {
 		if a.String() != datanode[i].String() {
-			return getValueMismatchStatus("liquidityProvisions", core, datanode)
+			return getValueMismatchStatus("liquidityProvisions", core, datanode, lpKey)
 		}
 	}
 
@@ -484,13 +479,13 @@ func diffStake(coreSnapshot *Result, dn *Result) Status {
 	sort.Slice(datanode, func(i, j int) bool { return datanode[i].Id < datanode[j].Id })
 
 	if len(core) != len(datanode) {
-		return getSizeMismatchStatus("stake", core, datanode)
+		return getSizeMismatchStatus("stake", core, datanode, stakeKey)
 	}
 
 	for i, a := range core {
 		d := datanode[i]
 		if a.String() != d.String() {
-			return getValueMismatchStatus("stake", core, datanode)
+			return getValueMismatchStatus("stake", core, datanode, stakeKey)
 		}
 	}
 	return getSuccessStatus("stake", core, datanode)
@@ -516,32 +511,108 @@ func diffTransfers(coreSnapshot *Result, dn *Result) Status {
 	sort.Slice(datanode, func(i, j int) bool { return datanode[i].Id < datanode[j].Id })
 
 	if len(core) != len(datanode) {
-		return getSizeMismatchStatus("transfers", core, datanode)
-	}
+		return getSizeMismatchStatus("transfers", core, datanode, transferKey)
+	}
+
+	// We ignore the difference in the timestamp, as the transfer timestamp
+	// is updated when the network is started from the checkpoint.
+	// The original values are restored once compared as copy is not trivial
+	// due to Mutex inside of the proto message
+	all := append(append([]*v1.Transfer{}, core...), datanode...)
+	timestamps := make([]int64, 0, len(all))
+	for _, t := range all {
+		timestamps = append(timestamps, t.Timestamp)
+		t.Timestamp = 0
+	}
+	defer func() {
+		for i, t := range all {
+			t.Timestamp = timestamps[i]
+		}
+	}()
 
 	for i, a := range core {
-		d := datanode[i]
-		coreTransferTimestamp := a.Timestamp
-		dnTransferTimestamp := d.Timestamp
+		if a.String() != datanode[i].String() {
+			return getValueMismatchStatus("transfers", core, datanode, transferKey)
+		}
+	}
+
+	return getSuccessStatus("transfers", core, datanode)
+}
+
+// diffMarketData compares the market data of the live markets in the core snapshot with the same from datanode.
+func diffMarketData(coreSnapshot *Result, dn *Result) Status {
+	core := coreSnapshot.MarketData
+	markets := map[string]struct{}{}
+	for _, md := range core {
+		markets[md.Market] = struct{}{}
+	}
+
+	datanode := []*vega.MarketData{}
+	for _, md := range dn.MarketData {
+		if _, ok := markets[md.Market]; ok {
+			datanode = append(datanode, md)
+		}
+	}
+	return diffEntities("marketData", core, datanode, marketDataKey)
+}
 
-		// We ignore the difference in the timestamp, as the transfer timestamp
-		// is updated when the network is started from the checkpoint.
-		a.Timestamp = 0
-		d.Timestamp = 0
+// diffFeesStats compares the fees stats of the current epoch in the core snapshot with the same from datanode, once the
+// epoch has ended. Datanode then has the stats of the whole epoch, so differences are possibly stale like those of the
+// domains that are not pinned to the snapshot height.
+func diffFeesStats(coreSnapshot *Result, dn *Result) Status {
+	if dn.FeesStatsPending {
+		return Status{Key: "fees", MatchResult: FullMatch, CoreResLen: len(coreSnapshot.FeesStats), Pending: true}
+	}
+	return diffEntities("fees", coreSnapshot.FeesStats, dn.FeesStats, feesStatsKey)
+}
 
-		transfetMatch := a.String() == d.String()
+// diffVestingBalances compares the locked and vesting balances of each party. Zero balances are left out as datanode
+// keeps them once the rewards have vested while core drops them.
+func diffVestingBalances(coreSnapshot *Result, dn *Result) Status {
+	return diffEntities("vestingBalances", nonZeroVestingBalances(coreSnapshot.VestingBalances),
+		nonZeroVestingBalances(dn.VestingBalances), vestingBalancesKey)
+}
 
-		// restore the original value as copy is not trivial due to Mutex
-		// inside of the proto message
-		a.Timestamp = coreTransferTimestamp
-		d.Timestamp = dnTransferTimestamp
+// nonZeroVestingBalances returns copies of the balances without the zero ones, and without the parties left with none.
+func nonZeroVestingBalances(balances []*dnproto.GetVestingBalancesSummaryResponse) []*dnproto.GetVestingBalancesSummaryResponse {
+	nonZero := func(balance string) bool { return len(balance) > 0 && balance != "0" }
 
-		if !transfetMatch {
-			return getValueMismatchStatus("transfers", core, datanode)
+	filtered := []*dnproto.GetVestingBalancesSummaryResponse{}
+	for _, b := range balances {
+		f := &dnproto.GetVestingBalancesSummaryResponse{PartyId: b.PartyId}
+		for _, lb := range b.LockedBalances {
+			if nonZero(lb.Balance) {
+				f.LockedBalances = append(f.LockedBalances, lb)
+			}
+		}
+		for _, vb := range b.VestingBalances {
+			if nonZero(vb.Balance) {
+				f.VestingBalances = append(f.VestingBalances, vb)
+			}
+		}
+		if len(f.LockedBalances) > 0 || len(f.VestingBalances) > 0 {
+			filtered = append(filtered, f)
 		}
 	}
+	return filtered
+}
 
-	return getSuccessStatus("transfers", core, datanode)
+// diffOracleSpecs compares the data source specs of the live markets in the core snapshot with the same from datanode,
+// which also lists the active specs of markets that were proposed but never enacted.
+func diffOracleSpecs(coreSnapshot *Result, dn *Result) Status {
+	core := coreSnapshot.OracleSpecs
+	ids := map[string]struct{}{}
+	for _, s := range core {
+		ids[s.Id] = struct{}{}
+	}
+
+	datanode := []*vega.DataSourceSpec{}
+	for _, s := range dn.OracleSpecs {
+		if _, ok := ids[s.Id]; ok {
+			datanode = append(datanode, s)
+		}
+	}
+	return diffEntities("oracleSpecs", core, datanode, oracleSpecKey)
 }
 
 func getSuccessStatus[A interface{ String() string }](key string, core, datanode []A) Status {
@@ -562,25 +633,37 @@ func getSimpleSuccessStatus(key string) Status {
 	}
 }
 
-func getSizeMismatchStatus[A interface{ String() string }](key string, core, datanode []A) Status {
+func getSizeMismatchStatus[A proto.Message](key string, core, datanode []A, id func(A) string) Status {
+	return getEntityMismatchStatus(key, SizeMismatch, core, datanode, id)
+}
+
+func getValueMismatchStatus[A proto.Message](key string, core, datanode []A, id func(A) string) Status {
+	return getEntityMismatchStatus(key, ValuesMismatch, core, datanode, id)
+}
+
+// getEntityMismatchStatus lists the entities only found on one side and the fields that differ for the others.
+func getEntityMismatchStatus[A proto.Message](key string, result MatchResult, core, datanode []A, id func(A) string) Status {
+	onlyInCore, onlyInDatanode, duplicates, changed := matchEntities(core, datanode, id)
 	return Status{
-		Key:         key,
-		MatchResult: SizeMismatch,
-		CoreRes:     sliceToString(core),
-		DatanodeRes: sliceToString(datanode),
-		CoreResLen:  len(core),
-		DataNodeLen: len(datanode),
+		Key:            key,
+		MatchResult:    result,
+		CoreResLen:     len(core),
+		DataNodeLen:    len(datanode),
+		OnlyInCore:     onlyInCore,
+		OnlyInDatanode: onlyInDatanode,
+		Duplicates:     duplicates,
+		Changed:        changed,
 	}
 }
 
-func getValueMismatchStatus[A interface{ String() string }](key string, core, datanode []A) Status {
+// getMessageMismatchStatus lists the fields that differ for a single entity such as the network limits.
+func getMessageMismatchStatus(key string, core, datanode proto.Message) Status {
 	return Status{
 		Key:         key,
 		MatchResult: ValuesMismatch,
-		CoreRes:     sliceToString(core),
-		DatanodeRes: sliceToString(datanode),
-		CoreResLen:  len(core),
-		DataNodeLen: len(datanode),
+		CoreResLen:  1,
+		DataNodeLen: 1,
+		Changed:     []EntityDiff{{ID: key, Fields: fieldDiffs(core, datanode)}},
 	}
 }
 
@@ -594,12 +677,3 @@ func getSimpleValueMismatchStatus(key string, core, datanode string) Status {
 		DataNodeLen: 1,
 	}
 }
-
-func sliceToString[A interface{ String() string }](s []A) string {
-	str := ""
-	for _, o := range s {
-		a := o.String()
-		str += a + "\n"
-	}
-	return str
-}
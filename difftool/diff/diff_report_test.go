@@ -0,0 +1,79 @@
+package diff
+
+import (
+	"testing"
+
+	dnproto "code.vegaprotocol.io/vega/protos/data-node/api/v2"
+	"code.vegaprotocol.io/vega/protos/vega"
+	v1 "code.vegaprotocol.io/vega/protos/vega/events/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffMarketDataOfLiveMarkets(t *testing.T) {
+	core := &Result{MarketData: []*vega.MarketData{{Market: "m1", MarkPrice: "10"}}}
+	datanode := &Result{MarketData: []*vega.MarketData{{Market: "m1", MarkPrice: "10"}, {Market: "settled", MarkPrice: "5"}}}
+
+	status := diffMarketData(core, datanode)
+	assert.Equal(t, FullMatch, status.MatchResult)
+	assert.Equal(t, 1, status.DataNodeLen)
+
+	datanode.MarketData[0].MarkPrice = "11"
+	assert.Equal(t, ValuesMismatch, diffMarketData(core, datanode).MatchResult)
+}
+
+func TestDiffFeesStatsOnceTheEpochHasEnded(t *testing.T) {
+	core := &Result{FeesStats: []*v1.FeesStats{{Market: "m1", EpochSeq: 7}}}
+
+	status := diffFeesStats(core, &Result{FeesStatsPending: true})
+	assert.Equal(t, FullMatch, status.MatchResult)
+	assert.True(t, status.Pending)
+	assert.Equal(t, "key=fees, matchResult=full match, coreLength=1, datanodeLength=0, not compared as datanode does not have it yet", status.String())
+
+	status = diffFeesStats(core, &Result{})
+	assert.Equal(t, SizeMismatch, status.MatchResult)
+	assert.False(t, status.Pending)
+
+	status = diffFeesStats(core, &Result{FeesStats: []*v1.FeesStats{{Market: "m1", EpochSeq: 7}}})
+	assert.Equal(t, FullMatch, status.MatchResult)
+}
+
+func TestDiffVestingBalancesIgnoresZeroBalances(t *testing.T) {
+	core := &Result{VestingBalances: []*dnproto.GetVestingBalancesSummaryResponse{
+		{PartyId: "p1", VestingBalances: []*dnproto.PartyVestingBalance{{Asset: "a", Balance: "10"}}},
+	}}
+	datanode := &Result{VestingBalances: []*dnproto.GetVestingBalancesSummaryResponse{
+		{
+			PartyId:         "p1",
+			LockedBalances:  []*dnproto.PartyLockedBalance{{Asset: "a", Balance: "0", UntilEpoch: 3}},
+			VestingBalances: []*dnproto.PartyVestingBalance{{Asset: "a", Balance: "10"}, {Asset: "b", Balance: "0"}},
+		},
+		{PartyId: "vested", VestingBalances: []*dnproto.PartyVestingBalance{{Asset: "a", Balance: "0"}}},
+	}}
+
+	status := diffVestingBalances(core, datanode)
+	assert.Equal(t, FullMatch, status.MatchResult)
+	assert.Equal(t, 1, status.DataNodeLen)
+	// the results are left untouched
+	assert.Len(t, datanode.VestingBalances[0].VestingBalances, 2)
+
+	datanode.VestingBalances[1].VestingBalances[0].Balance = "1"
+	status = diffVestingBalances(core, datanode)
+	assert.Equal(t, SizeMismatch, status.MatchResult)
+	assert.Equal(t, []string{"vested"}, status.OnlyInDatanode)
+}
+
+func TestDiffOracleSpecsOfLiveMarkets(t *testing.T) {
+	core := &Result{OracleSpecs: []*vega.DataSourceSpec{{Id: "s1", Status: vega.DataSourceSpec_STATUS_ACTIVE}}}
+	datanode := &Result{OracleSpecs: []*vega.DataSourceSpec{
+		{Id: "s1", Status: vega.DataSourceSpec_STATUS_ACTIVE},
+		{Id: "of-unenacted-market", Status: vega.DataSourceSpec_STATUS_ACTIVE},
+	}}
+
+	assert.Equal(t, FullMatch, diffOracleSpecs(core, datanode).MatchResult)
+
+	core.OracleSpecs = append(core.OracleSpecs, &vega.DataSourceSpec{Id: "s2"})
+	status := diffOracleSpecs(core, datanode)
+	assert.Equal(t, SizeMismatch, status.MatchResult)
+	assert.Equal(t, []string{"s2"}, status.OnlyInCore)
+}
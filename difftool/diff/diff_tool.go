@@ -1,33 +1,102 @@
 package diff
 
-import (
-	"errors"
-	"fmt"
-)
+import "time"
+
+// Opts control which domains are compared, how the report is written and which differences fail the run.
+type Opts struct {
+	// Only are the domains compared, all if empty
+	Only []string
+	// Skip are the domains that are not compared
+	Skip []string
+	// Format is text, json or junit
+	Format string
+	// File is where a json or junit report is written, stdout if empty
+	File string
+	// Known are the differences that do not fail the run
+	Known KnownDifferences
+	// DatanodeConcurrency is the number of data node calls in flight at once
+	DatanodeConcurrency int
+	// DatanodeTimeout is the timeout of each data node call
+	DatanodeTimeout time.Duration
+	// DatanodeRetries is the number of times a data node call is retried when rate limited or unavailable
+	DatanodeRetries int
+	// WaitForHeight is how long to wait for data node to reach the height of the snapshot before comparing
+	WaitForHeight time.Duration
+	// FailOnStale fails the run on the differences that are possibly stale as well
+	FailOnStale bool
+}
 
 // Run takes a snapshot (proto serialised) file path and data node connection string and runs the diff tool.
-// Returns nil if no error is found or the error report otherwise.
-func Run(snapshotFilePath, datanodeConnection string) error {
-	// get data node data
-	datanode := newDataNodeClient(datanodeConnection)
-	dataNodeResult, err := datanode.Collect()
+// Returns nil if no error is found, a MismatchError with the report if there are differences other than the known
+// ones, or the error otherwise.
+func Run(snapshotFilePath, datanodeConnection string, opts Opts) error {
+	domains, err := selectDomains(opts.Only, opts.Skip)
 	if err != nil {
 		return err
 	}
 
+	diffReport, err := compareWithDatanode(snapshotFilePath, datanodeConnection, domains, opts)
+	if err != nil {
+		return err
+	}
+	return diffReport.finish("core and datanode", opts)
+}
+
+// compareWithDatanode returns the report of the differences between the snapshot and data node at its height.
+func compareWithDatanode(snapshotFilePath, datanodeConnection string, domains []domain, opts Opts) (*Report, error) {
 	// get core snapshot data
 	coreSnapshot, err := newSnapshotData(snapshotFilePath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	coreResult := coreSnapshot.Collect()
+	height := coreSnapshot.getHeight()
+
+	// get data node data once it has caught up with the snapshot, pinned to its height where the API allows
+	datanode, err := newDataNodeClient(datanodeConnection, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer datanode.conn.Close()
+	if err := datanode.waitForHeight(height, opts.WaitForHeight); err != nil {
+		return nil, err
+	}
+	datanode.height = height
+	dataNodeResult, err := datanode.Collect(domains)
+	if err != nil {
+		return nil, err
+	}
+	datanodeHeight, err := datanode.getHeight()
+	if err != nil {
+		return nil, err
+	}
 
 	// generate a diff report
-	diffReport := newDiffReport(coreResult, dataNodeResult)
+	diffReport := newDiffReport(coreResult, dataNodeResult, domains)
+	diffReport.markStale(domains, height, datanodeHeight)
+	diffReport.failOnStale = opts.FailOnStale
+	return diffReport, nil
+}
 
-	if !diffReport.Success {
-		report := fmt.Sprintf("mismatch between core and datanode: %s", diffReport.String())
-		return errors.New(report)
+// RunSnapshots takes two snapshot (proto serialised) file paths, e.g. from two validators at the same height or one
+// node at two heights, and reports the entities added, removed and changed between them for each engine.
+// Returns nil if the snapshots match, a MismatchError with the report if there are differences other than the known
+// ones, or the error otherwise.
+func RunSnapshots(firstSnapshotFilePath, secondSnapshotFilePath string, opts Opts) error {
+	domains, err := selectDomains(opts.Only, opts.Skip)
+	if err != nil {
+		return err
 	}
-	return nil
+
+	first, err := newSnapshotData(firstSnapshotFilePath)
+	if err != nil {
+		return err
+	}
+	second, err := newSnapshotData(secondSnapshotFilePath)
+	if err != nil {
+		return err
+	}
+
+	diffReport := newSnapshotDiffReport(first.Collect(), second.Collect(), domains)
+	return diffReport.finish("snapshots", opts)
 }
@@ -0,0 +1,197 @@
+package diff
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// FieldDiff is a field that has a different value on each side of a comparison. Path is the dotted path of the
+// field within the entity, with the index or key of list and map entries in brackets.
+type FieldDiff struct {
+	Path     string `json:"path"`
+	Core     string `json:"core"`
+	Datanode string `json:"datanode"`
+}
+
+// EntityDiff lists the fields that differ for an entity found on both sides of a comparison.
+type EntityDiff struct {
+	ID     string      `json:"id"`
+	Fields []FieldDiff `json:"fields"`
+}
+
+// matchEntities pairs up core and datanode entities by the key returned by id. It returns the keys of the entities
+// only found in core, those only found in datanode, the keys held by more than one entity on either side, which can't
+// be paired up, and the field differences of those found once on both sides that differ.
+func matchEntities[A proto.Message](core, datanode []A, id func(A) string) ([]string, []string, []string, []EntityDiff) {
+	coreByID, coreCount := byID(core, id)
+	datanodeByID, datanodeCount := byID(datanode, id)
+
+	var onlyInCore, onlyInDatanode, duplicates []string
+	var changed []EntityDiff
+	for k, c := range coreByID {
+		if coreCount[k] > 1 || datanodeCount[k] > 1 {
+			duplicates = append(duplicates, k)
+			continue
+		}
+		d, ok := datanodeByID[k]
+		if !ok {
+			onlyInCore = append(onlyInCore, k)
+			continue
+		}
+		if !proto.Equal(c, d) {
+			changed = append(changed, EntityDiff{ID: k, Fields: fieldDiffs(c, d)})
+		}
+	}
+	for k := range datanodeByID {
+		if _, ok := coreByID[k]; ok {
+			continue
+		}
+		if datanodeCount[k] > 1 {
+			duplicates = append(duplicates, k)
+			continue
+		}
+		onlyInDatanode = append(onlyInDatanode, k)
+	}
+
+	sort.Strings(onlyInCore)
+	sort.Strings(onlyInDatanode)
+	sort.Strings(duplicates)
+	sort.Slice(changed, func(i, j int) bool { return changed[i].ID < changed[j].ID })
+	return onlyInCore, onlyInDatanode, duplicates, changed
+}
+
+// byID returns the entities by key along with the number of entities holding each key.
+func byID[A proto.Message](entities []A, id func(A) string) (map[string]A, map[string]int) {
+	m := make(map[string]A, len(entities))
+	count := make(map[string]int, len(entities))
+	for _, e := range entities {
+		k := id(e)
+		m[k] = e
+		count[k]++
+	}
+	return m, count
+}
+
+// fieldDiffs returns the fields that differ between two messages of the same type.
+func fieldDiffs(core, datanode proto.Message) []FieldDiff {
+	var diffs []FieldDiff
+	diffMessage("", core.ProtoReflect(), datanode.ProtoReflect(), &diffs)
+	return diffs
+}
+
+func diffMessage(prefix string, core, datanode protoreflect.Message, diffs *[]FieldDiff) {
+	fields := core.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		path := prefix + string(fd.Name())
+		switch {
+		case fd.IsList():
+			diffList(path, fd, core.Get(fd).List(), datanode.Get(fd).List(), diffs)
+		case fd.IsMap():
+			diffMap(path, fd, core.Get(fd).Map(), datanode.Get(fd).Map(), diffs)
+		case fd.Message() != nil:
+			hasCore, hasDatanode := core.Has(fd), datanode.Has(fd)
+			switch {
+			case !hasCore && !hasDatanode:
+			case hasCore != hasDatanode:
+				*diffs = append(*diffs, FieldDiff{Path: path, Core: formatField(core, fd), Datanode: formatField(datanode, fd)})
+			default:
+				diffMessage(path+".", core.Get(fd).Message(), datanode.Get(fd).Message(), diffs)
+			}
+		default:
+			diffValue(path, fd, core.Get(fd), datanode.Get(fd), diffs)
+		}
+	}
+}
+
+func diffList(path string, fd protoreflect.FieldDescriptor, core, datanode protoreflect.List, diffs *[]FieldDiff) {
+	if core.Len() != datanode.Len() {
+		*diffs = append(*diffs, FieldDiff{
+			Path:     path + ".length",
+			Core:     fmt.Sprint(core.Len()),
+			Datanode: fmt.Sprint(datanode.Len()),
+		})
+	}
+	n := core.Len()
+	if datanode.Len() < n {
+		n = datanode.Len()
+	}
+	for i := 0; i < n; i++ {
+		diffEntry(fmt.Sprintf("%s[%d]", path, i), fd, core.Get(i), datanode.Get(i), diffs)
+	}
+}
+
+func diffMap(path string, fd protoreflect.FieldDescriptor, core, datanode protoreflect.Map, diffs *[]FieldDiff) {
+	keys := map[string]protoreflect.MapKey{}
+	core.Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+		keys[k.String()] = k
+		return true
+	})
+	datanode.Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+		keys[k.String()] = k
+		return true
+	})
+
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		k := keys[name]
+		entryPath := fmt.Sprintf("%s[%s]", path, name)
+		switch {
+		case !core.Has(k):
+			*diffs = append(*diffs, FieldDiff{Path: entryPath, Core: "<unset>", Datanode: formatValue(fd.MapValue(), datanode.Get(k))})
+		case !datanode.Has(k):
+			*diffs = append(*diffs, FieldDiff{Path: entryPath, Core: formatValue(fd.MapValue(), core.Get(k)), Datanode: "<unset>"})
+		default:
+			diffEntry(entryPath, fd.MapValue(), core.Get(k), datanode.Get(k), diffs)
+		}
+	}
+}
+
+// diffEntry compares a list element or map value.
+func diffEntry(path string, fd protoreflect.FieldDescriptor, core, datanode protoreflect.Value, diffs *[]FieldDiff) {
+	if fd.Message() != nil {
+		diffMessage(path+".", core.Message(), datanode.Message(), diffs)
+		return
+	}
+	diffValue(path, fd, core, datanode, diffs)
+}
+
+func diffValue(path string, fd protoreflect.FieldDescriptor, core, datanode protoreflect.Value, diffs *[]FieldDiff) {
+	c, d := formatValue(fd, core), formatValue(fd, datanode)
+	if c != d {
+		*diffs = append(*diffs, FieldDiff{Path: path, Core: c, Datanode: d})
+	}
+}
+
+func formatField(m protoreflect.Message, fd protoreflect.FieldDescriptor) string {
+	if !m.Has(fd) {
+		return "<unset>"
+	}
+	return formatValue(fd, m.Get(fd))
+}
+
+func formatValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return "{" + prototext.MarshalOptions{}.Format(v.Message().Interface()) + "}"
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return fmt.Sprint(v.Enum())
+	case protoreflect.BytesKind:
+		return hex.EncodeToString(v.Bytes())
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
@@ -0,0 +1,106 @@
+package diff
+
+import (
+	"testing"
+
+	"code.vegaprotocol.io/vega/protos/vega"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestMatchEntities(t *testing.T) {
+	core := []*vega.Order{
+		{Id: "o1", Price: "10"},
+		{Id: "o2", Price: "10"},
+		{Id: "o3", Price: "10"},
+		{Id: "o5", Price: "10"},
+		{Id: "o5", Price: "10"},
+	}
+	datanode := []*vega.Order{
+		{Id: "o2", Price: "11"},
+		{Id: "o3", Price: "10"},
+		{Id: "o4", Price: "10"},
+		{Id: "o5", Price: "10"},
+		{Id: "o6", Price: "10"},
+		{Id: "o6", Price: "12"},
+	}
+
+	onlyInCore, onlyInDatanode, duplicates, changed := matchEntities(core, datanode, orderKey)
+	assert.Equal(t, []string{"o1"}, onlyInCore)
+	assert.Equal(t, []string{"o4"}, onlyInDatanode)
+	assert.Equal(t, []string{"o5", "o6"}, duplicates)
+	assert.Equal(t, []EntityDiff{{ID: "o2", Fields: []FieldDiff{{Path: "price", Core: "10", Datanode: "11"}}}}, changed)
+}
+
+func TestFieldDiffsOfNestedMessages(t *testing.T) {
+	core := &vega.Market{
+		Id:    "m1",
+		State: vega.Market_STATE_ACTIVE,
+		TradableInstrument: &vega.TradableInstrument{Instrument: &vega.Instrument{
+			Code:    "BTC/USD",
+			Product: &vega.Instrument_Future{Future: &vega.Future{SettlementAsset: "usd"}},
+		}},
+	}
+	datanode := &vega.Market{
+		Id:    "m1",
+		State: vega.Market_STATE_SUSPENDED,
+		TradableInstrument: &vega.TradableInstrument{Instrument: &vega.Instrument{
+			Code: "BTC/USDT",
+		}},
+	}
+
+	assert.Equal(t, []FieldDiff{
+		{Path: "tradable_instrument.instrument.code", Core: "BTC/USD", Datanode: "BTC/USDT"},
+		{Path: "tradable_instrument.instrument.future", Core: `{settlement_asset:"usd"}`, Datanode: "<unset>"},
+		{Path: "state", Core: "STATE_ACTIVE", Datanode: "STATE_SUSPENDED"},
+	}, normaliseSpaces(fieldDiffs(core, datanode)))
+}
+
+func TestFieldDiffsOfListsAndMaps(t *testing.T) {
+	core, err := structpb.NewStruct(map[string]interface{}{
+		"same":    "a",
+		"changed": "b",
+		"removed": "c",
+		"list":    []interface{}{"x", "y"},
+	})
+	require.NoError(t, err)
+	datanode, err := structpb.NewStruct(map[string]interface{}{
+		"same":    "a",
+		"changed": "B",
+		"added":   "d",
+		"list":    []interface{}{"x", "z", "w"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []FieldDiff{
+		{Path: "fields[added]", Core: "<unset>", Datanode: `{string_value:"d"}`},
+		{Path: "fields[changed].string_value", Core: "b", Datanode: "B"},
+		{Path: "fields[list].list_value.values.length", Core: "2", Datanode: "3"},
+		{Path: "fields[list].list_value.values[1].string_value", Core: "y", Datanode: "z"},
+		{Path: "fields[removed]", Core: `{string_value:"c"}`, Datanode: "<unset>"},
+	}, normaliseSpaces(fieldDiffs(core, datanode)))
+}
+
+// normaliseSpaces removes the spaces prototext randomly adds to its output so that it can't be relied on.
+func normaliseSpaces(diffs []FieldDiff) []FieldDiff {
+	strip := func(s string) string {
+		out := []rune{}
+		quoted := false
+		for _, r := range s {
+			if r == '"' {
+				quoted = !quoted
+			}
+			if r == ' ' && !quoted {
+				continue
+			}
+			out = append(out, r)
+		}
+		return string(out)
+	}
+	for i := range diffs {
+		diffs[i].Core, diffs[i].Datanode = strip(diffs[i].Core), strip(diffs[i].Datanode)
+	}
+	return diffs
+}
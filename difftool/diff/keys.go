@@ -0,0 +1,62 @@
+package diff
+
+import (
+	dnproto "code.vegaprotocol.io/vega/protos/data-node/api/v2"
+	"code.vegaprotocol.io/vega/protos/vega"
+	v1 "code.vegaprotocol.io/vega/protos/vega/events/v1"
+)
+
+// The key functions identify the entities of each collection of a Result so that the same entity can be matched
+// up on both sides of a comparison.
+
+func accountKey(ab *dnproto.AccountBalance) string {
+	return ab.Owner + "/" + ab.MarketId + "/" + ab.Asset + "/" + ab.Type.String()
+}
+
+func orderKey(o *vega.Order) string { return o.Id }
+
+func marketKey(m *vega.Market) string { return m.Id }
+
+func partyKey(p *vega.Party) string { return p.Id }
+
+func assetKey(a *vega.Asset) string { return a.Id }
+
+func delegationKey(d *vega.Delegation) string { return d.EpochSeq + "/" + d.NodeId + "/" + d.Party }
+
+func nodeKey(n *vega.Node) string { return n.Id }
+
+func netParamKey(p *vega.NetworkParameter) string { return p.Key }
+
+func proposalKey(p *vega.Proposal) string { return p.Id }
+
+func depositKey(d *vega.Deposit) string { return d.Id }
+
+func withdrawalKey(w *vega.Withdrawal) string { return w.Id }
+
+func transferKey(t *v1.Transfer) string { return t.Id }
+
+func lpKey(lp *vega.LiquidityProvision) string { return lp.Id }
+
+func stakeKey(s *v1.StakeLinking) string { return s.Id }
+
+func marketDataKey(md *vega.MarketData) string { return md.Market }
+
+func stopOrderKey(so *vega.StopOrder) string { return so.Id }
+
+func feesStatsKey(fs *v1.FeesStats) string { return fs.Market }
+
+func vestingBalancesKey(vb *dnproto.GetVestingBalancesSummaryResponse) string { return vb.PartyId }
+
+func referralProgramKey(rp *vega.ReferralProgram) string { return rp.Id }
+
+func referralSetKey(rs *dnproto.ReferralSet) string { return rs.Id }
+
+func referralSetRefereeKey(r *dnproto.ReferralSetReferee) string {
+	return r.ReferralSetId + "/" + r.Referee
+}
+
+func teamKey(t *dnproto.Team) string { return t.TeamId }
+
+func teamRefereeKey(r *dnproto.TeamReferee) string { return r.TeamId + "/" + r.Referee }
+
+func oracleSpecKey(s *vega.DataSourceSpec) string { return s.Id }
@@ -0,0 +1,113 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// KnownDifference lists the differences accepted for a key of the report, e.g. while a data node bug is being
+// fixed, so that only regressions fail a run.
+type KnownDifference struct {
+	// All accepts any difference for the key
+	All bool `json:"all"`
+	// IDs are the entities that may be missing on either side, duplicated or differ in any field
+	IDs []string `json:"ids"`
+	// Fields are the field paths that may differ for any entity, list indexes and map keys are ignored so that
+	// `legs.price` matches `legs[0].price`
+	Fields []string `json:"fields"`
+	// Reason is shown in the report for the known differences
+	Reason string `json:"reason"`
+}
+
+// KnownDifferences are the accepted differences by report key.
+type KnownDifferences map[string]KnownDifference
+
+// LoadKnownDifferences reads the known differences from a JSON file of the form
+//
+//	{"orders": {"fields": ["updated_at"], "reason": "..."}, "transfers": {"ids": ["..."]}}
+func LoadKnownDifferences(path string) (KnownDifferences, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read known differences: %w", err)
+	}
+	known := KnownDifferences{}
+	if err := json.Unmarshal(b, &known); err != nil {
+		return nil, fmt.Errorf("failed to parse known differences %s: %w", path, err)
+	}
+	return known, nil
+}
+
+var indexPattern = regexp.MustCompile(`\[[^\]]*\]`)
+
+// accept marks the statuses whose differences are all known. A status with some unknown differences is left with
+// only those, so that the report shows the regressions.
+func (dr *Report) accept(known KnownDifferences) {
+	for i, ds := range dr.DiffResult {
+		k, ok := known[ds.Key]
+		if !ok || ds.MatchResult == FullMatch {
+			continue
+		}
+		if remaining, ok := k.filter(ds); ok {
+			dr.DiffResult[i] = remaining
+			continue
+		}
+		dr.DiffResult[i].Known = true
+		dr.DiffResult[i].KnownReason = k.Reason
+	}
+
+	dr.Success = dr.Worst() == FullMatch
+}
+
+// filter removes the known differences from the status, and returns whether any are left.
+func (k KnownDifference) filter(ds Status) (Status, bool) {
+	if k.All {
+		return ds, false
+	}
+
+	ids := make(map[string]struct{}, len(k.IDs))
+	for _, id := range k.IDs {
+		ids[id] = struct{}{}
+	}
+	fields := make(map[string]struct{}, len(k.Fields))
+	for _, f := range k.Fields {
+		fields[f] = struct{}{}
+	}
+
+	unknown := func(keys []string) []string {
+		var left []string
+		for _, id := range keys {
+			if _, ok := ids[id]; !ok {
+				left = append(left, id)
+			}
+		}
+		return left
+	}
+
+	remaining := ds
+	remaining.OnlyInCore = unknown(ds.OnlyInCore)
+	remaining.OnlyInDatanode = unknown(ds.OnlyInDatanode)
+	remaining.Duplicates = unknown(ds.Duplicates)
+	remaining.Changed = nil
+	for _, e := range ds.Changed {
+		if _, ok := ids[e.ID]; ok {
+			continue
+		}
+		var left []FieldDiff
+		for _, f := range e.Fields {
+			if _, ok := fields[indexPattern.ReplaceAllString(f.Path, "")]; !ok {
+				left = append(left, f)
+			}
+		}
+		if len(left) > 0 {
+			remaining.Changed = append(remaining.Changed, EntityDiff{ID: e.ID, Fields: left})
+		}
+	}
+
+	// simple values such as the vega time have no entities so can only be accepted with all
+	simple := len(ds.OnlyInCore) == 0 && len(ds.OnlyInDatanode) == 0 && len(ds.Duplicates) == 0 && len(ds.Changed) == 0
+	left := simple || len(remaining.OnlyInCore) > 0 || len(remaining.OnlyInDatanode) > 0 || len(remaining.Duplicates) > 0 ||
+		len(remaining.Changed) > 0
+	return remaining, left
+}
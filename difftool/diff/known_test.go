@@ -0,0 +1,92 @@
+package diff
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ordersStatus() Status {
+	return Status{
+		Key:            "orders",
+		MatchResult:    ValuesMismatch,
+		OnlyInCore:     []string{"o1"},
+		OnlyInDatanode: []string{"o2"},
+		Changed: []EntityDiff{
+			{ID: "o3", Fields: []FieldDiff{{Path: "updated_at", Core: "1", Datanode: "2"}}},
+			{ID: "o4", Fields: []FieldDiff{
+				{Path: "legs[0].price", Core: "1", Datanode: "2"},
+				{Path: "remaining", Core: "1", Datanode: "2"},
+			}},
+		},
+	}
+}
+
+func TestLoadKnownDifferences(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "known.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"orders": {"fields": ["updated_at"], "reason": "bug"}, "vegaTime": {"all": true}}`), 0o644))
+
+	known, err := LoadKnownDifferences(path)
+	require.NoError(t, err)
+	assert.Equal(t, KnownDifferences{
+		"orders":   {Fields: []string{"updated_at"}, Reason: "bug"},
+		"vegaTime": {All: true},
+	}, known)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"orders": [}`), 0o644))
+	_, err = LoadKnownDifferences(path)
+	assert.Error(t, err)
+}
+
+func TestAcceptLeavesUnknownDifferences(t *testing.T) {
+	dr := &Report{DiffResult: []Status{ordersStatus(), {Key: "parties", MatchResult: FullMatch}}}
+	dr.accept(KnownDifferences{"orders": {IDs: []string{"o1"}, Fields: []string{"updated_at", "legs.price"}}})
+
+	assert.False(t, dr.Success)
+	assert.Equal(t, ValuesMismatch, dr.Worst())
+	assert.Equal(t, Status{
+		Key:            "orders",
+		MatchResult:    ValuesMismatch,
+		OnlyInDatanode: []string{"o2"},
+		Changed:        []EntityDiff{{ID: "o4", Fields: []FieldDiff{{Path: "remaining", Core: "1", Datanode: "2"}}}},
+	}, dr.DiffResult[0])
+}
+
+func TestAcceptAllKnownDifferences(t *testing.T) {
+	dr := &Report{DiffResult: []Status{ordersStatus()}}
+	dr.accept(KnownDifferences{"orders": {IDs: []string{"o1", "o2", "o4"}, Fields: []string{"updated_at"}, Reason: "bug"}})
+
+	assert.True(t, dr.Success)
+	assert.True(t, dr.DiffResult[0].Known)
+	assert.Equal(t, "bug", dr.DiffResult[0].KnownReason)
+	// the differences are still reported
+	assert.Len(t, dr.DiffResult[0].Changed, 2)
+}
+
+func TestAcceptSimpleValuesOnlyWithAll(t *testing.T) {
+	vegaTime := Status{Key: "vegaTime", MatchResult: ValuesMismatch, CoreRes: "1", DatanodeRes: "2"}
+
+	dr := &Report{DiffResult: []Status{vegaTime}}
+	dr.accept(KnownDifferences{"vegaTime": {Fields: []string{"vegaTime"}}})
+	assert.False(t, dr.Success)
+
+	dr = &Report{DiffResult: []Status{vegaTime}}
+	dr.accept(KnownDifferences{"vegaTime": {All: true}})
+	assert.True(t, dr.Success)
+}
+
+func TestAcceptDuplicates(t *testing.T) {
+	status := Status{Key: "orders", MatchResult: SizeMismatch, Duplicates: []string{"o1", "o2"}}
+
+	dr := &Report{DiffResult: []Status{status}}
+	dr.accept(KnownDifferences{"orders": {IDs: []string{"o1"}}})
+	assert.False(t, dr.Success)
+	assert.Equal(t, []string{"o2"}, dr.DiffResult[0].Duplicates)
+
+	dr = &Report{DiffResult: []Status{status}}
+	dr.accept(KnownDifferences{"orders": {IDs: []string{"o1", "o2"}}})
+	assert.True(t, dr.Success)
+}
@@ -0,0 +1,413 @@
+package diff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"code.vegaprotocol.io/vega/protos/vega"
+)
+
+// domain is a part of the state compared by the tool, e.g. the orders. Each domain reads its part of the state into a
+// Result from a core snapshot and from data node, and compares two Results.
+type domain struct {
+	name string
+	// pinned is set if the data node fetcher reads the state as of the snapshot height, so that it can be compared
+	// even once data node has moved on
+	pinned bool
+	// fromSnapshot sets the domain in the result from a core snapshot
+	fromSnapshot func(s *snap, res *Result)
+	// fromDatanode sets the domain in the result from the data node API
+	fromDatanode func(dnc *dataNodeClient, res *Result) error
+	// diff compares a core snapshot with data node
+	diff func(core, datanode *Result) Status
+	// diffSnapshots compares two core snapshots
+	diffSnapshots func(first, second *Result) Status
+}
+
+// domains are all the compared domains in the order they are reported. New engines are added by adding a domain here.
+// Positions are deliberately left out: core snapshots hold them as open volumes and sums of products per market while
+// data node serves realised and unrealised PnL, so they were only ever fetched from data node and never compared.
+var domains = []domain{
+	{
+		name:         "accounts",
+		fromSnapshot: func(s *snap, res *Result) { res.Accounts = s.getAccounts() },
+		fromDatanode: func(dnc *dataNodeClient, res *Result) (err error) {
+			res.Accounts, err = dnc.listAccounts()
+			return
+		},
+		diff: diffAccountBalances,
+		diffSnapshots: func(a, b *Result) Status {
+			return diffEntities("accounts", a.Accounts, b.Accounts, accountKey)
+		},
+	},
+	{
+		name:         "orders",
+		fromSnapshot: func(s *snap, res *Result) { res.Orders = s.getOrders() },
+		fromDatanode: func(dnc *dataNodeClient, res *Result) (err error) {
+			res.Orders, err = dnc.listOrders()
+			return
+		},
+		diff:          diffOrders,
+		diffSnapshots: func(a, b *Result) Status { return diffEntities("orders", a.Orders, b.Orders, orderKey) },
+	},
+	{
+		name:         "markets",
+		fromSnapshot: func(s *snap, res *Result) { res.Markets = s.getMarkets() },
+		fromDatanode: func(dnc *dataNodeClient, res *Result) (err error) {
+			res.Markets, err = dnc.listMarkets()
+			return
+		},
+		diff:          diffMarkets,
+		diffSnapshots: func(a, b *Result) Status { return diffEntities("markets", a.Markets, b.Markets, marketKey) },
+	},
+	{
+		name:         "parties",
+		fromSnapshot: func(s *snap, res *Result) { res.Parties = s.getParties() },
+		fromDatanode: func(dnc *dataNodeClient, res *Result) (err error) {
+			res.Parties, err = dnc.listParties()
+			return
+		},
+		diff:          diffParties,
+		diffSnapshots: func(a, b *Result) Status { return diffEntities("parties", a.Parties, b.Parties, partyKey) },
+	},
+	{
+		name:         "limits",
+		fromSnapshot: func(s *snap, res *Result) { res.Limits = s.getNetLimits() },
+		fromDatanode: func(dnc *dataNodeClient, res *Result) (err error) {
+			res.Limits, err = dnc.getNetworkLimits()
+			return
+		},
+		diff: diffLimits,
+		diffSnapshots: func(a, b *Result) Status {
+			return diffEntities("limits", []*vega.NetworkLimits{a.Limits}, []*vega.NetworkLimits{b.Limits}, func(*vega.NetworkLimits) string { return "limits" })
+		},
+	},
+	{
+		name:         "assets",
+		fromSnapshot: func(s *snap, res *Result) { res.Assets = s.getAssets() },
+		fromDatanode: func(dnc *dataNodeClient, res *Result) (err error) {
+			res.Assets, err = dnc.listAssets()
+			return
+		},
+		diff:          diffAssets,
+		diffSnapshots: func(a, b *Result) Status { return diffEntities("assets", a.Assets, b.Assets, assetKey) },
+	},
+	{
+		name:         "delegations",
+		pinned:       true,
+		fromSnapshot: func(s *snap, res *Result) { res.Delegations = s.getDelegations() },
+		fromDatanode: func(dnc *dataNodeClient, res *Result) (err error) {
+			res.Delegations, err = dnc.listDelegations()
+			return
+		},
+		diff: diffDelegations,
+		diffSnapshots: func(a, b *Result) Status {
+			return diffEntities("delegations", a.Delegations, b.Delegations, delegationKey)
+		},
+	},
+	{
+		name:         "epoch",
+		pinned:       true,
+		fromSnapshot: func(s *snap, res *Result) { res.Epoch = s.getEpoch() },
+		fromDatanode: func(dnc *dataNodeClient, res *Result) (err error) {
+			res.Epoch, err = dnc.getEpoch()
+			return
+		},
+		diff: diffEpoch,
+		diffSnapshots: func(a, b *Result) Status {
+			return diffEntities("epoch", []*vega.Epoch{a.Epoch}, []*vega.Epoch{b.Epoch}, func(*vega.Epoch) string { return "epoch" })
+		},
+	},
+	{
+		name:         "vegaTime",
+		fromSnapshot: func(s *snap, res *Result) { res.VegaTime = s.getVegaTime() },
+		fromDatanode: func(dnc *dataNodeClient, res *Result) (err error) {
+			res.VegaTime, err = dnc.getVegaTime()
+			return
+		},
+		diff: diffVegaTime,
+		diffSnapshots: func(a, b *Result) Status {
+			if a.VegaTime != b.VegaTime {
+				return getSimpleValueMismatchStatus("vegaTime", strconv.FormatInt(a.VegaTime, 10), strconv.FormatInt(b.VegaTime, 10))
+			}
+			return getSimpleSuccessStatus("vegaTime")
+		},
+	},
+	{
+		name:         "nodes",
+		pinned:       true,
+		fromSnapshot: func(s *snap, res *Result) { res.Nodes = s.getValidators() },
+		fromDatanode: func(dnc *dataNodeClient, res *Result) (err error) {
+			res.Nodes, err = dnc.listNodes()
+			return
+		},
+		diff:          diffNodes,
+		diffSnapshots: func(a, b *Result) Status { return diffEntities("nodes", a.Nodes, b.Nodes, nodeKey) },
+	},
+	{
+		name:         "netparams",
+		fromSnapshot: func(s *snap, res *Result) { res.NetParams = s.getNetParams() },
+		fromDatanode: func(dnc *dataNodeClient, res *Result) (err error) {
+			res.NetParams, err = dnc.listNetworkParameters()
+			return
+		},
+		diff: diffNetParams,
+		diffSnapshots: func(a, b *Result) Status {
+			return diffEntities("netparams", a.NetParams, b.NetParams, netParamKey)
+		},
+	},
+	{
+		name:         "proposals",
+		fromSnapshot: func(s *snap, res *Result) { res.Proposals = s.getProposals() },
+		fromDatanode: func(dnc *dataNodeClient, res *Result) (err error) {
+			res.Proposals, err = dnc.listGovernanceData()
+			return
+		},
+		diff: diffProposals,
+		diffSnapshots: func(a, b *Result) Status {
+			return diffEntities("proposals", a.Proposals, b.Proposals, proposalKey)
+		},
+	},
+	{
+		name:         "deposits",
+		fromSnapshot: func(s *snap, res *Result) { res.Deposits = s.getDeposits() },
+		fromDatanode: func(dnc *dataNodeClient, res *Result) (err error) {
+			res.Deposits, err = dnc.listDeposits()
+			return
+		},
+		diff:          diffDeposits,
+		diffSnapshots: func(a, b *Result) Status { return diffEntities("deposits", a.Deposits, b.Deposits, depositKey) },
+	},
+	{
+		name:         "withdrawals",
+		fromSnapshot: func(s *snap, res *Result) { res.Withdrawals = s.getWithdrawals() },
+		fromDatanode: func(dnc *dataNodeClient, res *Result) (err error) {
+			res.Withdrawals, err = dnc.listWithdrawals()
+			return
+		},
+		diff: diffWithdrawals,
+		diffSnapshots: func(a, b *Result) Status {
+			return diffEntities("withdrawals", a.Withdrawals, b.Withdrawals, withdrawalKey)
+		},
+	},
+	{
+		name:         "liquidityProvisions",
+		fromSnapshot: func(s *snap, res *Result) { res.Lps = s.getLps() },
+		fromDatanode: func(dnc *dataNodeClient, res *Result) (err error) {
+			res.Lps, err = dnc.listAllLiquidityProvisions()
+			return
+		},
+		diff:          diffLPs,
+		diffSnapshots: func(a, b *Result) Status { return diffEntities("liquidityProvisions", a.Lps, b.Lps, lpKey) },
+	},
+	{
+		name:         "stake",
+		fromSnapshot: func(s *snap, res *Result) { res.Stake = s.getStake() },
+		fromDatanode: func(dnc *dataNodeClient, res *Result) (err error) {
+			res.Stake, err = dnc.getStake()
+			return
+		},
+		diff:          diffStake,
+		diffSnapshots: func(a, b *Result) Status { return diffEntities("stake", a.Stake, b.Stake, stakeKey) },
+	},
+	{
+		name:         "transfers",
+		fromSnapshot: func(s *snap, res *Result) { res.Transfers = s.getTransfers() },
+		fromDatanode: func(dnc *dataNodeClient, res *Result) (err error) {
+			res.Transfers, err = dnc.listTransfers()
+			return
+		},
+		diff: diffTransfers,
+		diffSnapshots: func(a, b *Result) Status {
+			return diffEntities("transfers", a.Transfers, b.Transfers, transferKey)
+		},
+	},
+	{
+		name:         "marketData",
+		fromSnapshot: func(s *snap, res *Result) { res.MarketData = s.getMarketData() },
+		fromDatanode: func(dnc *dataNodeClient, res *Result) (err error) {
+			res.MarketData, err = dnc.listLatestMarketData()
+			return
+		},
+		diff: diffMarketData,
+		diffSnapshots: func(a, b *Result) Status {
+			return diffEntities("marketData", a.MarketData, b.MarketData, marketDataKey)
+		},
+	},
+	{
+		name:         "stopOrders",
+		fromSnapshot: func(s *snap, res *Result) { res.StopOrders = s.getStopOrders() },
+		fromDatanode: func(dnc *dataNodeClient, res *Result) (err error) {
+			res.StopOrders, err = dnc.listStopOrders()
+			return
+		},
+		diff: func(core, datanode *Result) Status {
+			return diffEntities("stopOrders", core.StopOrders, datanode.StopOrders, stopOrderKey)
+		},
+		diffSnapshots: func(a, b *Result) Status {
+			return diffEntities("stopOrders", a.StopOrders, b.StopOrders, stopOrderKey)
+		},
+	},
+	{
+		// core only holds the fees stats of the current epoch and data node only those of ended epochs, so they are
+		// compared once data node is past the epoch of the snapshot
+		name:         "fees",
+		fromSnapshot: func(s *snap, res *Result) { res.FeesStats = s.getFeesStats() },
+		fromDatanode: func(dnc *dataNodeClient, res *Result) (err error) {
+			res.FeesStats, res.FeesStatsPending, err = dnc.listFeesStats()
+			return
+		},
+		diff: diffFeesStats,
+		diffSnapshots: func(a, b *Result) Status {
+			return diffEntities("fees", a.FeesStats, b.FeesStats, feesStatsKey)
+		},
+	},
+	{
+		// rewards are compared as the locked and vesting balances of the vesting engine, the reward accounts are
+		// compared by the accounts domain already
+		name:         "vestingBalances",
+		fromSnapshot: func(s *snap, res *Result) { res.VestingBalances = s.getVestingBalances() },
+		fromDatanode: func(dnc *dataNodeClient, res *Result) (err error) {
+			res.VestingBalances, err = dnc.listVestingBalances()
+			return
+		},
+		diff: diffVestingBalances,
+		diffSnapshots: func(a, b *Result) Status {
+			return diffEntities("vestingBalances", a.VestingBalances, b.VestingBalances, vestingBalancesKey)
+		},
+	},
+	{
+		name:         "referralPrograms",
+		fromSnapshot: func(s *snap, res *Result) { res.ReferralPrograms = s.getReferralPrograms() },
+		fromDatanode: func(dnc *dataNodeClient, res *Result) (err error) {
+			res.ReferralPrograms, err = dnc.getReferralPrograms()
+			return
+		},
+		diff: func(core, datanode *Result) Status {
+			return diffEntities("referralPrograms", core.ReferralPrograms, datanode.ReferralPrograms, referralProgramKey)
+		},
+		diffSnapshots: func(a, b *Result) Status {
+			return diffEntities("referralPrograms", a.ReferralPrograms, b.ReferralPrograms, referralProgramKey)
+		},
+	},
+	{
+		name:         "referralSets",
+		fromSnapshot: func(s *snap, res *Result) { res.ReferralSets, _ = s.getReferralSets() },
+		fromDatanode: func(dnc *dataNodeClient, res *Result) (err error) {
+			res.ReferralSets, err = dnc.listReferralSets()
+			return
+		},
+		diff: func(core, datanode *Result) Status {
+			return diffEntities("referralSets", core.ReferralSets, datanode.ReferralSets, referralSetKey)
+		},
+		diffSnapshots: func(a, b *Result) Status {
+			return diffEntities("referralSets", a.ReferralSets, b.ReferralSets, referralSetKey)
+		},
+	},
+	{
+		name:         "referralSetReferees",
+		fromSnapshot: func(s *snap, res *Result) { _, res.ReferralSetReferees = s.getReferralSets() },
+		fromDatanode: func(dnc *dataNodeClient, res *Result) (err error) {
+			res.ReferralSetReferees, err = dnc.listReferralSetReferees()
+			return
+		},
+		diff: func(core, datanode *Result) Status {
+			return diffEntities("referralSetReferees", core.ReferralSetReferees, datanode.ReferralSetReferees, referralSetRefereeKey)
+		},
+		diffSnapshots: func(a, b *Result) Status {
+			return diffEntities("referralSetReferees", a.ReferralSetReferees, b.ReferralSetReferees, referralSetRefereeKey)
+		},
+	},
+	{
+		name:         "teams",
+		fromSnapshot: func(s *snap, res *Result) { res.Teams, _ = s.getTeams() },
+		fromDatanode: func(dnc *dataNodeClient, res *Result) (err error) {
+			res.Teams, err = dnc.listTeams()
+			return
+		},
+		diff: func(core, datanode *Result) Status {
+			return diffEntities("teams", core.Teams, datanode.Teams, teamKey)
+		},
+		diffSnapshots: func(a, b *Result) Status {
+			return diffEntities("teams", a.Teams, b.Teams, teamKey)
+		},
+	},
+	{
+		name:         "teamReferees",
+		fromSnapshot: func(s *snap, res *Result) { _, res.TeamReferees = s.getTeams() },
+		fromDatanode: func(dnc *dataNodeClient, res *Result) (err error) {
+			res.TeamReferees, err = dnc.listTeamReferees()
+			return
+		},
+		diff: func(core, datanode *Result) Status {
+			return diffEntities("teamReferees", core.TeamReferees, datanode.TeamReferees, teamRefereeKey)
+		},
+		diffSnapshots: func(a, b *Result) Status {
+			return diffEntities("teamReferees", a.TeamReferees, b.TeamReferees, teamRefereeKey)
+		},
+	},
+	{
+		name:         "oracleSpecs",
+		fromSnapshot: func(s *snap, res *Result) { res.OracleSpecs = s.getOracleSpecs() },
+		fromDatanode: func(dnc *dataNodeClient, res *Result) (err error) {
+			res.OracleSpecs, err = dnc.listOracleSpecs()
+			return
+		},
+		diff: diffOracleSpecs,
+		diffSnapshots: func(a, b *Result) Status {
+			return diffEntities("oracleSpecs", a.OracleSpecs, b.OracleSpecs, oracleSpecKey)
+		},
+	},
+}
+
+// DomainNames returns the names of all the compared domains, which are also the keys of the report.
+func DomainNames() []string {
+	names := make([]string, 0, len(domains))
+	for _, d := range domains {
+		names = append(names, d.name)
+	}
+	return names
+}
+
+// selectDomains returns the domains to compare, all of them or only those in only if given, less those in skip.
+func selectDomains(only, skip []string) ([]domain, error) {
+	known := make(map[string]struct{}, len(domains))
+	for _, d := range domains {
+		known[d.name] = struct{}{}
+	}
+	toSet := func(names []string) (map[string]struct{}, error) {
+		set := make(map[string]struct{}, len(names))
+		for _, n := range names {
+			if _, ok := known[n]; !ok {
+				return nil, fmt.Errorf("unknown domain %s, expected one of %s", n, strings.Join(DomainNames(), ", "))
+			}
+			set[n] = struct{}{}
+		}
+		return set, nil
+	}
+
+	onlySet, err := toSet(only)
+	if err != nil {
+		return nil, err
+	}
+	skipSet, err := toSet(skip)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := []domain{}
+	for _, d := range domains {
+		if _, ok := onlySet[d.name]; len(onlySet) > 0 && !ok {
+			continue
+		}
+		if _, ok := skipSet[d.name]; ok {
+			continue
+		}
+		selected = append(selected, d)
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no domains left to compare")
+	}
+	return selected, nil
+}
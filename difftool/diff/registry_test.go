@@ -0,0 +1,75 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func domainNames(selected []domain) []string {
+	names := make([]string, 0, len(selected))
+	for _, d := range selected {
+		names = append(names, d.name)
+	}
+	return names
+}
+
+func TestDomainsAreComplete(t *testing.T) {
+	seen := map[string]struct{}{}
+	for _, d := range domains {
+		_, dup := seen[d.name]
+		assert.False(t, dup, "domain %s is registered twice", d.name)
+		seen[d.name] = struct{}{}
+		assert.NotNil(t, d.fromSnapshot, d.name)
+		assert.NotNil(t, d.fromDatanode, d.name)
+		assert.NotNil(t, d.diff, d.name)
+		assert.NotNil(t, d.diffSnapshots, d.name)
+	}
+	assert.Equal(t, domainNames(domains), DomainNames())
+}
+
+func TestSelectDomains(t *testing.T) {
+	all, err := selectDomains(nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, DomainNames(), domainNames(all))
+
+	// the registry order is kept whatever the order given
+	only, err := selectDomains([]string{"orders", "accounts"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"accounts", "orders"}, domainNames(only))
+
+	skipped, err := selectDomains(nil, []string{"transfers"})
+	require.NoError(t, err)
+	assert.Len(t, skipped, len(domains)-1)
+	assert.NotContains(t, domainNames(skipped), "transfers")
+
+	both, err := selectDomains([]string{"orders", "accounts"}, []string{"orders"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"accounts"}, domainNames(both))
+}
+
+func TestSelectDomainsErrors(t *testing.T) {
+	_, err := selectDomains([]string{"positions"}, nil)
+	assert.ErrorContains(t, err, "unknown domain positions")
+
+	_, err = selectDomains(nil, []string{"order"})
+	assert.ErrorContains(t, err, "unknown domain order")
+
+	_, err = selectDomains([]string{"orders"}, []string{"orders"})
+	assert.EqualError(t, err, "no domains left to compare")
+}
+
+func TestDiffSnapshotsThroughRegistry(t *testing.T) {
+	selected, err := selectDomains([]string{"parties", "vegaTime"}, nil)
+	require.NoError(t, err)
+
+	first := &Result{VegaTime: 1}
+	second := &Result{VegaTime: 2}
+	dr := newSnapshotDiffReport(first, second, selected)
+	require.Len(t, dr.DiffResult, 2)
+	assert.Equal(t, FullMatch, dr.DiffResult[0].MatchResult)
+	assert.Equal(t, Status{Key: "vegaTime", MatchResult: ValuesMismatch, CoreRes: "1", DatanodeRes: "2", CoreResLen: 1, DataNodeLen: 1},
+		dr.DiffResult[1])
+	assert.False(t, dr.Success)
+}
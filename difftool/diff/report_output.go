@@ -0,0 +1,153 @@
+package diff
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+)
+
+// The formats the report can be written in.
+const (
+	FormatText  = "text"
+	FormatJSON  = "json"
+	FormatJUnit = "junit"
+)
+
+// MismatchError is returned when the report has differences that are not known. ExitCode reflects the worst of them
+// so that scripts can tell a missing entity from a mismatching value.
+type MismatchError struct {
+	Worst  MatchResult
+	report string
+}
+
+func (e *MismatchError) Error() string {
+	return e.report
+}
+
+// ExitCode is 2 for a size mismatch and 3 for a values mismatch, 1 being left for failures to run the comparison.
+func (e *MismatchError) ExitCode() int {
+	return int(e.Worst) + 1
+}
+
+type jsonReport struct {
+	Comparison string      `json:"comparison"`
+	Success    bool        `json:"success"`
+	Worst      MatchResult `json:"worst"`
+	// the heights compared, omitted when comparing snapshots
+	SnapshotHeight uint64   `json:"snapshot_height,omitempty"`
+	DatanodeHeight uint64   `json:"datanode_height,omitempty"`
+	Results        []Status `json:"results"`
+}
+
+type junitTestSuite struct {
+	XMLName  xml.Name        `xml:"testsuite"`
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// finish applies the known differences and writes the report, returning a MismatchError if it has other differences.
+func (dr *Report) finish(name string, opts Opts) error {
+	dr.accept(opts.Known)
+
+	switch opts.Format {
+	case "", FormatText:
+		if !dr.Success {
+			return &MismatchError{Worst: dr.Worst(), report: fmt.Sprintf("mismatch between %s: %s", name, dr.String())}
+		}
+		return nil
+	case FormatJSON, FormatJUnit:
+	default:
+		return fmt.Errorf("unknown report format %s, expected text, json or junit", opts.Format)
+	}
+
+	w := io.Writer(os.Stdout)
+	if len(opts.File) > 0 {
+		f, err := os.Create(opts.File)
+		if err != nil {
+			return fmt.Errorf("failed to create report file: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	var err error
+	if opts.Format == FormatJSON {
+		err = dr.writeJSON(w, name)
+	} else {
+		err = dr.writeJUnit(w, name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+
+	if !dr.Success {
+		return &MismatchError{Worst: dr.Worst(), report: fmt.Sprintf("mismatch between %s: %s", name, matchResultToName[dr.Worst()])}
+	}
+	return nil
+}
+
+func (dr *Report) writeJSON(w io.Writer, name string) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(jsonReport{
+		Comparison:     name,
+		Success:        dr.Success,
+		Worst:          dr.Worst(),
+		SnapshotHeight: dr.SnapshotHeight,
+		DatanodeHeight: dr.DatanodeHeight,
+		Results:        dr.DiffResult,
+	})
+}
+
+// writeJUnit writes a test case per key, failed if it has differences that are not known and skipped if they are or,
+// unless those fail the run, are possibly stale. Keys that are not compared yet are skipped too.
+func (dr *Report) writeJUnit(w io.Writer, name string) error {
+	suite := junitTestSuite{Name: "difftool " + name, Tests: len(dr.DiffResult)}
+	for _, ds := range dr.DiffResult {
+		tc := junitTestCase{Name: ds.Key, ClassName: "difftool"}
+		switch {
+		case ds.Pending:
+			tc.Skipped = &junitMessage{Message: "not compared yet", Body: ds.format(dr.sides)}
+			suite.Skipped++
+		case ds.MatchResult == FullMatch:
+		case ds.Known:
+			tc.Skipped = &junitMessage{Message: "known difference: " + ds.KnownReason, Body: ds.format(dr.sides)}
+			suite.Skipped++
+		case ds.PossiblyStale && !dr.failOnStale:
+			tc.Skipped = &junitMessage{Message: "possibly stale", Body: ds.format(dr.sides)}
+			suite.Skipped++
+		default:
+			tc.Failure = &junitMessage{Message: matchResultToName[ds.MatchResult], Body: ds.format(dr.sides)}
+			suite.Failures++
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suite); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
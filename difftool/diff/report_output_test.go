@@ -0,0 +1,127 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testReport() *Report {
+	return &Report{
+		sides: coreDatanodeSides,
+		DiffResult: []Status{
+			{Key: "markets", MatchResult: FullMatch, CoreResLen: 1, DataNodeLen: 1},
+			{Key: "orders", MatchResult: SizeMismatch, CoreResLen: 2, DataNodeLen: 1, OnlyInCore: []string{"o1"}},
+			{Key: "parties", MatchResult: ValuesMismatch, CoreResLen: 1, DataNodeLen: 1, Changed: []EntityDiff{
+				{ID: "p1", Fields: []FieldDiff{{Path: "alias", Core: "a", Datanode: "b"}}},
+			}},
+		},
+		SnapshotHeight: 10,
+		DatanodeHeight: 10,
+	}
+}
+
+func TestStatusFormat(t *testing.T) {
+	dr := testReport()
+	assert.Equal(t, "key=orders, matchResult=mismatching number of elements, coreLength=2, datanodeLength=1\n  only in core: o1",
+		dr.DiffResult[1].String())
+	assert.Equal(t, "key=parties, matchResult=mismatching values, firstLength=1, secondLength=1\n  p1: alias first=a second=b",
+		dr.DiffResult[2].format(snapshotSides))
+}
+
+func TestWriteJSON(t *testing.T) {
+	dr := testReport()
+	dr.accept(nil)
+
+	buf := bytes.Buffer{}
+	require.NoError(t, dr.writeJSON(&buf, "core and datanode"))
+
+	var got map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.Equal(t, "core and datanode", got["comparison"])
+	assert.Equal(t, false, got["success"])
+	assert.Equal(t, "values_mismatch", got["worst"])
+	assert.Equal(t, float64(10), got["snapshot_height"])
+	results := got["results"].([]interface{})
+	require.Len(t, results, 3)
+	assert.Equal(t, "size_mismatch", results[1].(map[string]interface{})["match_result"])
+	assert.Equal(t, []interface{}{"o1"}, results[1].(map[string]interface{})["only_in_core"])
+}
+
+func TestWriteJUnit(t *testing.T) {
+	dr := testReport()
+	dr.accept(KnownDifferences{"orders": {All: true, Reason: "bug"}})
+
+	buf := bytes.Buffer{}
+	require.NoError(t, dr.writeJUnit(&buf, "core and datanode"))
+	out := buf.String()
+	assert.Contains(t, out, `<testsuite name="difftool core and datanode" tests="3" failures="1" skipped="1">`)
+	assert.Contains(t, out, `<testcase name="markets" classname="difftool"></testcase>`)
+	assert.Contains(t, out, `<skipped message="known difference: bug">`)
+	assert.Contains(t, out, `<failure message="mismatching values">key=parties`)
+
+	dr.DiffResult = append(dr.DiffResult, Status{Key: "fees", MatchResult: FullMatch, Pending: true})
+	buf.Reset()
+	require.NoError(t, dr.writeJUnit(&buf, "core and datanode"))
+	assert.Contains(t, buf.String(), `<skipped message="not compared yet">key=fees`)
+}
+
+func TestFinish(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	err := testReport().finish("core and datanode", Opts{Format: FormatJSON, File: path})
+
+	var mismatch *MismatchError
+	require.True(t, errors.As(err, &mismatch))
+	assert.Equal(t, 3, mismatch.ExitCode())
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"worst": "values_mismatch"`)
+
+	err = testReport().finish("core and datanode", Opts{Known: KnownDifferences{"parties": {All: true}}})
+	require.True(t, errors.As(err, &mismatch))
+	assert.Equal(t, 2, mismatch.ExitCode())
+
+	err = testReport().finish("core and datanode", Opts{Known: KnownDifferences{"parties": {All: true}, "orders": {IDs: []string{"o1"}}}})
+	assert.NoError(t, err)
+
+	err = testReport().finish("core and datanode", Opts{Format: "xml"})
+	assert.EqualError(t, err, "unknown report format xml, expected text, json or junit")
+}
+
+func TestStaleMismatches(t *testing.T) {
+	// orders and parties cannot be pinned to the snapshot height and data node has moved past it
+	domains := []domain{{name: "markets", pinned: true}, {name: "orders"}, {name: "parties"}}
+	staleReport := func() *Report {
+		dr := testReport()
+		dr.markStale(domains, 10, 12)
+		return dr
+	}
+	assert.False(t, staleReport().DiffResult[0].PossiblyStale)
+	assert.True(t, staleReport().DiffResult[1].PossiblyStale)
+
+	assert.NoError(t, staleReport().finish("core and datanode", Opts{}))
+
+	dr := staleReport()
+	dr.failOnStale = true
+	err := dr.finish("core and datanode", Opts{})
+	var mismatch *MismatchError
+	require.True(t, errors.As(err, &mismatch))
+	assert.Equal(t, 3, mismatch.ExitCode())
+
+	buf := bytes.Buffer{}
+	require.NoError(t, dr.writeJUnit(&buf, "core and datanode"))
+	assert.Contains(t, buf.String(), `failures="2" skipped="0"`)
+
+	dr = staleReport()
+	dr.accept(nil)
+	buf.Reset()
+	require.NoError(t, dr.writeJUnit(&buf, "core and datanode"))
+	assert.Contains(t, buf.String(), `failures="0" skipped="2"`)
+	assert.Contains(t, buf.String(), `<skipped message="possibly stale">`)
+}
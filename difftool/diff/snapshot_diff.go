@@ -0,0 +1,35 @@
+package diff
+
+import "google.golang.org/protobuf/proto"
+
+// newSnapshotDiffReport compares two core snapshots engine by engine. Unlike the comparison with data node nothing is
+// filtered out, every entity must be in both snapshots and match.
+func newSnapshotDiffReport(first *Result, second *Result, domains []domain) *Report {
+	d := &Report{
+		coreResult:     first,
+		datanodeResult: second,
+		sides:          snapshotSides,
+		DiffResult:     []Status{},
+		Success:        true,
+	}
+	diffFuncs := make([]func(*Result, *Result) Status, 0, len(domains))
+	for _, dom := range domains {
+		diffFuncs = append(diffFuncs, dom.diffSnapshots)
+	}
+	d.run(diffFuncs)
+	return d
+}
+
+// diffEntities matches the entities of two snapshots by the key returned by id and lists the keys of the entities
+// only in the first (removed), only in the second (added), those held by more than one entity and the fields of those
+// in both that changed.
+func diffEntities[A proto.Message](key string, first, second []A, id func(A) string) Status {
+	status := getEntityMismatchStatus(key, FullMatch, first, second, id)
+	switch {
+	case len(status.Changed) > 0:
+		status.MatchResult = ValuesMismatch
+	case len(status.OnlyInCore) > 0 || len(status.OnlyInDatanode) > 0 || len(status.Duplicates) > 0:
+		status.MatchResult = SizeMismatch
+	}
+	return status
+}
@@ -5,7 +5,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	snapshot "code.vegaprotocol.io/vega/protos/vega/snapshot/v1"
 	db "github.com/cometbft/cometbft-db"
@@ -15,6 +19,9 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
+// snapshotDBName is the name core gives the snapshot database.
+const snapshotDBName = "snapshot"
+
 // SnapshotData is a representation of the information we an scrape from the avl tree
 type SnapshotData struct {
 	Version int64  `json:"version"`
@@ -105,7 +112,10 @@ func writeSnapshotAsProtobuf(tree *iavl.MutableTree, outputPath string) error {
 	if err != nil {
 		return err
 	}
+	return writePayloads(payloads, outputPath)
+}
 
+func writePayloads(payloads []*snapshot.Payload, outputPath string) error {
 	f, _ := os.Create(outputPath)
 	defer f.Close()
 
@@ -139,26 +149,131 @@ func displayNumberOfVersions(versions int) error {
 	return nil
 }
 
-// SnapshotRun is the main entry point for this tool
-func SnapshotRun(dbpath string, versionsOnly bool, outputPath string, heightToOutput int64, outputFormat string) error {
+// openSnapshotTree opens the snapshot database read only and loads its tree. The database must be closed once done.
+func openSnapshotTree(dbpath string) (*iavl.MutableTree, db.DB, error) {
 	// Attempt to open the database
 	options := &opt.Options{
 		ErrorIfMissing: true,
 		ReadOnly:       true,
 	}
-	db, err := db.NewGoLevelDBWithOpts("snapshot", dbpath, options)
+	ldb, err := db.NewGoLevelDBWithOpts(snapshotDBName, dbpath, options)
 	if err != nil {
-		return fmt.Errorf("failed to open database located at %s : %w", dbpath, err)
+		return nil, nil, fmt.Errorf("failed to open database located at %s : %w", dbpath, err)
 	}
 
-	tree, err := iavl.NewMutableTree(db, 0, false)
+	tree, err := iavl.NewMutableTree(ldb, 0, false)
 	if err != nil {
-		return err
+		ldb.Close()
+		return nil, nil, err
 	}
 
 	if _, err := tree.Load(); err != nil {
+		ldb.Close()
+		return nil, nil, err
+	}
+	return tree, ldb, nil
+}
+
+// copySnapshotDB copies the snapshot database to dir, replacing any earlier copy, so that it can be read while core
+// holds the lock of the database. The table files never change once written so are hard linked where possible.
+func copySnapshotDB(dbpath, dir string) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	// the leveldb database is in a directory named after the database
+	dbpath, dir = filepath.Join(dbpath, snapshotDBName+".db"), filepath.Join(dir, snapshotDBName+".db")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(dbpath)
+	if err != nil {
+		return err
+	}
+	// the manifest must not be older than the files it lists, so CURRENT and the manifest are copied first
+	sort.SliceStable(entries, func(i, j int) bool { return dbCopyOrder(entries[i].Name()) < dbCopyOrder(entries[j].Name()) })
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || name == "LOCK" {
+			continue
+		}
+		src, dst := filepath.Join(dbpath, name), filepath.Join(dir, name)
+		if filepath.Ext(name) == ".ldb" && os.Link(src, dst) == nil {
+			continue
+		}
+		if err := copyFile(src, dst); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dbCopyOrder(name string) int {
+	switch {
+	case name == "CURRENT":
+		return 0
+	case strings.HasPrefix(name, "MANIFEST-"):
+		return 1
+	default:
+		return 2
+	}
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// openSnapshotCopy copies the snapshot database to dir and opens the copy. The database must be closed once done.
+func openSnapshotCopy(dbpath, dir string) (*iavl.MutableTree, db.DB, error) {
+	if err := copySnapshotDB(dbpath, dir); err != nil {
+		return nil, nil, fmt.Errorf("failed to copy database located at %s : %w", dbpath, err)
+	}
+	return openSnapshotTree(dir)
+}
+
+// versionsAfter returns the versions of the tree newer than after, oldest first.
+func versionsAfter(tree *iavl.MutableTree, after int64) []int64 {
+	versions := []int64{}
+	for _, v := range tree.AvailableVersions() {
+		if int64(v) > after {
+			versions = append(versions, int64(v))
+		}
+	}
+	return versions
+}
+
+// writeSnapshotVersion writes a version of the tree to outputPath as protobuf and returns its block height.
+func writeSnapshotVersion(tree *iavl.MutableTree, version int64, outputPath string) (uint64, error) {
+	if _, err := tree.LazyLoadVersion(version); err != nil {
+		return 0, err
+	}
+	payloads, blockHeight, err := getAllPayloads(tree)
+	if err != nil {
+		return 0, err
+	}
+	return blockHeight, writePayloads(payloads, outputPath)
+}
+
+// SnapshotRun is the main entry point for this tool
+func SnapshotRun(dbpath string, versionsOnly bool, outputPath string, heightToOutput int64, outputFormat string) error {
+	tree, ldb, err := openSnapshotTree(dbpath)
+	if err != nil {
 		return err
 	}
+	defer ldb.Close()
 	versions := tree.AvailableVersions()
 
 	switch {
@@ -0,0 +1,52 @@
+package diff
+
+import (
+	"path/filepath"
+	"testing"
+
+	db "github.com/cometbft/cometbft-db"
+	"github.com/cosmos/iavl"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeSnapshotDB writes a snapshot database with the given number of versions and returns it still open, as core
+// keeps it.
+func writeSnapshotDB(t *testing.T, dbpath string, versions int) db.DB {
+	t.Helper()
+	ldb, err := db.NewGoLevelDB(snapshotDBName, dbpath)
+	require.NoError(t, err)
+	tree, err := iavl.NewMutableTree(ldb, 0, false)
+	require.NoError(t, err)
+	for v := 0; v < versions; v++ {
+		_, err := tree.Set([]byte{byte(v)}, []byte("payload"))
+		require.NoError(t, err)
+		_, _, err = tree.SaveVersion()
+		require.NoError(t, err)
+	}
+	return ldb
+}
+
+func TestOpenSnapshotCopyWhileDatabaseIsOpen(t *testing.T) {
+	dbpath, copyDir := t.TempDir(), filepath.Join(t.TempDir(), snapshotCopyDir)
+	ldb := writeSnapshotDB(t, dbpath, 3)
+	defer ldb.Close()
+
+	// the database is locked by its writer
+	_, _, err := openSnapshotTree(dbpath)
+	require.Error(t, err)
+
+	tree, copied, err := openSnapshotCopy(dbpath, copyDir)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2, 3}, versionsAfter(tree, 0))
+	assert.Equal(t, []int64{3}, versionsAfter(tree, 2))
+	assert.Empty(t, versionsAfter(tree, 3))
+	require.NoError(t, copied.Close())
+
+	// the earlier copy is replaced
+	tree, copied, err = openSnapshotCopy(dbpath, copyDir)
+	require.NoError(t, err)
+	defer copied.Close()
+	assert.Equal(t, []int64{1, 2, 3}, versionsAfter(tree, 0))
+}
@@ -2,6 +2,7 @@ package diff
 
 import (
 	"fmt"
+	"strings"
 
 	dn "code.vegaprotocol.io/vega/protos/data-node/api/v2"
 	"code.vegaprotocol.io/vega/protos/vega"
@@ -26,6 +27,17 @@ var matchResultToName map[MatchResult]string = map[MatchResult]string{
 	ValuesMismatch: "mismatching values",
 }
 
+var matchResultToCode = map[MatchResult]string{
+	FullMatch:      "full_match",
+	SizeMismatch:   "size_mismatch",
+	ValuesMismatch: "values_mismatch",
+}
+
+// MarshalText writes the match result as a code in machine readable reports.
+func (r MatchResult) MarshalText() ([]byte, error) {
+	return []byte(matchResultToCode[r]), nil
+}
+
 // Result corresponds to a dataset representing data node state ot core snapshot state.
 type Result struct {
 	Accounts    []*dn.AccountBalance
@@ -43,37 +55,145 @@ type Result struct {
 	Deposits    []*vega.Deposit
 	Withdrawals []*vega.Withdrawal
 	Transfers   []*v1.Transfer
-	Positions   []*vega.Position
 	Lps         []*vega.LiquidityProvision
 	Stake       []*v1.StakeLinking
+
+	MarketData          []*vega.MarketData
+	StopOrders          []*vega.StopOrder
+	FeesStats           []*v1.FeesStats
+	VestingBalances     []*dn.GetVestingBalancesSummaryResponse
+	ReferralPrograms    []*vega.ReferralProgram
+	ReferralSets        []*dn.ReferralSet
+	ReferralSetReferees []*dn.ReferralSetReferee
+	Teams               []*dn.Team
+	TeamReferees        []*dn.TeamReferee
+	OracleSpecs         []*vega.DataSourceSpec
+
+	// FeesStatsPending is set by data node while the epoch of the snapshot has not ended, as it only has the fees
+	// stats of ended epochs
+	FeesStatsPending bool
 }
 
 // Status is a diff summary report for a key.
 type Status struct {
-	Key         string
-	MatchResult MatchResult
-	DatanodeRes string
-	CoreRes     string
-	CoreResLen  int
-	DataNodeLen int
+	Key         string      `json:"key"`
+	MatchResult MatchResult `json:"match_result"`
+	DatanodeRes string      `json:"datanode_result,omitempty"`
+	CoreRes     string      `json:"core_result,omitempty"`
+	CoreResLen  int         `json:"core_length"`
+	DataNodeLen int         `json:"datanode_length"`
+	// OnlyInCore and OnlyInDatanode are the keys of the entities found on one side only, Duplicates the keys held by
+	// more than one entity on either side, and Changed the fields that differ for the entities found on both.
+	OnlyInCore     []string     `json:"only_in_core,omitempty"`
+	OnlyInDatanode []string     `json:"only_in_datanode,omitempty"`
+	Duplicates     []string     `json:"duplicates,omitempty"`
+	Changed        []EntityDiff `json:"changed,omitempty"`
+	// Known is set when all the differences are listed as known differences, with the reason they are accepted.
+	Known       bool   `json:"known,omitempty"`
+	KnownReason string `json:"known_reason,omitempty"`
+	// PossiblyStale is set when data node could not be queried as of the snapshot height and had moved past it, so
+	// the differences may be changes made since.
+	PossiblyStale bool `json:"possibly_stale,omitempty"`
+	// Pending is set when data node does not have the state to compare with yet, so the key is not compared.
+	Pending bool `json:"pending,omitempty"`
+}
+
+// sides are the names of the two sides of a comparison used in reports.
+type sides struct {
+	core     string
+	datanode string
 }
 
+var (
+	coreDatanodeSides = sides{core: "core", datanode: "datanode"}
+	snapshotSides     = sides{core: "first", datanode: "second"}
+)
+
 func (ds Status) String() string {
-	return fmt.Sprintf("key=%s, matchResult=%s, coreLength=%d, datanodeLength=%d, coreResult=%s, datanodeResult=%s", ds.Key, matchResultToName[ds.MatchResult], ds.CoreResLen, ds.DataNodeLen, ds.CoreRes, ds.DatanodeRes)
+	return ds.format(coreDatanodeSides)
+}
+
+func (ds Status) format(s sides) string {
+	str := fmt.Sprintf("key=%s, matchResult=%s, %sLength=%d, %sLength=%d", ds.Key, matchResultToName[ds.MatchResult], s.core, ds.CoreResLen, s.datanode, ds.DataNodeLen)
+	if ds.Known {
+		str += fmt.Sprintf(", known difference: %s", ds.KnownReason)
+	}
+	if ds.Pending {
+		str += fmt.Sprintf(", not compared as %s does not have it yet", s.datanode)
+	}
+	if ds.PossiblyStale {
+		str += fmt.Sprintf(", possibly stale as %s has moved past the snapshot", s.datanode)
+	}
+	if len(ds.CoreRes) > 0 || len(ds.DatanodeRes) > 0 {
+		str += fmt.Sprintf(", %sResult=%s, %sResult=%s", s.core, ds.CoreRes, s.datanode, ds.DatanodeRes)
+	}
+	if len(ds.OnlyInCore) > 0 {
+		str += fmt.Sprintf("\n  only in %s: %s", s.core, strings.Join(ds.OnlyInCore, ", "))
+	}
+	if len(ds.OnlyInDatanode) > 0 {
+		str += fmt.Sprintf("\n  only in %s: %s", s.datanode, strings.Join(ds.OnlyInDatanode, ", "))
+	}
+	if len(ds.Duplicates) > 0 {
+		str += fmt.Sprintf("\n  duplicate keys: %s", strings.Join(ds.Duplicates, ", "))
+	}
+	for _, e := range ds.Changed {
+		fields := make([]string, 0, len(e.Fields))
+		for _, f := range e.Fields {
+			fields = append(fields, fmt.Sprintf("%s %s=%s %s=%s", f.Path, s.core, f.Core, s.datanode, f.Datanode))
+		}
+		str += fmt.Sprintf("\n  %s: %s", e.ID, strings.Join(fields, ", "))
+	}
+	return str
 }
 
 // Report is the top level diff result aggregating the results from all compared keys.
 type Report struct {
 	coreResult     *Result
 	datanodeResult *Result
+	sides          sides
 	DiffResult     []Status
 	Success        bool
+	// SnapshotHeight and DatanodeHeight are the heights compared, set when comparing with data node
+	SnapshotHeight uint64
+	DatanodeHeight uint64
+	// failOnStale counts the differences that are possibly stale as any other
+	failOnStale bool
+}
+
+// Worst returns the worst match result of the keys whose differences are not known, nor possibly stale unless
+// those fail the run too.
+func (dr *Report) Worst() MatchResult {
+	worst := FullMatch
+	for _, ds := range dr.DiffResult {
+		if !ds.Known && (!ds.PossiblyStale || dr.failOnStale) && ds.MatchResult > worst {
+			worst = ds.MatchResult
+		}
+	}
+	return worst
+}
+
+// markStale marks the differences of the domains that could not be pinned to the snapshot height as possibly stale
+// if data node had moved past it by the end of the collection.
+func (dr *Report) markStale(domains []domain, snapshotHeight, datanodeHeight uint64) {
+	dr.SnapshotHeight, dr.DatanodeHeight = snapshotHeight, datanodeHeight
+	if datanodeHeight <= snapshotHeight {
+		return
+	}
+	pinned := map[string]bool{}
+	for _, d := range domains {
+		pinned[d.name] = d.pinned
+	}
+	for i, ds := range dr.DiffResult {
+		if ds.MatchResult != FullMatch && !pinned[ds.Key] {
+			dr.DiffResult[i].PossiblyStale = true
+		}
+	}
 }
 
 func (dr *Report) String() string {
 	str := ""
 	for _, ds := range dr.DiffResult {
-		str += ds.String() + "\n"
+		str += ds.format(dr.sides) + "\n"
 	}
 	return fmt.Sprintf("success=%t, report:\n%s", dr.Success, str)
 }
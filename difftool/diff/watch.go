@@ -0,0 +1,232 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cosmos/iavl"
+)
+
+// WatchOpts control how new snapshots are picked up and how divergences are recorded and alerted.
+type WatchOpts struct {
+	// Interval is how often the snapshot database is checked for new versions
+	Interval time.Duration
+	// WorkDir is where the snapshot database is copied to be read, and the snapshots are written to be compared
+	WorkDir string
+	// Keep is the number of the latest snapshot files kept in WorkDir, older ones are removed
+	Keep int
+	// HistoryFile is the file the result of every comparison is appended to as a line of JSON, if set
+	HistoryFile string
+	// Webhook is the URL the result of a comparison is posted to when it diverges, if set
+	Webhook string
+	// ExitOnDivergence stops watching with a MismatchError on the first divergence
+	ExitOnDivergence bool
+}
+
+// historyEntry is the result of comparing one snapshot, written to the history file and posted to the webhook.
+type historyEntry struct {
+	Time           time.Time   `json:"time"`
+	Version        int64       `json:"version"`
+	SnapshotHeight uint64      `json:"snapshot_height"`
+	DatanodeHeight uint64      `json:"datanode_height,omitempty"`
+	Success        bool        `json:"success"`
+	Worst          MatchResult `json:"worst"`
+	Error          string      `json:"error,omitempty"`
+	// Results only holds the keys that do not fully match, to keep the history small
+	Results []Status `json:"results,omitempty"`
+}
+
+const (
+	// defaultWatchInterval is how often the snapshot database is checked if not set
+	defaultWatchInterval = 10 * time.Second
+
+	snapshotFilePrefix = "snapshot-"
+	snapshotFileSuffix = ".dat"
+	// snapshotCopyDir is the directory of WorkDir the snapshot database is copied to before being read
+	snapshotCopyDir = "snapshot-db"
+)
+
+// Watch compares every new version of the snapshot database with data node at its height, starting with the latest
+// one, until it fails to record a result or, with ExitOnDivergence, a divergence is found.
+func Watch(dbpath, datanodeConnection string, opts Opts, watch WatchOpts) error {
+	domains, err := selectDomains(opts.Only, opts.Skip)
+	if err != nil {
+		return err
+	}
+	if watch.Interval <= 0 {
+		watch.Interval = defaultWatchInterval
+	}
+	if err := os.MkdirAll(watch.WorkDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create work directory: %w", err)
+	}
+
+	lastVersion := int64(-1)
+	for {
+		if lastVersion, err = compareNewVersions(dbpath, datanodeConnection, lastVersion, domains, opts, watch); err != nil {
+			return err
+		}
+		time.Sleep(watch.Interval)
+	}
+}
+
+// compareNewVersions compares the versions of a copy of the snapshot database newer than lastVersion, only the latest
+// one the first time, and returns the last version compared.
+func compareNewVersions(dbpath, datanodeConnection string, lastVersion int64, domains []domain, opts Opts, watch WatchOpts) (int64, error) {
+	// core holds the lock of the database so it is read from a copy
+	tree, ldb, err := openSnapshotCopy(dbpath, filepath.Join(watch.WorkDir, snapshotCopyDir))
+	if err != nil {
+		// core may compact the database while it is copied, try again on the next tick
+		fmt.Fprintf(os.Stderr, "failed to read snapshot versions: %v\n", err)
+		return lastVersion, nil
+	}
+	defer ldb.Close()
+
+	versions := versionsAfter(tree, lastVersion)
+	// the versions already in the database when starting are history, only the latest is compared
+	if lastVersion < 0 && len(versions) > 1 {
+		versions = versions[len(versions)-1:]
+	}
+
+	for _, v := range versions {
+		entry := compareVersion(tree, datanodeConnection, v, domains, opts, watch)
+		lastVersion = v
+
+		if err := appendHistory(watch.HistoryFile, entry); err != nil {
+			return lastVersion, err
+		}
+		if err := pruneSnapshots(watch.WorkDir, watch.Keep); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to prune snapshots: %v\n", err)
+		}
+
+		if entry.Success || len(entry.Error) > 0 {
+			continue
+		}
+		if len(watch.Webhook) > 0 {
+			if err := postWebhook(watch.Webhook, entry); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to post divergence at height %d to webhook: %v\n", entry.SnapshotHeight, err)
+			}
+		}
+		if watch.ExitOnDivergence {
+			return lastVersion, &MismatchError{Worst: entry.Worst, report: fmt.Sprintf("mismatch between core and datanode at height %d", entry.SnapshotHeight)}
+		}
+	}
+	return lastVersion, nil
+}
+
+// compareVersion writes a version of the snapshot tree to the work directory and compares it with data node.
+func compareVersion(tree *iavl.MutableTree, datanodeConnection string, version int64, domains []domain, opts Opts, watch WatchOpts) historyEntry {
+	entry := historyEntry{Time: time.Now(), Version: version}
+
+	snapshotPath := filepath.Join(watch.WorkDir, snapshotFilePrefix+strconv.FormatInt(version, 10)+snapshotFileSuffix)
+	height, err := writeSnapshotVersion(tree, version, snapshotPath)
+	if err != nil {
+		entry.Error = err.Error()
+		fmt.Fprintf(os.Stderr, "failed to write snapshot version %d: %v\n", version, err)
+		return entry
+	}
+	entry.SnapshotHeight = height
+
+	diffReport, err := compareWithDatanode(snapshotPath, datanodeConnection, domains, opts)
+	if err != nil {
+		entry.Error = err.Error()
+		fmt.Fprintf(os.Stderr, "failed to compare snapshot at height %d: %v\n", height, err)
+		return entry
+	}
+	diffReport.accept(opts.Known)
+
+	entry.DatanodeHeight = diffReport.DatanodeHeight
+	entry.Success = diffReport.Success
+	entry.Worst = diffReport.Worst()
+	for _, ds := range diffReport.DiffResult {
+		if ds.MatchResult != FullMatch {
+			entry.Results = append(entry.Results, ds)
+		}
+	}
+
+	if entry.Success {
+		fmt.Printf("height %d: core and datanode match\n", height)
+	} else {
+		fmt.Printf("height %d: mismatch between core and datanode: %s", height, diffReport)
+	}
+	return entry
+}
+
+func appendHistory(path string, entry historyEntry) error {
+	if len(path) == 0 {
+		return nil
+	}
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+	return nil
+}
+
+func postWebhook(url string, entry historyEntry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// pruneSnapshots removes all but the keep latest snapshot files written to the work directory.
+func pruneSnapshots(dir string, keep int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	versions := []int64{}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, snapshotFilePrefix) || !strings.HasSuffix(name, snapshotFileSuffix) {
+			continue
+		}
+		v, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(name, snapshotFilePrefix), snapshotFileSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, v)
+	}
+	if keep < 0 {
+		keep = 0
+	}
+	if len(versions) <= keep {
+		return nil
+	}
+
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	for _, v := range versions[:len(versions)-keep] {
+		path := filepath.Join(dir, snapshotFilePrefix+strconv.FormatInt(v, 10)+snapshotFileSuffix)
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
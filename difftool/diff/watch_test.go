@@ -0,0 +1,62 @@
+package diff
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPruneSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"snapshot-9.dat", "snapshot-10.dat", "snapshot-11.dat", "notes.txt"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o644))
+	}
+	require.NoError(t, os.Mkdir(filepath.Join(dir, snapshotCopyDir), 0o755))
+
+	// versions are ordered by number rather than name
+	require.NoError(t, pruneSnapshots(dir, 2))
+	assert.Equal(t, []string{"notes.txt", "snapshot-10.dat", "snapshot-11.dat", snapshotCopyDir}, fileNames(t, dir))
+
+	require.NoError(t, pruneSnapshots(dir, 0))
+	assert.Equal(t, []string{"notes.txt", snapshotCopyDir}, fileNames(t, dir))
+}
+
+func fileNames(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	names := []string{}
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestAppendHistory(t *testing.T) {
+	require.NoError(t, appendHistory("", historyEntry{}))
+
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	require.NoError(t, appendHistory(path, historyEntry{Version: 1, SnapshotHeight: 100, Success: true}))
+	require.NoError(t, appendHistory(path, historyEntry{
+		Version: 2, SnapshotHeight: 200, Worst: SizeMismatch,
+		Results: []Status{{Key: "orders", MatchResult: SizeMismatch, OnlyInCore: []string{"o1"}}},
+	}))
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	require.Len(t, lines, 2)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &entry))
+	assert.Equal(t, float64(2), entry["version"])
+	assert.Equal(t, float64(200), entry["snapshot_height"])
+	assert.Equal(t, false, entry["success"])
+	assert.Equal(t, "size_mismatch", entry["worst"])
+	assert.Equal(t, []interface{}{"o1"}, entry["results"].([]interface{})[0].(map[string]interface{})["only_in_core"])
+}
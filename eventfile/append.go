@@ -0,0 +1,224 @@
+package eventfile
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Append opens the event file at path to add events after the last complete block already written to it, creating
+// the file if it does not exist. A partially written block at the end of the file, as left behind by a killed process
+// or a dropped connection, is removed. With rotation enabled the existing segments are kept and the events are
+// written to a new segment.
+func Append(path string, opts WriterOpts) (*Writer, error) {
+	w, err := newWriter(path, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.rotating() {
+		var last string
+		if w.sequence, last, err = w.nextSequence(); err != nil {
+			return nil, err
+		}
+		if len(last) > 0 {
+			if _, err := w.resume(last); err != nil {
+				return nil, err
+			}
+		}
+		if w.seg, err = createSegment(w.segmentPath(), opts.Compression); err != nil {
+			return nil, err
+		}
+		return w, nil
+	}
+
+	segPath := w.segmentPath()
+	if _, err := os.Stat(segPath); errors.Is(err, os.ErrNotExist) {
+		if w.seg, err = createSegment(segPath, opts.Compression); err != nil {
+			return nil, err
+		}
+		return w, nil
+	}
+
+	if opts.Compression != CompressionNone {
+		return nil, fmt.Errorf("unable to append to compressed file %s, enable rotation to continue in a new file", segPath)
+	}
+	end, err := w.resume(segPath)
+	if err != nil {
+		return nil, err
+	}
+	if w.seg, err = appendSegment(segPath, end); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// resume records the last complete block of an existing segment and removes anything written after it, so that a
+// block cut short is not replayed as well as written again in full. The offset at which writing can continue is
+// returned.
+func (w *Writer) resume(path string) (int64, error) {
+	rp, err := findResumePoint(path)
+	if err != nil {
+		return 0, err
+	}
+	if rp.version != CurrentVersion {
+		return 0, fmt.Errorf("unable to append to %s, it was written with file format version %d", path, rp.version)
+	}
+
+	switch {
+	case !rp.trailing:
+		// nothing follows the last complete block but the index may still hold blocks whose events were lost
+		if rp.staleIndex {
+			if err := truncateIndex(path, rp.end); err != nil {
+				return 0, err
+			}
+		}
+	case rp.compression == CompressionNone:
+		if err := truncate(path, rp.end); err != nil {
+			return 0, err
+		}
+	default:
+		// compressed files cannot be truncated in place
+		if err := rewrite(path, rp.compression, rp.end); err != nil {
+			return 0, err
+		}
+	}
+
+	w.last, w.hasLast = rp.last, rp.complete
+	return rp.end, nil
+}
+
+// resumePoint is where writing can continue in an existing event file.
+type resumePoint struct {
+	version     uint32
+	compression string
+	// end is the offset just past the last complete block
+	end      int64
+	last     IndexEntry
+	complete bool
+	// trailing is set if anything was written after end
+	trailing bool
+	// staleIndex is set if the index holds blocks starting at or after end
+	staleIndex bool
+}
+
+// findResumePoint scans an event file from its last indexed block to find the end of the last complete block. A
+// block is complete once the next one has started, or, for the final block, if the writer closed the file and
+// recorded the block in the index. The blocks before the last indexed one are known to be complete, so the resume
+// point is never before it, unless the events of the indexed blocks were lost, as when the end of a compressed file is
+// cut short.
+func findResumePoint(path string) (resumePoint, error) {
+	loaded, err := LoadIndex(path)
+	if err != nil {
+		return resumePoint{}, err
+	}
+
+	r, err := Open(path)
+	if err != nil {
+		return resumePoint{}, err
+	}
+	defer r.Close()
+
+	rp := resumePoint{
+		version:     r.Header().Version,
+		compression: r.Compression(),
+		end:         r.Offset(),
+	}
+	// only the indexed blocks that can be reached are known to be complete
+	index := loaded
+	for len(index) > 0 {
+		err := r.SeekOffset(index[len(index)-1].Offset)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return rp, err
+		}
+		index = index[:len(index)-1]
+	}
+	if n := len(index); n > 0 {
+		rp.end = index[n-1].Offset
+		if n > 1 {
+			rp.last, rp.complete = index[n-2], true
+		}
+	} else if err := r.SeekOffset(rp.end); err != nil {
+		return rp, err
+	}
+
+	var (
+		block   IndexEntry
+		inBlock bool
+		clean   bool
+		torn    bool
+		pos     int64
+	)
+	for {
+		pos = r.Offset()
+		e, err := r.Next()
+		if err == io.EOF {
+			clean = true
+			break
+		}
+		// a corrupt record is left for Repair, only a record cut short ends the file
+		var recErr *RecordError
+		if errors.As(err, &recErr) {
+			if recErr.Recoverable {
+				continue
+			}
+			torn = true
+			break
+		}
+		if err != nil {
+			return rp, err
+		}
+
+		if h, ok := BlockHeight(e); ok && (!inBlock || h != block.Height) {
+			if inBlock {
+				rp.last, rp.complete, rp.end = block, true, pos
+			}
+			block = IndexEntry{Height: h, Offset: pos}
+			inBlock = true
+		}
+		if tu := e.GetTimeUpdate(); tu != nil {
+			block.VegaTime = tu.Timestamp
+		}
+	}
+
+	// a block is only indexed once its events are flushed, so in an uncompressed file the last indexed block is also
+	// complete if the file ends part way through the record after it
+	indexed := inBlock && len(index) > 0 && index[len(index)-1].Height == block.Height
+	if indexed && (clean || (torn && rp.compression == CompressionNone)) {
+		rp.last, rp.complete, rp.end = block, true, pos
+	}
+	rp.trailing = !clean || pos > rp.end
+	rp.staleIndex = len(loaded) > 0 && loaded[len(loaded)-1].Offset >= rp.end
+	return rp, nil
+}
+
+// appendSegment opens an uncompressed segment to write events from offset, its current size, onwards.
+func appendSegment(path string, offset int64) (*segmentWriter, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open file %s: %w", path, err)
+	}
+
+	index, err := openIndex(path)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	s := &segmentWriter{
+		path:    path,
+		file:    f,
+		written: &countingWriter{w: f, n: offset},
+		index:   index,
+		offset:  offset,
+		created: time.Now(),
+	}
+	s.buf = bufio.NewWriter(s.written)
+	return s, nil
+}
@@ -0,0 +1,199 @@
+package eventfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func appendEvents(t *testing.T, path string, opts WriterOpts, events []*eventspb.BusEvent) {
+	t.Helper()
+	w, err := Append(path, opts)
+	require.NoError(t, err)
+	for _, e := range events {
+		require.NoError(t, w.Write(e))
+	}
+	require.NoError(t, w.Close())
+}
+
+func TestAppendCreatesMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.evt")
+	appendEvents(t, path, WriterOpts{}, blocks(1, 2))
+	assert.Equal(t, ids(blocks(1, 2)), readAll(t, path))
+}
+
+func TestAppendAfterClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.evt")
+	writeFile(t, path, WriterOpts{}, blocks(1, 2))
+
+	w, err := Append(path, WriterOpts{})
+	require.NoError(t, err)
+	last, ok := w.LastBlock()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(2), last)
+	require.NoError(t, w.Close())
+
+	appendEvents(t, path, WriterOpts{}, blocks(3, 4))
+	assert.Equal(t, ids(blocks(1, 4)), readAll(t, path))
+
+	index, err := LoadIndex(path)
+	require.NoError(t, err)
+	assert.Len(t, index, 4)
+}
+
+func TestAppendDropsIncompleteBlock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.evt")
+	writeFile(t, path, WriterOpts{}, blocks(1, 3))
+
+	// the writer was killed part way through block 4, after block 3 was indexed
+	f, err := Create(path+".partial", WriterOpts{})
+	require.NoError(t, err)
+	for _, e := range block(4, 2)[:2] {
+		require.NoError(t, f.Write(e))
+	}
+	require.NoError(t, f.Close())
+	partial, err := os.ReadFile(path + ".partial")
+	require.NoError(t, err)
+	out, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = out.Write(partial[headerSize:])
+	require.NoError(t, err)
+	require.NoError(t, out.Close())
+	appendTorn(t, path, block(4, 2)[2])
+
+	w, err := Append(path, WriterOpts{})
+	require.NoError(t, err)
+	last, ok := w.LastBlock()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(3), last)
+	for _, e := range blocks(4, 5) {
+		require.NoError(t, w.Write(e))
+	}
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, ids(blocks(1, 5)), readAll(t, path))
+}
+
+func TestAppendKeepsLastIndexedBlockWithTornTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.evt")
+	writeFile(t, path, WriterOpts{}, blocks(1, 3))
+	// the first record of block 4 was cut short, block 3 was already flushed and indexed
+	appendTorn(t, path, block(4, 2)[0])
+
+	appendEvents(t, path, WriterOpts{}, blocks(4, 4))
+	assert.Equal(t, ids(blocks(1, 4)), readAll(t, path))
+}
+
+func TestAppendKeepsLastIndexedBlockWithCorruptRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.evt")
+	writeFile(t, path, WriterOpts{}, blocks(1, 3))
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	index, err := LoadIndex(path)
+	require.NoError(t, err)
+	corrupt(t, path, index[2].Offset)
+
+	w, err := Append(path, WriterOpts{})
+	require.NoError(t, err)
+	last, ok := w.LastBlock()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(3), last)
+	require.NoError(t, w.Close())
+
+	// the damaged record is left for repair rather than truncating complete blocks
+	after, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, fi.Size(), after.Size())
+}
+
+func TestAppendTrimsRotatedCompressedSegment(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "events.evt")
+	opts := WriterOpts{Compression: CompressionGzip, MaxBlocks: 3}
+
+	// the writer was killed part way through block 3, after blocks 1 and 2 were indexed
+	writeFile(t, path, opts, append(blocks(1, 2), block(3, 2)[:3]...))
+	segment := filepath.Join(dir, "events-000000.evt.gz")
+	require.NoError(t, os.Truncate(segment+IndexSuffix, headerSize+2*indexEntrySize))
+
+	w, err := Append(path, opts)
+	require.NoError(t, err)
+	last, ok := w.LastBlock()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(2), last)
+	for _, e := range blocks(3, 4) {
+		require.NoError(t, w.Write(e))
+	}
+	require.NoError(t, w.Close())
+
+	// block 3 is only replayed once, from the new segment
+	assert.Equal(t, ids(blocks(1, 2)), readAll(t, segment))
+	assert.Equal(t, ids(blocks(1, 4)), readAll(t, dir))
+
+	// and only indexed once
+	heights := []uint64{}
+	for _, seg := range []string{segment, filepath.Join(dir, "events-000001.evt.gz")} {
+		index, err := LoadIndex(seg)
+		require.NoError(t, err)
+		for _, e := range index {
+			heights = append(heights, e.Height)
+		}
+	}
+	assert.Equal(t, []uint64{1, 2, 3, 4}, heights)
+}
+
+func TestAppendTrimsIndexOfLostCompressedBlocks(t *testing.T) {
+	for _, lost := range []string{"block", "tail"} {
+		t.Run(lost, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "events.evt")
+			opts := WriterOpts{Compression: CompressionGzip, MaxBlocks: 5}
+			writeFile(t, path, opts, blocks(1, 3))
+			segment := filepath.Join(dir, "events-000000.evt.gz")
+
+			// the events of block 3, or the end of the file, were lost after the blocks were indexed
+			if lost == "block" {
+				writeFile(t, path+".lost", WriterOpts{Compression: CompressionGzip}, blocks(1, 2))
+				b, err := os.ReadFile(path + ".lost.gz")
+				require.NoError(t, err)
+				require.NoError(t, os.WriteFile(segment, b, 0o644))
+			} else {
+				fi, err := os.Stat(segment)
+				require.NoError(t, err)
+				require.NoError(t, os.Truncate(segment, fi.Size()-80))
+			}
+
+			w, err := Append(path, opts)
+			require.NoError(t, err)
+			last, ok := w.LastBlock()
+			require.True(t, ok)
+			for _, e := range blocks(last+1, 4) {
+				require.NoError(t, w.Write(e))
+			}
+			require.NoError(t, w.Close())
+
+			assert.Equal(t, ids(blocks(1, 4)), readAll(t, dir))
+			heights := []uint64{}
+			for _, seg := range []string{segment, filepath.Join(dir, "events-000001.evt.gz")} {
+				index, err := LoadIndex(seg)
+				require.NoError(t, err)
+				for _, e := range index {
+					heights = append(heights, e.Height)
+				}
+			}
+			assert.Equal(t, []uint64{1, 2, 3, 4}, heights)
+
+			// seeking lands on the blocks written again
+			r, err := Open(dir)
+			require.NoError(t, err)
+			defer r.Close()
+			require.NoError(t, r.SeekHeight(3))
+			assert.Equal(t, ids(blocks(3, 4)), readIDs(t, r))
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package eventfile
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Supported compression of event files.
+const (
+	CompressionNone = ""
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// compressionExtension returns the file extension used for files written with the given compression.
+func compressionExtension(compression string) (string, error) {
+	switch compression {
+	case CompressionNone:
+		return "", nil
+	case CompressionGzip:
+		return ".gz", nil
+	case CompressionZstd:
+		return ".zst", nil
+	default:
+		return "", fmt.Errorf("unsupported compression %q, allowed values: gzip, zstd", compression)
+	}
+}
+
+func newCompressor(w io.Writer, compression string) (io.WriteCloser, error) {
+	switch compression {
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case CompressionZstd:
+		return zstd.NewWriter(w)
+	default:
+		return nil, fmt.Errorf("unsupported compression %q", compression)
+	}
+}
+
+// detectCompression returns the compression of the stream from its leading bytes.
+func detectCompression(r *bufio.Reader) string {
+	// short files cannot be compressed, they are handled as bare event files
+	head, _ := r.Peek(len(zstdMagic))
+
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		return CompressionGzip
+	case bytes.HasPrefix(head, zstdMagic):
+		return CompressionZstd
+	default:
+		return CompressionNone
+	}
+}
+
+// newDecompressor wraps r to decompress it. A nil closer is returned for uncompressed streams.
+func newDecompressor(r *bufio.Reader, compression string) (io.Reader, io.Closer, error) {
+	switch compression {
+	case CompressionGzip:
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open gzip stream: %w", err)
+		}
+		return gz, gz, nil
+	case CompressionZstd:
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open zstd stream: %w", err)
+		}
+		rc := zr.IOReadCloser()
+		return rc, rc, nil
+	default:
+		return r, nil, nil
+	}
+}
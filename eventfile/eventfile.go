@@ -0,0 +1,113 @@
+package eventfile
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strconv"
+	"strings"
+
+	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
+)
+
+// Version0 is the legacy format: a bare stream of 4 byte big-endian length prefixed BusEvent records with no header.
+const Version0 uint32 = 0
+
+// Version1 adds a file header in front of the length prefixed records and a block index sidecar file.
+const Version1 uint32 = 1
+
+// Version2 adds a CRC-32C checksum of the event bytes after each record.
+const Version2 uint32 = 2
+
+// CurrentVersion is the version written by Writer.
+const CurrentVersion = Version2
+
+// maxRecordSize bounds the size of a single event so that a corrupt size is not used to allocate memory.
+const maxRecordSize = 1 << 28
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// IndexSuffix is appended to the name of an event file to get the name of its block index.
+const IndexSuffix = ".idx"
+
+var fileMagic = [8]byte{'V', 'E', 'G', 'A', 'E', 'V', 'T', '\n'}
+
+// headerSize is the magic followed by a 4 byte version and 4 bytes of flags.
+const headerSize = int64(len(fileMagic) + 8)
+
+// Header describes the version of an event file.
+type Header struct {
+	Version uint32
+	Flags   uint32
+}
+
+func writeHeader(w io.Writer, h Header) error {
+	b := make([]byte, headerSize)
+	copy(b, fileMagic[:])
+	binary.BigEndian.PutUint32(b[8:], h.Version)
+	binary.BigEndian.PutUint32(b[12:], h.Flags)
+	_, err := w.Write(b)
+	return err
+}
+
+// peekHeader reads the file header from r without consuming it. Files written before the header was introduced start
+// directly with a record size, in which case a Version0 header is returned along with a data offset of 0.
+func peekHeader(r *bufio.Reader) (Header, int64, error) {
+	b, err := r.Peek(int(headerSize))
+	if err != nil && err != io.EOF {
+		return Header{}, 0, fmt.Errorf("failed to read file header: %w", err)
+	}
+
+	if int64(len(b)) < headerSize || string(b[:len(fileMagic)]) != string(fileMagic[:]) {
+		return Header{Version: Version0}, 0, nil
+	}
+
+	h := Header{
+		Version: binary.BigEndian.Uint32(b[8:]),
+		Flags:   binary.BigEndian.Uint32(b[12:]),
+	}
+	if h.Version > CurrentVersion {
+		return Header{}, 0, fmt.Errorf("unsupported event file version %d", h.Version)
+	}
+	return h, headerSize, nil
+}
+
+// RecordError is returned when a record in an event file cannot be read.
+type RecordError struct {
+	Path   string
+	Offset int64
+	// Recoverable is true if the reader has moved past the bad record and can carry on reading the next one.
+	Recoverable bool
+	Err         error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("bad record in %s at offset %d: %v", e.Path, e.Offset, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// BlockHeight returns the height of the block an event was emitted in. Begin and end block events carry the height
+// explicitly, every other event has an ID of the form `<height>-<sequence>`.
+func BlockHeight(e *eventspb.BusEvent) (uint64, bool) {
+	if bb := e.GetBeginBlock(); bb != nil {
+		return bb.Height, true
+	}
+	if eb := e.GetEndBlock(); eb != nil {
+		return eb.Height, true
+	}
+
+	id := e.GetId()
+	if i := strings.IndexByte(id, '-'); i > 0 {
+		id = id[:i]
+	}
+	height, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return height, true
+}
@@ -0,0 +1,245 @@
+package eventfile
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"code.vegaprotocol.io/vega/protos/vega"
+	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+// blockTime is the vega time of the block at height in the test files, one second apart.
+func blockTime(height uint64) int64 {
+	return int64(height) * 1e9
+}
+
+func timeOf(vegaTime int64) time.Time {
+	return time.Unix(0, vegaTime)
+}
+
+// block returns the events of a block: its begin, time update, orders and end events.
+func block(height uint64, orders int) []*eventspb.BusEvent {
+	id := func(seq int) string { return fmt.Sprintf("%d-%d", height, seq) }
+	events := []*eventspb.BusEvent{
+		{Id: id(0), Type: eventspb.BusEventType_BUS_EVENT_TYPE_BEGIN_BLOCK, Event: &eventspb.BusEvent_BeginBlock{
+			BeginBlock: &eventspb.BeginBlock{Height: height, Timestamp: blockTime(height)},
+		}},
+		{Id: id(1), Type: eventspb.BusEventType_BUS_EVENT_TYPE_TIME_UPDATE, Event: &eventspb.BusEvent_TimeUpdate{
+			TimeUpdate: &eventspb.TimeUpdate{Timestamp: blockTime(height)},
+		}},
+	}
+	for i := 0; i < orders; i++ {
+		events = append(events, &eventspb.BusEvent{
+			Id:   id(2 + i),
+			Type: eventspb.BusEventType_BUS_EVENT_TYPE_ORDER,
+			Event: &eventspb.BusEvent_Order{
+				Order: &vega.Order{Id: fmt.Sprintf("order-%d-%d", height, i), PartyId: "party", MarketId: "market"},
+			},
+		})
+	}
+	return append(events, &eventspb.BusEvent{
+		Id: id(2 + orders), Type: eventspb.BusEventType_BUS_EVENT_TYPE_END_BLOCK, Event: &eventspb.BusEvent_EndBlock{
+			EndBlock: &eventspb.EndBlock{Height: height},
+		},
+	})
+}
+
+// blocks returns the events of the blocks from one height to another, inclusive.
+func blocks(from, to uint64) []*eventspb.BusEvent {
+	events := []*eventspb.BusEvent{}
+	for h := from; h <= to; h++ {
+		events = append(events, block(h, 2)...)
+	}
+	return events
+}
+
+func writeFile(t *testing.T, path string, opts WriterOpts, events []*eventspb.BusEvent) {
+	t.Helper()
+	w, err := Create(path, opts)
+	require.NoError(t, err)
+	for _, e := range events {
+		require.NoError(t, w.Write(e))
+	}
+	require.NoError(t, w.Close())
+}
+
+// readIDs returns the IDs of the events left in the reader.
+func readIDs(t *testing.T, r *Reader) []string {
+	t.Helper()
+	ids := []string{}
+	for {
+		e, err := r.Next()
+		if err == io.EOF {
+			return ids
+		}
+		require.NoError(t, err)
+		ids = append(ids, e.Id)
+	}
+}
+
+func readAll(t *testing.T, path string) []string {
+	t.Helper()
+	r, err := Open(path)
+	require.NoError(t, err)
+	defer r.Close()
+	return readIDs(t, r)
+}
+
+func ids(events []*eventspb.BusEvent) []string {
+	ids := make([]string, 0, len(events))
+	for _, e := range events {
+		ids = append(ids, e.Id)
+	}
+	return ids
+}
+
+func TestBlockHeight(t *testing.T) {
+	height, ok := BlockHeight(&eventspb.BusEvent{Event: &eventspb.BusEvent_EndBlock{EndBlock: &eventspb.EndBlock{Height: 7}}})
+	assert.True(t, ok)
+	assert.Equal(t, uint64(7), height)
+
+	height, ok = BlockHeight(&eventspb.BusEvent{Id: "12-3"})
+	assert.True(t, ok)
+	assert.Equal(t, uint64(12), height)
+
+	_, ok = BlockHeight(&eventspb.BusEvent{Id: "not-a-height"})
+	assert.False(t, ok)
+}
+
+func TestWriteAndRead(t *testing.T) {
+	for _, compression := range []string{CompressionNone, CompressionGzip, CompressionZstd} {
+		t.Run(compression, func(t *testing.T) {
+			ext, err := compressionExtension(compression)
+			require.NoError(t, err)
+			path := filepath.Join(t.TempDir(), "events.evt"+ext)
+			events := blocks(1, 5)
+			writeFile(t, path, WriterOpts{Compression: compression}, events)
+
+			r, err := Open(path)
+			require.NoError(t, err)
+			defer r.Close()
+			assert.Equal(t, CurrentVersion, r.Header().Version)
+			assert.Equal(t, compression, r.Compression())
+			assert.Equal(t, ids(events), readIDs(t, r))
+		})
+	}
+}
+
+func TestReadVersion0(t *testing.T) {
+	// files written before the header was introduced are bare size prefixed records
+	path := filepath.Join(t.TempDir(), "legacy.evt")
+	events := blocks(1, 2)
+	b := []byte{}
+	for _, e := range events {
+		msg, err := proto.Marshal(e)
+		require.NoError(t, err)
+		b = binary.BigEndian.AppendUint32(b, uint32(len(msg)))
+		b = append(b, msg...)
+	}
+	require.NoError(t, os.WriteFile(path, b, 0o644))
+
+	r, err := Open(path)
+	require.NoError(t, err)
+	defer r.Close()
+	assert.Equal(t, Version0, r.Header().Version)
+	assert.Equal(t, ids(events), readIDs(t, r))
+}
+
+func TestIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.evt")
+	writeFile(t, path, WriterOpts{}, blocks(1, 3))
+
+	index, err := LoadIndex(path)
+	require.NoError(t, err)
+	require.Len(t, index, 3)
+	for i, e := range index {
+		assert.Equal(t, uint64(i+1), e.Height)
+		assert.Equal(t, blockTime(e.Height), e.VegaTime)
+	}
+	assert.Equal(t, headerSize, index[0].Offset)
+
+	// every entry points at the first event of its block
+	r, err := Open(path)
+	require.NoError(t, err)
+	defer r.Close()
+	for _, e := range index {
+		require.NoError(t, r.SeekOffset(e.Offset))
+		event, err := r.Next()
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("%d-0", e.Height), event.Id)
+	}
+
+	entry, ok := index.ForHeight(2)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(2), entry.Height)
+	_, ok = index.ForHeight(0)
+	assert.False(t, ok)
+
+	entry, ok = index.ForTime(timeOf(blockTime(2) + 1))
+	assert.True(t, ok)
+	assert.Equal(t, uint64(2), entry.Height)
+}
+
+func TestMissingIndexIsEmpty(t *testing.T) {
+	index, err := LoadIndex(filepath.Join(t.TempDir(), "missing.evt"))
+	require.NoError(t, err)
+	assert.Empty(t, index)
+}
+
+func TestSeek(t *testing.T) {
+	for _, compression := range []string{CompressionNone, CompressionZstd} {
+		t.Run(compression, func(t *testing.T) {
+			ext, err := compressionExtension(compression)
+			require.NoError(t, err)
+			path := filepath.Join(t.TempDir(), "events.evt"+ext)
+			writeFile(t, path, WriterOpts{Compression: compression}, blocks(1, 5))
+
+			r, err := Open(path)
+			require.NoError(t, err)
+			defer r.Close()
+
+			require.NoError(t, r.SeekHeight(4))
+			assert.Equal(t, ids(blocks(4, 5)), readIDs(t, r))
+
+			// seeking backwards rewinds
+			require.NoError(t, r.SeekTime(timeOf(blockTime(2))))
+			assert.Equal(t, ids(blocks(2, 5)), readIDs(t, r))
+
+			assert.Equal(t, io.EOF, r.SeekHeight(6))
+		})
+	}
+}
+
+func TestRotatedSegmentsAreReadInOrder(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "events.evt")
+	events := blocks(1, 7)
+	writeFile(t, path, WriterOpts{Compression: CompressionGzip, MaxBlocks: 2}, events)
+
+	segments, err := listSegments(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "events-000000.evt.gz"),
+		filepath.Join(dir, "events-000001.evt.gz"),
+		filepath.Join(dir, "events-000002.evt.gz"),
+		filepath.Join(dir, "events-000003.evt.gz"),
+	}, segments)
+	assert.Equal(t, ids(events), readAll(t, dir))
+
+	// the indexes of the segments let the reader jump straight to the segment holding the block
+	r, err := Open(dir)
+	require.NoError(t, err)
+	defer r.Close()
+	require.NoError(t, r.SeekHeight(6))
+	assert.Equal(t, filepath.Join(dir, "events-000002.evt.gz"), r.Segment())
+	assert.Equal(t, ids(blocks(6, 7)), readIDs(t, r))
+}
@@ -0,0 +1,112 @@
+package eventfile
+
+import (
+	"fmt"
+	"strings"
+
+	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Filter selects events by type, the party or market they reference and the block they were emitted in.
+// Zero values match everything.
+type Filter struct {
+	Types      []eventspb.BusEventType
+	Party      string
+	Market     string
+	FromHeight uint64
+	ToHeight   uint64
+}
+
+// ParseTypes converts event type names, with or without the BUS_EVENT_TYPE_ prefix, to event types.
+func ParseTypes(names []string) ([]eventspb.BusEventType, error) {
+	types := make([]eventspb.BusEventType, 0, len(names))
+	for _, name := range names {
+		name = strings.ToUpper(name)
+		if !strings.HasPrefix(name, "BUS_EVENT_TYPE_") {
+			name = "BUS_EVENT_TYPE_" + name
+		}
+		t, ok := eventspb.BusEventType_value[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown event type %s", name)
+		}
+		types = append(types, eventspb.BusEventType(t))
+	}
+	return types, nil
+}
+
+// Match returns true if the event passes every part of the filter.
+func (f Filter) Match(e *eventspb.BusEvent) bool {
+	if len(f.Types) > 0 && !f.matchType(e.Type) {
+		return false
+	}
+
+	if f.FromHeight > 0 || f.ToHeight > 0 {
+		height, ok := BlockHeight(e)
+		if !ok || height < f.FromHeight || (f.ToHeight > 0 && height > f.ToHeight) {
+			return false
+		}
+	}
+
+	// IDs are unique hashes so any field holding the value is taken to be a reference to it
+	if len(f.Party) > 0 && !containsString(e.ProtoReflect(), f.Party) {
+		return false
+	}
+	if len(f.Market) > 0 && !containsString(e.ProtoReflect(), f.Market) {
+		return false
+	}
+	return true
+}
+
+// PastEnd returns true once events are from blocks after the end of the filter's block range.
+func (f Filter) PastEnd(e *eventspb.BusEvent) bool {
+	if f.ToHeight == 0 {
+		return false
+	}
+	height, ok := BlockHeight(e)
+	return ok && height > f.ToHeight
+}
+
+func (f Filter) matchType(t eventspb.BusEventType) bool {
+	for _, ft := range f.Types {
+		if ft == t {
+			return true
+		}
+	}
+	return false
+}
+
+// containsString returns true if any string field, at any depth of the message, equals value.
+func containsString(m protoreflect.Message, value string) bool {
+	found := false
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsList():
+			l := v.List()
+			for i := 0; i < l.Len() && !found; i++ {
+				found = valueContains(fd, l.Get(i), value)
+			}
+		case fd.IsMap():
+			v.Map().Range(func(k protoreflect.MapKey, mv protoreflect.Value) bool {
+				found = k.String() == value || valueContains(fd.MapValue(), mv, value)
+				return !found
+			})
+		default:
+			found = valueContains(fd, v, value)
+		}
+		return !found
+	})
+	return found
+}
+
+func valueContains(fd protoreflect.FieldDescriptor, v protoreflect.Value, value string) bool {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return v.String() == value
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return containsString(v.Message(), value)
+	default:
+		return false
+	}
+}
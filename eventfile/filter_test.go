@@ -0,0 +1,47 @@
+package eventfile
+
+import (
+	"testing"
+
+	"code.vegaprotocol.io/vega/protos/vega"
+	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTypes(t *testing.T) {
+	types, err := ParseTypes([]string{"order", "BUS_EVENT_TYPE_TRADE"})
+	require.NoError(t, err)
+	assert.Equal(t, []eventspb.BusEventType{eventspb.BusEventType_BUS_EVENT_TYPE_ORDER, eventspb.BusEventType_BUS_EVENT_TYPE_TRADE}, types)
+
+	_, err = ParseTypes([]string{"orders"})
+	assert.EqualError(t, err, "unknown event type BUS_EVENT_TYPE_ORDERS")
+}
+
+func TestFilterMatch(t *testing.T) {
+	order := &eventspb.BusEvent{Id: "5-2", Type: eventspb.BusEventType_BUS_EVENT_TYPE_ORDER, Event: &eventspb.BusEvent_Order{
+		Order: &vega.Order{Id: "o1", PartyId: "p1", MarketId: "m1"},
+	}}
+	timeUpdate := block(5, 0)[1]
+
+	assert.True(t, Filter{}.Match(order))
+	assert.True(t, Filter{Types: []eventspb.BusEventType{eventspb.BusEventType_BUS_EVENT_TYPE_ORDER}}.Match(order))
+	assert.False(t, Filter{Types: []eventspb.BusEventType{eventspb.BusEventType_BUS_EVENT_TYPE_ORDER}}.Match(timeUpdate))
+
+	// any field of the event, at any depth, references the party or market
+	assert.True(t, Filter{Party: "p1", Market: "m1"}.Match(order))
+	assert.False(t, Filter{Party: "p2"}.Match(order))
+	assert.False(t, Filter{Market: "m1"}.Match(timeUpdate))
+
+	assert.True(t, Filter{FromHeight: 5, ToHeight: 5}.Match(order))
+	assert.False(t, Filter{FromHeight: 6}.Match(order))
+	assert.False(t, Filter{ToHeight: 4}.Match(order))
+}
+
+func TestFilterPastEnd(t *testing.T) {
+	e := &eventspb.BusEvent{Id: "5-0"}
+	assert.False(t, Filter{}.PastEnd(e))
+	assert.False(t, Filter{ToHeight: 5}.PastEnd(e))
+	assert.True(t, Filter{ToHeight: 4}.PastEnd(e))
+}
@@ -0,0 +1,145 @@
+package eventfile
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+var indexMagic = [8]byte{'V', 'E', 'G', 'A', 'I', 'D', 'X', '\n'}
+
+const indexVersion uint32 = 1
+
+// indexEntrySize is the height, vega time and offset of a block, 8 bytes each.
+const indexEntrySize = 24
+
+// IndexEntry records where the first event of a block starts in an event file.
+type IndexEntry struct {
+	Height uint64
+	// VegaTime is the time of the block in unix nanoseconds, or 0 if the block had no time update event.
+	VegaTime int64
+	Offset   int64
+}
+
+// Index is the list of blocks in an event file ordered by height.
+type Index []IndexEntry
+
+// LoadIndex reads the block index stored alongside the event file at path. A missing index is not an error,
+// an empty index is returned instead.
+func LoadIndex(path string) (Index, error) {
+	f, err := os.Open(path + IndexSuffix)
+	if errors.Is(err, os.ErrNotExist) {
+		return Index{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to open index for %s: %w", path, err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("failed to read index header: %w", err)
+	}
+	if string(header[:len(indexMagic)]) != string(indexMagic[:]) {
+		return nil, fmt.Errorf("%s%s is not an event index", path, IndexSuffix)
+	}
+	if v := binary.BigEndian.Uint32(header[8:]); v > indexVersion {
+		return nil, fmt.Errorf("unsupported event index version %d", v)
+	}
+
+	index := Index{}
+	b := make([]byte, indexEntrySize)
+	for {
+		// a partially written trailing entry is ignored
+		if _, err := io.ReadFull(r, b); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return index, nil
+			}
+			return nil, fmt.Errorf("failed to read index entry: %w", err)
+		}
+		index = append(index, IndexEntry{
+			Height:   binary.BigEndian.Uint64(b),
+			VegaTime: int64(binary.BigEndian.Uint64(b[8:])),
+			Offset:   int64(binary.BigEndian.Uint64(b[16:])),
+		})
+	}
+}
+
+// ForHeight returns the last indexed block at or below height.
+func (idx Index) ForHeight(height uint64) (IndexEntry, bool) {
+	i := sort.Search(len(idx), func(i int) bool { return idx[i].Height > height })
+	if i == 0 {
+		return IndexEntry{}, false
+	}
+	return idx[i-1], true
+}
+
+// ForTime returns the last indexed block with a vega time at or before t.
+func (idx Index) ForTime(t time.Time) (IndexEntry, bool) {
+	var (
+		entry IndexEntry
+		found bool
+	)
+	for _, e := range idx {
+		if e.VegaTime == 0 {
+			continue
+		}
+		if e.VegaTime > t.UnixNano() {
+			break
+		}
+		entry, found = e, true
+	}
+	return entry, found
+}
+
+type indexWriter struct {
+	file *os.File
+	buf  []byte
+}
+
+func createIndex(path string) (*indexWriter, error) {
+	f, err := os.Create(path + IndexSuffix)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create index for %s: %w", path, err)
+	}
+
+	header := make([]byte, headerSize)
+	copy(header, indexMagic[:])
+	binary.BigEndian.PutUint32(header[8:], indexVersion)
+	if _, err := f.Write(header); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to write index header: %w", err)
+	}
+
+	return &indexWriter{file: f, buf: make([]byte, indexEntrySize)}, nil
+}
+
+// openIndex opens the index of path to add entries after the existing ones, creating it if it does not exist.
+func openIndex(path string) (*indexWriter, error) {
+	f, err := os.OpenFile(path+IndexSuffix, os.O_WRONLY|os.O_APPEND, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return createIndex(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to open index for %s: %w", path, err)
+	}
+	return &indexWriter{file: f, buf: make([]byte, indexEntrySize)}, nil
+}
+
+func (w *indexWriter) write(e IndexEntry) error {
+	binary.BigEndian.PutUint64(w.buf, e.Height)
+	binary.BigEndian.PutUint64(w.buf[8:], uint64(e.VegaTime))
+	binary.BigEndian.PutUint64(w.buf[16:], uint64(e.Offset))
+	_, err := w.file.Write(w.buf)
+	return err
+}
+
+func (w *indexWriter) close() error {
+	return w.file.Close()
+}
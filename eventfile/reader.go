@@ -0,0 +1,271 @@
+package eventfile
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"time"
+
+	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// position locates a record within the segments of a Reader.
+type position struct {
+	segment int
+	offset  int64
+}
+
+// Reader reads bus events from an event file of any version, or from a directory of rotated segments in order.
+// Compressed files are decompressed transparently.
+type Reader struct {
+	segments []string
+	current  int
+	seg      *segment
+
+	// pending holds an event that was read ahead while seeking and must be returned by the next call to Next.
+	pending    *eventspb.BusEvent
+	pendingPos position
+
+	sizeBytes []byte
+	crcBytes  []byte
+	msgBytes  []byte
+}
+
+// Open opens the event file, or directory of event files, at path for reading.
+func Open(path string) (*Reader, error) {
+	segments, err := listSegments(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Reader{
+		segments:  segments,
+		sizeBytes: make([]byte, 4),
+		crcBytes:  make([]byte, 4),
+		msgBytes:  make([]byte, 0, 10000),
+	}
+	if err := r.openSegment(0); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Reader) openSegment(i int) error {
+	if r.seg != nil {
+		r.seg.close()
+		r.seg = nil
+	}
+
+	seg, err := openSegment(r.segments[i])
+	if err != nil {
+		return err
+	}
+	r.seg = seg
+	r.current = i
+	return nil
+}
+
+// Header returns the header of the event file currently being read.
+func (r *Reader) Header() Header {
+	return r.seg.header
+}
+
+// Compression returns the compression of the event file currently being read.
+func (r *Reader) Compression() string {
+	return r.seg.compression
+}
+
+// Segment returns the path of the event file currently being read.
+func (r *Reader) Segment() string {
+	return r.seg.path
+}
+
+// Offset returns the position in the current event file of the next event returned by Next.
+func (r *Reader) Offset() int64 {
+	return r.position().offset
+}
+
+func (r *Reader) position() position {
+	if r.pending != nil {
+		return r.pendingPos
+	}
+	return position{segment: r.current, offset: r.seg.offset}
+}
+
+// SeekOffset moves the reader to the record starting at offset in the current event file.
+func (r *Reader) SeekOffset(offset int64) error {
+	return r.seek(position{segment: r.current, offset: offset})
+}
+
+func (r *Reader) seek(pos position) error {
+	r.pending = nil
+	if pos.segment != r.current {
+		if err := r.openSegment(pos.segment); err != nil {
+			return err
+		}
+	}
+	return r.seg.seek(pos.offset)
+}
+
+// Next returns the next event, or io.EOF once all events have been read.
+func (r *Reader) Next() (*eventspb.BusEvent, error) {
+	if r.pending != nil {
+		e := r.pending
+		r.pending = nil
+		return e, nil
+	}
+
+	for {
+		e, err := r.readRecord()
+		if err != io.EOF || r.current+1 >= len(r.segments) {
+			return e, err
+		}
+		if err := r.openSegment(r.current + 1); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (r *Reader) readRecord() (*eventspb.BusEvent, error) {
+	seg := r.seg
+	start := seg.offset
+	if _, err := io.ReadFull(seg.buf, r.sizeBytes); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, &RecordError{Path: seg.path, Offset: start, Err: fmt.Errorf("error whilst reading message size: %w", err)}
+	}
+
+	msgSize := binary.BigEndian.Uint32(r.sizeBytes)
+	if msgSize > maxRecordSize {
+		return nil, &RecordError{Path: seg.path, Offset: start, Err: fmt.Errorf("message size %d is too large", msgSize)}
+	}
+	if cap(r.msgBytes) < int(msgSize) {
+		r.msgBytes = make([]byte, msgSize)
+	}
+	r.msgBytes = r.msgBytes[:msgSize]
+	if _, err := io.ReadFull(seg.buf, r.msgBytes); err != nil {
+		return nil, &RecordError{Path: seg.path, Offset: start, Err: fmt.Errorf("error whilst reading message bytes: %w", err)}
+	}
+	recordSize := int64(len(r.sizeBytes)) + int64(msgSize)
+
+	checksummed := seg.header.Version >= Version2
+	if checksummed {
+		if _, err := io.ReadFull(seg.buf, r.crcBytes); err != nil {
+			return nil, &RecordError{Path: seg.path, Offset: start, Err: fmt.Errorf("error whilst reading message checksum: %w", err)}
+		}
+		recordSize += int64(len(r.crcBytes))
+	}
+
+	// the whole record has been read, so from here on a bad record can be skipped
+	seg.offset += recordSize
+
+	if checksummed && binary.BigEndian.Uint32(r.crcBytes) != crc32.Checksum(r.msgBytes, crcTable) {
+		return nil, &RecordError{Path: seg.path, Offset: start, Recoverable: true, Err: errors.New("checksum mismatch")}
+	}
+
+	event := &eventspb.BusEvent{}
+	if err := proto.Unmarshal(r.msgBytes, event); err != nil {
+		return nil, &RecordError{Path: seg.path, Offset: start, Recoverable: true, Err: fmt.Errorf("failed to unmarshal bus event: %w", err)}
+	}
+
+	return event, nil
+}
+
+// unread pushes back an event so that it is returned by the next call to Next.
+func (r *Reader) unread(e *eventspb.BusEvent, pos position) {
+	r.pending = e
+	r.pendingPos = pos
+}
+
+// indexedStart uses the block indexes of the segments to find the closest known block before the one wanted.
+func (r *Reader) indexedStart(lookup func(Index) (IndexEntry, bool)) (position, error) {
+	start := position{}
+	for i, path := range r.segments {
+		index, err := LoadIndex(path)
+		if err != nil {
+			return start, err
+		}
+		// segments are in order, so stop at the first one starting after the block or without an index
+		entry, ok := lookup(index)
+		if !ok {
+			break
+		}
+		start = position{segment: i, offset: entry.Offset}
+	}
+	return start, nil
+}
+
+// SeekHeight moves the reader to the first event of the first block at or above height. The block index is used to
+// jump close to the block, the remainder is found by scanning forward.
+func (r *Reader) SeekHeight(height uint64) error {
+	start, err := r.indexedStart(func(idx Index) (IndexEntry, bool) { return idx.ForHeight(height) })
+	if err != nil {
+		return err
+	}
+	if err := r.seek(start); err != nil {
+		return err
+	}
+
+	return r.skipUntil(func(e *eventspb.BusEvent) bool {
+		h, ok := BlockHeight(e)
+		return ok && h >= height
+	})
+}
+
+// SeekTime moves the reader to the first event of the first block with a vega time at or after t.
+func (r *Reader) SeekTime(t time.Time) error {
+	start, err := r.indexedStart(func(idx Index) (IndexEntry, bool) { return idx.ForTime(t) })
+	if err != nil {
+		return err
+	}
+	if err := r.seek(start); err != nil {
+		return err
+	}
+
+	// the time update is not the first event of a block, so remember where the block started
+	var (
+		blockStart  = r.position()
+		blockHeight uint64
+		inBlock     bool
+	)
+	for {
+		pos := r.position()
+		e, err := r.Next()
+		if err != nil {
+			return err
+		}
+
+		if h, ok := BlockHeight(e); ok && (!inBlock || h != blockHeight) {
+			blockStart, blockHeight, inBlock = pos, h, true
+		}
+
+		if tu := e.GetTimeUpdate(); tu != nil && tu.Timestamp >= t.UnixNano() {
+			return r.seek(blockStart)
+		}
+	}
+}
+
+// skipUntil discards events until match returns true, leaving the matching event as the next one to be read.
+func (r *Reader) skipUntil(match func(e *eventspb.BusEvent) bool) error {
+	for {
+		pos := r.position()
+		e, err := r.Next()
+		if err != nil {
+			return err
+		}
+		if match(e) {
+			r.unread(e, pos)
+			return nil
+		}
+	}
+}
+
+// Close closes the event file currently being read.
+func (r *Reader) Close() error {
+	return r.seg.close()
+}
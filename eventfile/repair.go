@@ -0,0 +1,167 @@
+package eventfile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// RepairResult describes the changes made to an event file by Repair.
+type RepairResult struct {
+	// Dropped is the number of complete records that were removed because they were corrupt.
+	Dropped int
+	// TruncatedAt is the offset at which a partially written trailing record was cut off, or -1.
+	TruncatedAt int64
+	// Rewritten is true if the file was rewritten rather than truncated in place.
+	Rewritten bool
+}
+
+// Changed returns true if Repair modified the file.
+func (r RepairResult) Changed() bool {
+	return r.Dropped > 0 || r.TruncatedAt >= 0
+}
+
+// Repair makes the event file at path readable to the end. A partially written trailing record, as left behind by a
+// killed process, is truncated in place. Corrupt records elsewhere, or any damage to a compressed file, require the
+// readable events to be rewritten to a new file which then replaces the original. The block index is rebuilt to match.
+func Repair(path string) (RepairResult, error) {
+	res := RepairResult{TruncatedAt: -1}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return res, fmt.Errorf("unable to open file %s: %w", path, err)
+	}
+	if fi.IsDir() {
+		return res, fmt.Errorf("%s is a directory, repair one event file at a time", path)
+	}
+
+	in, err := Open(path)
+	if err != nil {
+		return res, err
+	}
+	compression := in.Compression()
+	if res.Dropped, res.TruncatedAt, err = scan(in); err != nil {
+		in.Close()
+		return res, err
+	}
+	in.Close()
+
+	switch {
+	case !res.Changed():
+		return res, nil
+	case res.Dropped == 0 && compression == CompressionNone:
+		return res, truncate(path, res.TruncatedAt)
+	default:
+		res.Rewritten = true
+		return res, rewrite(path, compression, -1)
+	}
+}
+
+// scan reads all records, counting the corrupt ones and returning the offset of the record that could not be read
+// in full, if any.
+func scan(in *Reader) (int, int64, error) {
+	dropped := 0
+	for {
+		_, err := in.Next()
+		if err == io.EOF {
+			return dropped, -1, nil
+		}
+
+		var recErr *RecordError
+		if errors.As(err, &recErr) {
+			if !recErr.Recoverable {
+				return dropped, recErr.Offset, nil
+			}
+			dropped++
+			continue
+		}
+		if err != nil {
+			return dropped, -1, err
+		}
+	}
+}
+
+// truncate cuts an uncompressed event file at offset and drops the index entries of the blocks removed with it.
+func truncate(path string, offset int64) error {
+	if err := os.Truncate(path, offset); err != nil {
+		return fmt.Errorf("failed to truncate %s: %w", path, err)
+	}
+	return truncateIndex(path, offset)
+}
+
+// truncateIndex drops the index entries of the blocks starting at or after offset.
+func truncateIndex(path string, offset int64) error {
+	if _, err := os.Stat(path + IndexSuffix); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	index, err := LoadIndex(path)
+	if err != nil {
+		return err
+	}
+
+	w, err := createIndex(path)
+	if err != nil {
+		return err
+	}
+	for _, e := range index {
+		if e.Offset >= offset {
+			break
+		}
+		if err := w.write(e); err != nil {
+			w.close()
+			return fmt.Errorf("failed to write index entry: %w", err)
+		}
+	}
+	return w.close()
+}
+
+// rewrite copies the readable events of path before offset end, or all of them if end is negative, to a new file and
+// moves it, with its index, over the original.
+func rewrite(path, compression string, end int64) error {
+	in, err := Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := Create(path+".repair", WriterOpts{Compression: compression})
+	if err != nil {
+		return err
+	}
+	tmp := out.Path()
+
+	for end < 0 || in.Offset() < end {
+		e, err := in.Next()
+		if err == io.EOF {
+			break
+		}
+		var recErr *RecordError
+		if errors.As(err, &recErr) {
+			if recErr.Recoverable {
+				continue
+			}
+			break
+		}
+		if err == nil {
+			err = out.Write(e)
+		}
+		if err != nil {
+			out.Close()
+			os.Remove(tmp)
+			os.Remove(tmp + IndexSuffix)
+			return err
+		}
+	}
+
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	if err := os.Rename(tmp+IndexSuffix, path+IndexSuffix); err != nil {
+		return fmt.Errorf("failed to replace index of %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package eventfile
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+// appendTorn appends the first half of a record holding e to the file at path, as left by a killed writer.
+func appendTorn(t *testing.T, path string, e *eventspb.BusEvent) {
+	t.Helper()
+	msg, err := proto.Marshal(e)
+	require.NoError(t, err)
+	b := binary.BigEndian.AppendUint32(nil, uint32(len(msg)))
+	b = append(b, msg[:len(msg)/2]...)
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.Write(b)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+}
+
+// corrupt flips a byte of the event of the record at offset, breaking its checksum.
+func corrupt(t *testing.T, path string, offset int64) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	require.NoError(t, err)
+	defer f.Close()
+
+	b := make([]byte, 1)
+	_, err = f.ReadAt(b, offset+4)
+	require.NoError(t, err)
+	b[0] ^= 0xff
+	_, err = f.WriteAt(b, offset+4)
+	require.NoError(t, err)
+}
+
+func TestRepairUntouchedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.evt")
+	writeFile(t, path, WriterOpts{}, blocks(1, 2))
+
+	res, err := Repair(path)
+	require.NoError(t, err)
+	assert.False(t, res.Changed())
+}
+
+func TestRepairTruncatesTornTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.evt")
+	events := blocks(1, 3)
+	writeFile(t, path, WriterOpts{}, events)
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	appendTorn(t, path, block(4, 2)[0])
+
+	res, err := Repair(path)
+	require.NoError(t, err)
+	assert.Equal(t, RepairResult{TruncatedAt: fi.Size()}, res)
+	assert.Equal(t, ids(events), readAll(t, path))
+}
+
+func TestRepairDropsCorruptRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.evt")
+	events := blocks(1, 3)
+	writeFile(t, path, WriterOpts{}, events)
+	index, err := LoadIndex(path)
+	require.NoError(t, err)
+	corrupt(t, path, index[1].Offset)
+
+	res, err := Repair(path)
+	require.NoError(t, err)
+	assert.Equal(t, RepairResult{Dropped: 1, TruncatedAt: -1, Rewritten: true}, res)
+
+	expected := append(ids(blocks(1, 1)), ids(block(2, 2)[1:])...)
+	expected = append(expected, ids(blocks(3, 3))...)
+	assert.Equal(t, expected, readAll(t, path))
+
+	// the index is rebuilt to match the rewritten file
+	index, err = LoadIndex(path)
+	require.NoError(t, err)
+	require.Len(t, index, 3)
+	r, err := Open(path)
+	require.NoError(t, err)
+	defer r.Close()
+	require.NoError(t, r.SeekHeight(3))
+	assert.Equal(t, ids(blocks(3, 3)), readIDs(t, r))
+}
@@ -0,0 +1,148 @@
+package eventfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// segmentExtensions are the file extensions recognised as event files when reading a directory.
+var segmentExtensions = []string{".evt", ".evt.gz", ".evt.zst"}
+
+// listSegments returns the event files making up path. A directory is read as a sequence of rotated segments
+// ordered by name, anything else is a single event file.
+func listSegments(path string) ([]string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open file %s: %w", path, err)
+	}
+	if !fi.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read directory %s: %w", path, err)
+	}
+
+	segments := []string{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		for _, ext := range segmentExtensions {
+			if strings.HasSuffix(e.Name(), ext) {
+				segments = append(segments, filepath.Join(path, e.Name()))
+				break
+			}
+		}
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("no event files found in %s", path)
+	}
+
+	sort.Strings(segments)
+	return segments, nil
+}
+
+// segment is a single, possibly compressed, event file being read.
+type segment struct {
+	path        string
+	file        *os.File
+	compression string
+	decompress  io.Closer
+	buf         *bufio.Reader
+	header      Header
+	dataStart   int64
+	offset      int64
+}
+
+func openSegment(path string) (*segment, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open file %s: %w", path, err)
+	}
+
+	s := &segment{path: path, file: f}
+	if err := s.rewind(); err != nil {
+		s.close()
+		return nil, err
+	}
+
+	if s.header, s.dataStart, err = peekHeader(s.buf); err != nil {
+		s.close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := s.seek(s.dataStart); err != nil {
+		s.close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// compressed returns true if the segment can only be read sequentially.
+func (s *segment) compressed() bool {
+	return s.decompress != nil
+}
+
+// rewind moves back to the start of the (decompressed) file.
+func (s *segment) rewind() error {
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind %s: %w", s.path, err)
+	}
+	if s.decompress != nil {
+		s.decompress.Close()
+	}
+
+	br := bufio.NewReader(s.file)
+	s.compression = detectCompression(br)
+	r, closer, err := newDecompressor(br, s.compression)
+	if err != nil {
+		return fmt.Errorf("%s: %w", s.path, err)
+	}
+	s.decompress = closer
+	s.buf = bufio.NewReader(r)
+	s.offset = 0
+	return nil
+}
+
+// seek moves to offset in the decompressed file. Compressed files are read forward to the offset,
+// rewinding first if it is behind the current position.
+func (s *segment) seek(offset int64) error {
+	if offset < s.dataStart {
+		offset = s.dataStart
+	}
+
+	if !s.compressed() {
+		if _, err := s.file.Seek(offset, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to seek to offset %d: %w", offset, err)
+		}
+		s.buf.Reset(s.file)
+		s.offset = offset
+		return nil
+	}
+
+	if offset < s.offset {
+		if err := s.rewind(); err != nil {
+			return err
+		}
+	}
+	n, err := io.CopyN(io.Discard, s.buf, offset-s.offset)
+	s.offset += n
+	if err != nil {
+		return fmt.Errorf("failed to seek to offset %d: %w", offset, err)
+	}
+	return nil
+}
+
+func (s *segment) close() error {
+	if s.decompress != nil {
+		s.decompress.Close()
+	}
+	return s.file.Close()
+}
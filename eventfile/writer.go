@@ -0,0 +1,310 @@
+package eventfile
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// WriterOpts configures the compression and rotation of the files written by a Writer.
+type WriterOpts struct {
+	Compression string
+	// A new segment is started at the next block boundary once any of the limits is reached, a zero value disables a limit.
+	MaxBytes  int64
+	MaxBlocks uint64
+	MaxAge    time.Duration
+}
+
+func (o WriterOpts) rotating() bool {
+	return o.MaxBytes > 0 || o.MaxBlocks > 0 || o.MaxAge > 0
+}
+
+// Writer persists bus events to an event file and maintains its block index. When rotation is enabled the events
+// are written to a sequence of segments named `<name>-<sequence>.evt` in the directory of the path given.
+type Writer struct {
+	opts     WriterOpts
+	path     string
+	ext      string
+	sequence int
+	seg      *segmentWriter
+
+	block   IndexEntry
+	inBlock bool
+	// last is the last block completely written, including those found in the file by Append
+	last    IndexEntry
+	hasLast bool
+
+	sizeBytes []byte
+	crcBytes  []byte
+}
+
+// Create creates, or truncates, the event file at path and its block index.
+func Create(path string, opts WriterOpts) (*Writer, error) {
+	w, err := newWriter(path, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.rotating() {
+		if w.sequence, _, err = w.nextSequence(); err != nil {
+			return nil, err
+		}
+	}
+
+	if w.seg, err = createSegment(w.segmentPath(), opts.Compression); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func newWriter(path string, opts WriterOpts) (*Writer, error) {
+	ext, err := compressionExtension(opts.Compression)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Writer{
+		opts:      opts,
+		path:      path,
+		ext:       ext,
+		sizeBytes: make([]byte, 4),
+		crcBytes:  make([]byte, 4),
+	}, nil
+}
+
+// Path returns the path of the event file currently being written.
+func (w *Writer) Path() string {
+	return w.seg.path
+}
+
+func (w *Writer) segmentPath() string {
+	if !w.opts.rotating() {
+		if strings.HasSuffix(w.path, w.ext) {
+			return w.path
+		}
+		return w.path + w.ext
+	}
+	dir, name := filepath.Split(w.path)
+	stem := strings.TrimSuffix(name, filepath.Ext(name))
+	return filepath.Join(dir, fmt.Sprintf("%s-%06d.evt%s", stem, w.sequence, w.ext))
+}
+
+// nextSequence returns the sequence number following any segments already in the directory so that they are
+// never overwritten, along with the path of the last of those segments.
+func (w *Writer) nextSequence() (int, string, error) {
+	dir, name := filepath.Split(w.path)
+	if len(dir) == 0 {
+		dir = "."
+	}
+	prefix := strings.TrimSuffix(name, filepath.Ext(name)) + "-"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, "", fmt.Errorf("unable to read directory %s: %w", dir, err)
+	}
+
+	next, last := 0, ""
+	for _, e := range entries {
+		rest := strings.TrimPrefix(e.Name(), prefix)
+		i := strings.Index(rest, ".evt")
+		if rest == e.Name() || i <= 0 || strings.HasSuffix(e.Name(), IndexSuffix) {
+			continue
+		}
+		if seq, err := strconv.Atoi(rest[:i]); err == nil && seq >= next {
+			next, last = seq+1, filepath.Join(dir, e.Name())
+		}
+	}
+	return next, last, nil
+}
+
+// Write appends an event to the file. Buffered events are flushed whenever a new block starts, which is also
+// the only point at which the file is rotated.
+func (w *Writer) Write(e *eventspb.BusEvent) error {
+	if height, ok := BlockHeight(e); ok && (!w.inBlock || height != w.block.Height) {
+		if err := w.endBlock(); err != nil {
+			return err
+		}
+		if w.opts.rotating() && w.seg.full(w.opts) {
+			if err := w.rotate(); err != nil {
+				return err
+			}
+		}
+		w.block = IndexEntry{Height: height, Offset: w.seg.offset}
+		w.inBlock = true
+		w.seg.blocks++
+	}
+
+	if tu := e.GetTimeUpdate(); tu != nil {
+		w.block.VegaTime = tu.Timestamp
+	}
+
+	protoBytes, err := proto.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal bus event: %w", err)
+	}
+
+	binary.BigEndian.PutUint32(w.sizeBytes, uint32(len(protoBytes)))
+	binary.BigEndian.PutUint32(w.crcBytes, crc32.Checksum(protoBytes, crcTable))
+	if err := w.seg.write(w.sizeBytes, protoBytes, w.crcBytes); err != nil {
+		return fmt.Errorf("failed to write bus event: %w", err)
+	}
+	return nil
+}
+
+// endBlock flushes the events of the current block and records it in the index.
+func (w *Writer) endBlock() error {
+	if err := w.seg.buf.Flush(); err != nil {
+		return fmt.Errorf("failed to flush events: %w", err)
+	}
+	if !w.inBlock {
+		return nil
+	}
+	if err := w.seg.index.write(w.block); err != nil {
+		return fmt.Errorf("failed to write index entry: %w", err)
+	}
+	w.last, w.hasLast = w.block, true
+	w.inBlock = false
+	return nil
+}
+
+// LastBlock returns the height of the last block completely written to the file, including blocks already in the
+// file when it was opened by Append.
+func (w *Writer) LastBlock() (uint64, bool) {
+	return w.last.Height, w.hasLast
+}
+
+func (w *Writer) rotate() error {
+	if err := w.seg.close(); err != nil {
+		return err
+	}
+	w.sequence++
+
+	seg, err := createSegment(w.segmentPath(), w.opts.Compression)
+	if err != nil {
+		return err
+	}
+	w.seg = seg
+	return nil
+}
+
+// Close flushes any pending events and closes the event file and its index.
+func (w *Writer) Close() error {
+	err := w.endBlock()
+	if cerr := w.seg.close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// countingWriter records the number of bytes that reach the file.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// segmentWriter is a single, possibly compressed, event file being written.
+type segmentWriter struct {
+	path       string
+	file       *os.File
+	written    *countingWriter
+	compressor io.WriteCloser
+	buf        *bufio.Writer
+	index      *indexWriter
+
+	// offset is the position in the uncompressed stream, as recorded in the index
+	offset  int64
+	blocks  uint64
+	created time.Time
+}
+
+func createSegment(path, compression string) (*segmentWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create file %s: %w", path, err)
+	}
+
+	index, err := createIndex(path)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	s := &segmentWriter{
+		path:    path,
+		file:    f,
+		written: &countingWriter{w: f},
+		index:   index,
+		created: time.Now(),
+	}
+
+	var out io.Writer = s.written
+	if compression != CompressionNone {
+		if s.compressor, err = newCompressor(s.written, compression); err != nil {
+			s.close()
+			return nil, err
+		}
+		out = s.compressor
+	}
+	s.buf = bufio.NewWriter(out)
+
+	if err := writeHeader(s.buf, Header{Version: CurrentVersion}); err != nil {
+		s.close()
+		return nil, fmt.Errorf("failed to write file header: %w", err)
+	}
+	s.offset = headerSize
+
+	return s, nil
+}
+
+func (s *segmentWriter) write(chunks ...[]byte) error {
+	for _, c := range chunks {
+		if _, err := s.buf.Write(c); err != nil {
+			return err
+		}
+		s.offset += int64(len(c))
+	}
+	return nil
+}
+
+// full returns true once the segment has reached any of the rotation limits.
+func (s *segmentWriter) full(opts WriterOpts) bool {
+	return (opts.MaxBytes > 0 && s.written.n >= opts.MaxBytes) ||
+		(opts.MaxBlocks > 0 && s.blocks >= opts.MaxBlocks) ||
+		(opts.MaxAge > 0 && time.Since(s.created) >= opts.MaxAge)
+}
+
+func (s *segmentWriter) close() error {
+	var err error
+	if s.buf != nil {
+		err = s.buf.Flush()
+	}
+	if s.compressor != nil {
+		if cerr := s.compressor.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if cerr := s.index.close(); err == nil {
+		err = cerr
+	}
+	if cerr := s.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
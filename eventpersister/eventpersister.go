@@ -2,17 +2,17 @@ package eventpersister
 
 import (
 	"context"
-	"encoding/binary"
 	"flag"
 	"fmt"
-	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 
 	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
 	"code.vegaprotocol.io/vega/vegatools/stream"
-
-	"google.golang.org/protobuf/proto"
+	"code.vegaprotocol.io/vegatools/eventfile"
+	"code.vegaprotocol.io/vegatools/streamcheck"
 )
 
 // Run is the main function of `eventpersister` package
@@ -20,48 +20,63 @@ func Run(
 	file string,
 	batchSize uint,
 	party, market, serverAddr, logFormat string,
-	reconnect bool,
+	reconnect, resume, checkStream bool,
 	types []string,
+	fileOpts eventfile.WriterOpts,
 ) error {
 	flag.Parse()
 
 	if len(serverAddr) <= 0 {
 		return fmt.Errorf("error: missing grpc server address")
 	}
+	if checkStream && (len(types) > 0 || len(party) > 0 || len(market) > 0) {
+		return fmt.Errorf("the stream can only be checked when it is not filtered by type, party or market")
+	}
 
 	filePath, err := filepath.Abs(file)
 	if err != nil {
 		return fmt.Errorf("unable to determine absolute path of file %s: %w", file, err)
 	}
 
-	fi, err := os.Create(file)
-	defer fi.Close()
-
+	openFile := eventfile.Create
+	if resume {
+		openFile = eventfile.Append
+	}
+	fi, err := openFile(filePath, fileOpts)
 	if err != nil {
-		return fmt.Errorf("unable to create file %s: %w", filePath, err)
+		return err
 	}
+	defer fi.Close()
+
+	fmt.Printf("persisting events to: %s\n", fi.Path())
 
-	fmt.Printf("persisting events to: %s\n", filePath)
+	cp := checkpoint{sequence: len(types) == 0 && len(party) == 0 && len(market) == 0}
+	if last, ok := fi.LastBlock(); ok {
+		fmt.Printf("resuming after block %d\n", last)
+		cp.height, cp.started, cp.complete = last, true, true
+	}
 
 	logEventToConsole, err := stream.NewLogEventToConsoleFn(logFormat)
 	if err != nil {
 		return err
 	}
 
-	sizeBytes := make([]byte, 4)
 	handleEvent := func(e *eventspb.BusEvent) {
-		size := uint32(proto.Size(e))
-		protoBytes, err := proto.Marshal(e)
-		if err != nil {
-			panic("failed to marshal bus event:" + e.String())
+		if !cp.accept(e) {
+			return
+		}
+		if err := fi.Write(e); err != nil {
+			panic(fmt.Sprintf("failed to persist bus event %s: %v", e.String(), err))
 		}
-
-		binary.BigEndian.PutUint32(sizeBytes, size)
-		allBytes := append(sizeBytes, protoBytes...)
-		fi.Write(allBytes)
 		logEventToConsole(e)
 	}
 
+	if checkStream {
+		handleEvent = streamcheck.NewChecker(true, func(problem string) {
+			fmt.Printf("stream check: %s\n", problem)
+		}).Wrap(handleEvent)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	wg := sync.WaitGroup{}
@@ -74,3 +89,67 @@ func Run(
 
 	return nil
 }
+
+// checkpoint tracks the block being persisted so that events already written are not written again when the stream
+// reconnects, and events lost while disconnected are reported.
+type checkpoint struct {
+	height  uint64
+	started bool
+	// complete is set when resuming after a block that was fully written before the restart
+	complete bool
+	// seq is the sequence number of the last event persisted in the block
+	seq uint64
+	// sequence is set when the stream is not filtered, so that a gap in the sequence numbers of a block means events
+	// were missed rather than filtered out
+	sequence bool
+	// beginBlocks is set once the stream is seen to start blocks with a BEGIN_BLOCK event
+	beginBlocks bool
+}
+
+// accept returns false for events that have already been persisted.
+func (c *checkpoint) accept(e *eventspb.BusEvent) bool {
+	height, ok := eventfile.BlockHeight(e)
+	if !ok {
+		return true
+	}
+	seq, hasSeq := eventSeq(e)
+	if e.GetBeginBlock() != nil {
+		c.beginBlocks = true
+	}
+
+	if c.started && height == c.height && !c.complete {
+		if !hasSeq {
+			return true
+		}
+		if seq <= c.seq {
+			return false
+		}
+		if c.sequence && seq > c.seq+1 {
+			fmt.Printf("block %d is incomplete, unable to recover events %d-%d missed while disconnected\n", height, c.seq+1, seq-1)
+		}
+		c.seq = seq
+		return true
+	}
+
+	if c.started && height <= c.height {
+		return false
+	}
+	if c.started && height > c.height+1 {
+		fmt.Printf("unable to recover missing blocks %d-%d\n", c.height+1, height-1)
+	}
+	if c.sequence && c.beginBlocks && e.GetBeginBlock() == nil {
+		fmt.Printf("block %d is incomplete, its start was missed while disconnected\n", height)
+	}
+	c.height, c.started, c.complete, c.seq = height, true, false, seq
+	return true
+}
+
+// eventSeq returns the sequence number of an event within its block, taken from its ID.
+func eventSeq(e *eventspb.BusEvent) (uint64, bool) {
+	i := strings.IndexByte(e.Id, '-')
+	if i < 0 {
+		return 0, false
+	}
+	seq, err := strconv.ParseUint(e.Id[i+1:], 10, 64)
+	return seq, err == nil
+}
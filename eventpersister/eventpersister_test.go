@@ -0,0 +1,102 @@
+package eventpersister
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
+	"code.vegaprotocol.io/vegatools/eventfile"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func event(height, seq uint64) *eventspb.BusEvent {
+	e := &eventspb.BusEvent{Id: fmt.Sprintf("%d-%d", height, seq), Type: eventspb.BusEventType_BUS_EVENT_TYPE_ORDER}
+	if seq == 0 {
+		e.Type = eventspb.BusEventType_BUS_EVENT_TYPE_BEGIN_BLOCK
+		e.Event = &eventspb.BusEvent_BeginBlock{BeginBlock: &eventspb.BeginBlock{Height: height}}
+	}
+	return e
+}
+
+// acceptAll passes the events through the checkpoint and returns the IDs of those accepted and what was printed.
+func acceptAll(t *testing.T, c *checkpoint, events ...*eventspb.BusEvent) ([]string, string) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	accepted := []string{}
+	for _, e := range events {
+		if c.accept(e) {
+			accepted = append(accepted, e.Id)
+		}
+	}
+	require.NoError(t, w.Close())
+	out, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return accepted, string(out)
+}
+
+func TestCheckpointDropsEventsAlreadyPersisted(t *testing.T) {
+	c := &checkpoint{sequence: true}
+	accepted, out := acceptAll(t, c,
+		event(1, 0), event(1, 1), event(1, 2),
+		// reconnected part way through block 1
+		event(1, 1), event(1, 2), event(1, 3),
+		event(2, 0), event(2, 1),
+		// reconnected in block 2 after it was left
+		event(1, 4), event(2, 1), event(2, 2),
+	)
+	assert.Equal(t, []string{"1-0", "1-1", "1-2", "1-3", "2-0", "2-1", "2-2"}, accepted)
+	assert.Empty(t, out)
+}
+
+func TestCheckpointResumesAfterCompleteBlock(t *testing.T) {
+	c := &checkpoint{height: 5, started: true, complete: true, sequence: true}
+	accepted, out := acceptAll(t, c, event(5, 3), event(5, 4), event(6, 0), event(6, 1))
+	assert.Equal(t, []string{"6-0", "6-1"}, accepted)
+	assert.Empty(t, out)
+}
+
+func TestCheckpointReportsMissedEvents(t *testing.T) {
+	c := &checkpoint{sequence: true}
+	accepted, out := acceptAll(t, c,
+		event(1, 0), event(1, 1),
+		// reconnected after events of block 1 were sent
+		event(1, 4), event(1, 5),
+		// and after block 2 started
+		event(2, 3),
+		// and after blocks were missed
+		event(5, 0),
+	)
+	assert.Equal(t, []string{"1-0", "1-1", "1-4", "1-5", "2-3", "5-0"}, accepted)
+	assert.Equal(t, "block 1 is incomplete, unable to recover events 2-3 missed while disconnected\n"+
+		"block 2 is incomplete, its start was missed while disconnected\n"+
+		"unable to recover missing blocks 3-4\n", out)
+}
+
+func TestCheckpointIgnoresGapsInFilteredStream(t *testing.T) {
+	c := &checkpoint{}
+	accepted, out := acceptAll(t, c, event(1, 0), event(1, 3), event(2, 2))
+	assert.Equal(t, []string{"1-0", "1-3", "2-2"}, accepted)
+	assert.Empty(t, out)
+}
+
+func TestRejectedFlagsLeaveFileUntouched(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vega.evt")
+	require.NoError(t, os.WriteFile(path, []byte("captured events"), 0o644))
+
+	err := Run(path, 0, "", "", "localhost:3002", "raw", false, false, true, []string{"BUS_EVENT_TYPE_ORDER"}, eventfile.WriterOpts{})
+	assert.EqualError(t, err, "the stream can only be checked when it is not filtered by type, party or market")
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "captured events", string(b))
+}
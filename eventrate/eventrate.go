@@ -3,12 +3,19 @@ package eventrate
 import (
 	"context"
 	"fmt"
+	"os"
 	"sort"
 	"sync"
 	"time"
 
 	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
 	"code.vegaprotocol.io/vega/vegatools/stream"
+	"code.vegaprotocol.io/vegatools/eventfile"
+	"code.vegaprotocol.io/vegatools/metrics"
+	"code.vegaprotocol.io/vegatools/output"
+	"code.vegaprotocol.io/vegatools/streamcheck"
+
+	"github.com/prometheus/client_golang/prometheus"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -20,6 +27,17 @@ type Opts struct {
 	EventCountDump      int
 	ReportStyle         bool
 	FinalReportRowCount int
+	CheckStream         bool
+	// MetricsListen is the address Prometheus metrics are served on, nothing is printed when it is set
+	MetricsListen string
+	// TypeRates prints a table of the rate of each event type, limited to the Top types by bandwidth if set
+	TypeRates bool
+	Top       int
+	// Market and Party limit the event type table to events referencing them
+	Market string
+	Party  string
+	// Output is the format results are written in, text, json or csv
+	Output string
 }
 
 func min(a, b uint64) uint64 {
@@ -40,6 +58,7 @@ type data struct {
 	Events    uint64
 	Bytes     uint64
 	BlockTime time.Time
+	Types     map[eventspb.BusEventType]typeData
 }
 
 type eventCount struct {
@@ -69,12 +88,46 @@ func Run(opts Opts) error {
 	if len(opts.ServerAddr) <= 0 {
 		return fmt.Errorf("error: missing grpc server address")
 	}
+	// asking for the top types or filtering them only makes sense with the type table
+	opts.TypeRates = opts.TypeRates || opts.Top > 0 || len(opts.Market) > 0 || len(opts.Party) > 0
+
+	var m *rateMetrics
+	if len(opts.MetricsListen) > 0 {
+		registry := prometheus.NewRegistry()
+		m = newRateMetrics(registry)
+		if err := metrics.Serve(opts.MetricsListen, registry); err != nil {
+			return err
+		}
+	}
+	headless := m != nil
+
+	out, err := output.NewWriter(os.Stdout, opts.Output)
+	if err != nil {
+		return err
+	}
+	// only the structured records are written to stdout
+	quiet := headless || out != nil
+	filter := eventfile.Filter{Market: opts.Market, Party: opts.Party}
 
 	handleEvent := func(e *eventspb.BusEvent) {
+		size := proto.Size(e)
+		if m != nil {
+			m.event(e, size)
+		}
+
 		mu.Lock()
 		dataThisSecond.Events++
-		dataThisSecond.Bytes += uint64(proto.Size(e))
+		dataThisSecond.Bytes += uint64(size)
 		eventCounts[int32(e.Type)]++
+		if opts.TypeRates && filter.Match(e) {
+			if dataThisSecond.Types == nil {
+				dataThisSecond.Types = map[eventspb.BusEventType]typeData{}
+			}
+			td := dataThisSecond.Types[e.Type]
+			td.Events++
+			td.Bytes += uint64(size)
+			dataThisSecond.Types[e.Type] = td
+		}
 
 		switch e.Type {
 		case eventspb.BusEventType_BUS_EVENT_TYPE_TIME_UPDATE:
@@ -84,6 +137,20 @@ func Run(opts Opts) error {
 		mu.Unlock()
 	}
 
+	if opts.CheckStream {
+		handleEvent = streamcheck.NewChecker(true, func(problem string) {
+			if headless {
+				m.problems.Inc()
+				return
+			}
+			if out != nil {
+				fmt.Fprintf(os.Stderr, "stream check: %s\n", problem)
+				return
+			}
+			fmt.Printf("\nstream check: %s\n", problem)
+		}).Wrap(handleEvent)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	wg := sync.WaitGroup{}
@@ -100,9 +167,14 @@ func Run(opts Opts) error {
 		historicData = append(historicData, dataThisSecond)
 		dataThisSecond.Events = 0
 		dataThisSecond.Bytes = 0
+		dataThisSecond.Types = nil
 		blockTime := dataThisSecond.BlockTime
 		mu.Unlock()
 
+		if m != nil {
+			m.bucket(historicData[len(historicData)-1], opts.SecondsPerBucket)
+		}
+
 		// Cap the number of historic buckets we keep
 		if len(historicData) > opts.Buckets {
 			historicData = historicData[1:]
@@ -125,7 +197,31 @@ func Run(opts Opts) error {
 		}
 		avgEvents = totalEvents / uint64(len(historicData))
 		avgBytes = totalBytes / uint64(len(historicData))
-		if opts.FinalReportRowCount == 0 {
+		if opts.FinalReportRowCount == 0 && out != nil && !headless {
+			result := BucketResult{
+				Time:      time.Now(),
+				BlockTime: blockTime,
+				Events:    historicData[len(historicData)-1].Events,
+				Bytes:     historicData[len(historicData)-1].Bytes,
+				MinEvents: minEvents,
+				MaxEvents: maxEvents,
+				AvgEvents: avgEvents,
+				MinBytes:  minBytes,
+				MaxBytes:  maxBytes,
+				AvgBytes:  avgBytes,
+			}
+			if opts.TypeRates {
+				result.Types = typeResults(typeRates(historicData, opts.SecondsPerBucket, opts.Top))
+			}
+			if err := out.Write(result); err != nil {
+				return fmt.Errorf("failed to write result: %w", err)
+			}
+		}
+		if opts.FinalReportRowCount == 0 && !quiet {
+			if opts.TypeRates && !opts.ReportStyle {
+				// redraw the table in place
+				fmt.Print("\033[H\033[2J")
+			}
 			fmt.Printf("%s Events:Bandwidth (", blockTime.Format(time.UnixDate))
 			for i := len(historicData) - 1; i > 0; i-- {
 				fmt.Printf("[%d:%s], ", historicData[i].Events, fixUnits(historicData[i].Bytes))
@@ -134,12 +230,16 @@ func Run(opts Opts) error {
 				historicData[0].Events, fixUnits(historicData[0].Bytes),
 				minEvents, fixUnits(minBytes), maxEvents, fixUnits(maxBytes), avgEvents, fixUnits(avgBytes))
 
-			if opts.ReportStyle {
+			if opts.ReportStyle || opts.TypeRates {
+				fmt.Println()
+			}
+			if opts.TypeRates {
+				printTypeRates(os.Stdout, typeRates(historicData, opts.SecondsPerBucket, opts.Top))
 				fmt.Println()
 			}
 		}
 
-		if opts.EventCountDump > 0 {
+		if opts.EventCountDump > 0 && !quiet {
 			displayCounter++
 			if displayCounter >= opts.EventCountDump {
 				fmt.Println()
@@ -166,6 +266,12 @@ func Run(opts Opts) error {
 		}
 		reportCount++
 		if reportCount == opts.FinalReportRowCount {
+			if headless {
+				break
+			}
+			if out != nil {
+				return out.Write(FinalResult{Buckets: len(historicData), AverageEvents: avgEvents, AverageBytes: avgBytes})
+			}
 			fmt.Printf("AverageEvents:%d:AverageBytes:%d\n", avgEvents, avgBytes)
 			break
 		}
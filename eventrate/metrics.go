@@ -0,0 +1,111 @@
+package eventrate
+
+import (
+	"sync"
+	"time"
+
+	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// rateMetrics are the Prometheus metrics exported with --metrics-listen.
+type rateMetrics struct {
+	events          prometheus.Counter
+	bytes           prometheus.Counter
+	eventsByType    *prometheus.CounterVec
+	eventsPerSecond prometheus.Gauge
+	bytesPerSecond  prometheus.Gauge
+	blockHeight     prometheus.Gauge
+	blockTime       prometheus.Gauge
+	blockDuration   prometheus.Histogram
+	problems        prometheus.Counter
+
+	mu       sync.Mutex
+	lastTime time.Time
+}
+
+func newRateMetrics(registry *prometheus.Registry) *rateMetrics {
+	m := &rateMetrics{
+		events: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "vega_eventrate_events_total",
+			Help: "Number of bus events received",
+		}),
+		bytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "vega_eventrate_bytes_total",
+			Help: "Size in bytes of the bus events received",
+		}),
+		eventsByType: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "vega_eventrate_events_by_type_total",
+			Help: "Number of bus events received of each type",
+		}, []string{"type"}),
+		eventsPerSecond: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "vega_eventrate_events_per_second",
+			Help: "Events per second received over the last bucket",
+		}),
+		bytesPerSecond: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "vega_eventrate_bytes_per_second",
+			Help: "Bytes per second received over the last bucket",
+		}),
+		blockHeight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "vega_eventrate_block_height",
+			Help: "Height of the last block started",
+		}),
+		blockTime: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "vega_eventrate_block_time_seconds",
+			Help: "Vega time of the last block as a unix timestamp",
+		}),
+		blockDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "vega_eventrate_block_duration_seconds",
+			Help:    "Vega time between consecutive blocks",
+			Buckets: []float64{0.1, 0.25, 0.5, 0.75, 1, 1.5, 2, 3, 5, 10, 30},
+		}),
+		problems: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "vega_eventrate_stream_problems_total",
+			Help: "Number of problems found in the stream with --check-stream",
+		}),
+	}
+
+	lag := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "vega_eventrate_block_lag_seconds",
+		Help: "Wall clock time less the vega time of the last block",
+	}, func() float64 {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.lastTime.IsZero() {
+			return 0
+		}
+		return time.Since(m.lastTime).Seconds()
+	})
+
+	registry.MustRegister(m.events, m.bytes, m.eventsByType, m.eventsPerSecond, m.bytesPerSecond,
+		m.blockHeight, m.blockTime, m.blockDuration, m.problems, lag)
+	return m
+}
+
+func (m *rateMetrics) event(e *eventspb.BusEvent, size int) {
+	m.events.Inc()
+	m.bytes.Add(float64(size))
+	m.eventsByType.WithLabelValues(e.Type.String()).Inc()
+
+	if bb := e.GetBeginBlock(); bb != nil {
+		m.blockHeight.Set(float64(bb.Height))
+	}
+	if tu := e.GetTimeUpdate(); tu != nil {
+		t := time.Unix(0, tu.Timestamp)
+		m.blockTime.Set(float64(t.UnixNano()) / float64(time.Second))
+
+		m.mu.Lock()
+		if !m.lastTime.IsZero() && t.After(m.lastTime) {
+			m.blockDuration.Observe(t.Sub(m.lastTime).Seconds())
+		}
+		m.lastTime = t
+		m.mu.Unlock()
+	}
+}
+
+// bucket records the rates of a completed bucket.
+func (m *rateMetrics) bucket(d data, secondsPerBucket int) {
+	m.eventsPerSecond.Set(float64(d.Events) / float64(secondsPerBucket))
+	m.bytesPerSecond.Set(float64(d.Bytes) / float64(secondsPerBucket))
+}
@@ -0,0 +1,72 @@
+package eventrate
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
+	"code.vegaprotocol.io/vegatools/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// freeAddress returns a local address nothing listens on.
+func freeAddress(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func scrape(t *testing.T, address string) string {
+	t.Helper()
+	resp, err := http.Get("http://" + address + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestRateMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	m := newRateMetrics(registry)
+	address := freeAddress(t)
+	require.NoError(t, metrics.Serve(address, registry))
+
+	start := time.Unix(1700000000, 0)
+	m.event(&eventspb.BusEvent{Type: eventspb.BusEventType_BUS_EVENT_TYPE_BEGIN_BLOCK, Event: &eventspb.BusEvent_BeginBlock{
+		BeginBlock: &eventspb.BeginBlock{Height: 12},
+	}}, 10)
+	for i := 0; i < 2; i++ {
+		m.event(&eventspb.BusEvent{Type: eventspb.BusEventType_BUS_EVENT_TYPE_TIME_UPDATE, Event: &eventspb.BusEvent_TimeUpdate{
+			TimeUpdate: &eventspb.TimeUpdate{Timestamp: start.Add(time.Duration(i) * 500 * time.Millisecond).UnixNano()},
+		}}, 20)
+	}
+	m.bucket(data{Events: 30, Bytes: 600}, 3)
+
+	out := scrape(t, address)
+	assert.Contains(t, out, "vega_eventrate_events_total 3\n")
+	assert.Contains(t, out, "vega_eventrate_bytes_total 50\n")
+	assert.Contains(t, out, `vega_eventrate_events_by_type_total{type="BUS_EVENT_TYPE_TIME_UPDATE"} 2`+"\n")
+	assert.Contains(t, out, "vega_eventrate_block_height 12\n")
+	assert.Contains(t, out, "vega_eventrate_block_time_seconds 1.7000000005e+09\n")
+	assert.Contains(t, out, `vega_eventrate_block_duration_seconds_bucket{le="0.5"} 1`+"\n")
+	assert.Contains(t, out, "vega_eventrate_events_per_second 10\n")
+	assert.Contains(t, out, "vega_eventrate_bytes_per_second 200\n")
+}
+
+func TestServeReportsAddressInUse(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	err = metrics.Serve(l.Addr().String(), prometheus.NewRegistry())
+	assert.ErrorContains(t, err, "unable to listen for metrics on "+l.Addr().String())
+}
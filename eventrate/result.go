@@ -0,0 +1,57 @@
+package eventrate
+
+import "time"
+
+// BucketResult is written for every bucket with --output json or csv. The minimum, maximum and average are over the
+// historic buckets.
+type BucketResult struct {
+	Time      time.Time    `json:"time"`
+	BlockTime time.Time    `json:"block_time"`
+	Events    uint64       `json:"events"`
+	Bytes     uint64       `json:"bytes"`
+	MinEvents uint64       `json:"min_events"`
+	MaxEvents uint64       `json:"max_events"`
+	AvgEvents uint64       `json:"avg_events"`
+	MinBytes  uint64       `json:"min_bytes"`
+	MaxBytes  uint64       `json:"max_bytes"`
+	AvgBytes  uint64       `json:"avg_bytes"`
+	Types     []TypeResult `json:"types,omitempty"`
+}
+
+// TypeResult is the rate of one event type, included in a BucketResult with --by-type. It is only written as JSON.
+type TypeResult struct {
+	Type               string  `json:"type"`
+	EventsPerSecond    float64 `json:"events_per_second"`
+	MinEventsPerSecond float64 `json:"min_events_per_second"`
+	MaxEventsPerSecond float64 `json:"max_events_per_second"`
+	AvgEventsPerSecond float64 `json:"avg_events_per_second"`
+	BytesPerSecond     float64 `json:"bytes_per_second"`
+	MinBytesPerSecond  float64 `json:"min_bytes_per_second"`
+	MaxBytesPerSecond  float64 `json:"max_bytes_per_second"`
+	AvgBytesPerSecond  float64 `json:"avg_bytes_per_second"`
+}
+
+// FinalResult is written instead of the bucket results when a final report is requested.
+type FinalResult struct {
+	Buckets       int    `json:"buckets"`
+	AverageEvents uint64 `json:"average_events"`
+	AverageBytes  uint64 `json:"average_bytes"`
+}
+
+func typeResults(rates []typeRate) []TypeResult {
+	results := make([]TypeResult, 0, len(rates))
+	for _, r := range rates {
+		results = append(results, TypeResult{
+			Type:               r.name,
+			EventsPerSecond:    r.events.last,
+			MinEventsPerSecond: r.events.min,
+			MaxEventsPerSecond: r.events.max,
+			AvgEventsPerSecond: r.events.avg,
+			BytesPerSecond:     r.bytes.last,
+			MinBytesPerSecond:  r.bytes.min,
+			MaxBytesPerSecond:  r.bytes.max,
+			AvgBytesPerSecond:  r.bytes.avg,
+		})
+	}
+	return results
+}
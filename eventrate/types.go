@@ -0,0 +1,94 @@
+package eventrate
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
+)
+
+// typeData is the number and size of the events of one type received in a bucket.
+type typeData struct {
+	Events uint64
+	Bytes  uint64
+}
+
+// rateStats are the per second rates of the last bucket and their minimum, maximum and average over all buckets.
+type rateStats struct {
+	last, min, max, avg float64
+}
+
+// typeRate is the rate of one event type over the historic buckets.
+type typeRate struct {
+	name   string
+	events rateStats
+	bytes  rateStats
+}
+
+// typeRates returns the rates of every event type seen in history, highest average bandwidth first. Only the first
+// top types are returned when top is greater than zero.
+func typeRates(history []data, secondsPerBucket int, top int) []typeRate {
+	seen := map[eventspb.BusEventType]struct{}{}
+	for _, d := range history {
+		for t := range d.Types {
+			seen[t] = struct{}{}
+		}
+	}
+
+	rates := make([]typeRate, 0, len(seen))
+	for t := range seen {
+		events := make([]float64, len(history))
+		bytes := make([]float64, len(history))
+		for i, d := range history {
+			events[i] = float64(d.Types[t].Events) / float64(secondsPerBucket)
+			bytes[i] = float64(d.Types[t].Bytes) / float64(secondsPerBucket)
+		}
+		rates = append(rates, typeRate{
+			name:   strings.TrimPrefix(t.String(), "BUS_EVENT_TYPE_"),
+			events: newRateStats(events),
+			bytes:  newRateStats(bytes),
+		})
+	}
+
+	sort.Slice(rates, func(i, j int) bool {
+		if rates[i].bytes.avg != rates[j].bytes.avg {
+			return rates[i].bytes.avg > rates[j].bytes.avg
+		}
+		return rates[i].name < rates[j].name
+	})
+	if top > 0 && len(rates) > top {
+		rates = rates[:top]
+	}
+	return rates
+}
+
+func newRateStats(values []float64) rateStats {
+	s := rateStats{last: values[len(values)-1], min: values[0], max: values[0]}
+	var total float64
+	for _, v := range values {
+		if v < s.min {
+			s.min = v
+		}
+		if v > s.max {
+			s.max = v
+		}
+		total += v
+	}
+	s.avg = total / float64(len(values))
+	return s
+}
+
+// printTypeRates writes rates as a table of events and bytes per second.
+func printTypeRates(w io.Writer, rates []typeRate) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "TYPE\tEVENTS/S\tMIN\tMAX\tAVG\tBYTES/S\tMIN\tMAX\tAVG\t")
+	for _, r := range rates {
+		fmt.Fprintf(tw, "%s\t%.1f\t%.1f\t%.1f\t%.1f\t%s\t%s\t%s\t%s\t\n", r.name,
+			r.events.last, r.events.min, r.events.max, r.events.avg,
+			fixUnits(uint64(r.bytes.last)), fixUnits(uint64(r.bytes.min)), fixUnits(uint64(r.bytes.max)), fixUnits(uint64(r.bytes.avg)))
+	}
+	tw.Flush()
+}
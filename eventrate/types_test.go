@@ -0,0 +1,55 @@
+package eventrate
+
+import (
+	"bytes"
+	"testing"
+
+	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	orderType = eventspb.BusEventType_BUS_EVENT_TYPE_ORDER
+	tradeType = eventspb.BusEventType_BUS_EVENT_TYPE_TRADE
+	timeType  = eventspb.BusEventType_BUS_EVENT_TYPE_TIME_UPDATE
+)
+
+func TestTypeRates(t *testing.T) {
+	history := []data{
+		{Types: map[eventspb.BusEventType]typeData{orderType: {Events: 10, Bytes: 1000}, timeType: {Events: 2, Bytes: 20}}},
+		{Types: map[eventspb.BusEventType]typeData{orderType: {Events: 30, Bytes: 3000}, tradeType: {Events: 4, Bytes: 4000}}},
+	}
+
+	rates := typeRates(history, 2, 0)
+	require.Len(t, rates, 3)
+	// highest average bandwidth first
+	assert.Equal(t, []string{"ORDER", "TRADE", "TIME_UPDATE"}, []string{rates[0].name, rates[1].name, rates[2].name})
+	assert.Equal(t, rateStats{last: 15, min: 5, max: 15, avg: 10}, rates[0].events)
+	assert.Equal(t, rateStats{last: 1500, min: 500, max: 1500, avg: 1000}, rates[0].bytes)
+	// a type missing from a bucket counts as no events in it
+	assert.Equal(t, rateStats{last: 0, min: 0, max: 1, avg: 0.5}, rates[2].events)
+
+	rates = typeRates(history, 2, 1)
+	require.Len(t, rates, 1)
+	assert.Equal(t, "ORDER", rates[0].name)
+
+	results := typeResults(rates)
+	assert.Equal(t, []TypeResult{{
+		Type: "ORDER", EventsPerSecond: 15, MinEventsPerSecond: 5, MaxEventsPerSecond: 15, AvgEventsPerSecond: 10,
+		BytesPerSecond: 1500, MinBytesPerSecond: 500, MaxBytesPerSecond: 1500, AvgBytesPerSecond: 1000,
+	}}, results)
+}
+
+func TestPrintTypeRates(t *testing.T) {
+	buf := bytes.Buffer{}
+	printTypeRates(&buf, []typeRate{{
+		name:   "ORDER",
+		events: rateStats{last: 15, min: 5, max: 15, avg: 10},
+		bytes:  rateStats{last: 3 * 1024 * 1024, min: 2048, max: 3 * 1024 * 1024, avg: 100},
+	}})
+	assert.Equal(t, ""+
+		"   TYPE  EVENTS/S  MIN   MAX   AVG  BYTES/S  MIN  MAX   AVG\n"+
+		"  ORDER      15.0  5.0  15.0  10.0      3MB  2KB  3MB  100B\n", buf.String())
+}
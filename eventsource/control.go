@@ -0,0 +1,162 @@
+package eventsource
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// control lets a replay be paused, resumed, stepped one block at a time and have its speed changed while it runs.
+type control struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	paused bool
+	steps  int
+	speed  float64
+}
+
+func newControl(speed float64, paused bool) *control {
+	c := &control{speed: speed, paused: paused}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+func (c *control) pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paused = true
+}
+
+func (c *control) resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paused = false
+	c.cond.Broadcast()
+}
+
+// step lets one more block through while paused.
+func (c *control) step() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paused = true
+	c.steps++
+	c.cond.Broadcast()
+}
+
+func (c *control) setSpeed(speed float64) error {
+	if speed < 0 {
+		return fmt.Errorf("speed must not be negative")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.speed = speed
+	return nil
+}
+
+func (c *control) getSpeed() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.speed
+}
+
+func (c *control) status() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state := "running"
+	if c.paused {
+		state = "paused"
+	}
+	return fmt.Sprintf("%s, speed %gx", state, c.speed)
+}
+
+// wait blocks while the replay is paused and returns true if it had to wait.
+func (c *control) wait() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	waited := false
+	for c.paused && c.steps == 0 {
+		waited = true
+		c.cond.Wait()
+	}
+	if c.steps > 0 {
+		c.steps--
+	}
+	return waited
+}
+
+// command applies a single text command, returning the resulting status.
+func (c *control) command(cmd string) (string, error) {
+	fields := strings.Fields(cmd)
+	if len(fields) == 0 {
+		return c.status(), nil
+	}
+
+	switch fields[0] {
+	case "pause", "p":
+		c.pause()
+	case "resume", "r":
+		c.resume()
+	case "step", "s":
+		c.step()
+	case "speed":
+		if len(fields) != 2 {
+			return "", fmt.Errorf("usage: speed <multiplier>")
+		}
+		speed, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid speed %s: %w", fields[1], err)
+		}
+		if err := c.setSpeed(speed); err != nil {
+			return "", err
+		}
+	case "status":
+	default:
+		return "", fmt.Errorf("unknown command %s, allowed commands: pause, resume, step, speed <multiplier>, status", fields[0])
+	}
+	return c.status(), nil
+}
+
+// readCommands applies the commands read from r, one per line, until it is closed.
+func (c *control) readCommands(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		status, err := c.command(scanner.Text())
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		fmt.Printf("replay %s\n", status)
+	}
+}
+
+// handler serves the commands over HTTP, e.g. POST /pause, POST /step or POST /speed?x=10. GET /status returns
+// the current state.
+func (c *control) handler() http.Handler {
+	mux := http.NewServeMux()
+	for _, name := range []string{"pause", "resume", "step", "speed", "status"} {
+		name := name
+		mux.HandleFunc("/"+name, func(w http.ResponseWriter, r *http.Request) {
+			if name != "status" && r.Method != http.MethodPost {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			cmd := name
+			if name == "speed" {
+				cmd += " " + r.URL.Query().Get("x")
+			}
+			status, err := c.command(cmd)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			fmt.Fprintln(w, status)
+		})
+	}
+	return mux
+}
@@ -0,0 +1,112 @@
+package eventsource
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlCommands(t *testing.T) {
+	c := newControl(1, false)
+
+	status, err := c.command("")
+	require.NoError(t, err)
+	assert.Equal(t, "running, speed 1x", status)
+
+	status, err = c.command("speed 2.5")
+	require.NoError(t, err)
+	assert.Equal(t, "running, speed 2.5x", status)
+
+	status, err = c.command("p")
+	require.NoError(t, err)
+	assert.Equal(t, "paused, speed 2.5x", status)
+
+	_, err = c.command("speed -1")
+	assert.EqualError(t, err, "speed must not be negative")
+	_, err = c.command("speed")
+	assert.EqualError(t, err, "usage: speed <multiplier>")
+	_, err = c.command("rewind")
+	assert.EqualError(t, err, "unknown command rewind, allowed commands: pause, resume, step, speed <multiplier>, status")
+}
+
+// waited returns a channel receiving the result of wait once it returns.
+func waited(c *control) <-chan bool {
+	done := make(chan bool, 1)
+	go func() { done <- c.wait() }()
+	return done
+}
+
+// assertWaiting checks the replay is still waiting after a while.
+func assertWaiting(t *testing.T, done <-chan bool) {
+	t.Helper()
+	select {
+	case <-done:
+		t.Fatal("a paused replay must wait")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestControlPauseAndStep(t *testing.T) {
+	c := newControl(0, true)
+
+	// a step lets a single block through and stays paused
+	done := waited(c)
+	assertWaiting(t, done)
+	c.step()
+	assert.True(t, <-done)
+	assert.Equal(t, "paused, speed 0x", c.status())
+
+	done = waited(c)
+	assertWaiting(t, done)
+	c.resume()
+	assert.True(t, <-done)
+	assert.False(t, <-waited(c))
+}
+
+func TestControlHandler(t *testing.T) {
+	server := httptest.NewServer(newControl(1, false).handler())
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/speed?x=4", "", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get(server.URL + "/status")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "running, speed 4x\n", string(body))
+
+	resp, err = http.Get(server.URL + "/pause")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	resp, err = http.Post(server.URL+"/speed?x=fast", "", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestPacerFollowsVegaTime(t *testing.T) {
+	p := newPacer(Pacing{RealTime: true, Speed: 10})
+
+	start := time.Now()
+	p.timeUpdate(int64(time.Second))
+	p.timeUpdate(int64(2 * time.Second))
+	// one second of vega time at ten times the speed
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+	// as fast as possible
+	require.NoError(t, p.ctl.setSpeed(0))
+	start = time.Now()
+	p.timeUpdate(int64(time.Hour))
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+}
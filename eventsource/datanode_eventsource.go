@@ -2,19 +2,21 @@ package eventsource
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"time"
 
 	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
 	"code.vegaprotocol.io/vega/vegatools/stream"
+	"code.vegaprotocol.io/vegatools/eventfile"
 )
 
 // RunDatanodeEventSource is the main function of `eventsource` package
-func RunDatanodeEventSource(eventsFile string, port uint, closeConnection bool, timeBetweenBlocks time.Duration,
-	consoleLogFormat string,
+func RunDatanodeEventSource(eventsFile string, port uint, closeConnection bool, pacing Pacing, batching Batching,
+	reportInterval time.Duration, consoleLogFormat string, eventRange Range,
 ) error {
-	eventSource, err := newDatanodeEventSource(eventsFile, timeBetweenBlocks, port, consoleLogFormat)
+	eventSource, err := newDatanodeEventSource(eventsFile, pacing, batching, port, consoleLogFormat, eventRange)
 	if err != nil {
 		return fmt.Errorf("failed to create event source: %w", err)
 	}
@@ -25,7 +27,27 @@ func RunDatanodeEventSource(eventsFile string, port uint, closeConnection bool,
 		return fmt.Errorf("failed to connect socket client source: %w", err)
 	}
 
+	if pacing.ControlStdin {
+		go eventSource.pace.ctl.readCommands(os.Stdin)
+	}
+	if len(pacing.ControlListen) > 0 {
+		fmt.Printf("replay control listening on: %s\n", pacing.ControlListen)
+		go func() {
+			if err := http.ListenAndServe(pacing.ControlListen, eventSource.pace.ctl.handler()); err != nil {
+				fmt.Printf("replay control stopped: %v\n", err)
+			}
+		}()
+	}
+
+	sent := eventSource.socketClient.sent
+	done := make(chan struct{})
+	if reportInterval > 0 {
+		go sent.report(reportInterval, done)
+	}
+
 	err = eventSource.sendEvents()
+	close(done)
+	sent.summary()
 	if err != nil {
 		return fmt.Errorf("failed to send events: %w", err)
 	}
@@ -42,12 +64,14 @@ func RunDatanodeEventSource(eventsFile string, port uint, closeConnection bool,
 type dataNodeEventSource struct {
 	socketClient      *socketClient
 	eventsFile        string
-	timeBetweenBlocks time.Duration
+	eventRange        Range
+	pace              *pacer
+	batching          Batching
 	logEventToConsole func(e *eventspb.BusEvent)
 }
 
-func newDatanodeEventSource(eventsFile string, timeBetweenBlocks time.Duration,
-	port uint, logFormat string) (*dataNodeEventSource, error,
+func newDatanodeEventSource(eventsFile string, pacing Pacing, batching Batching,
+	port uint, logFormat string, eventRange Range) (*dataNodeEventSource, error,
 ) {
 	filePath, err := filepath.Abs(eventsFile)
 	if err != nil {
@@ -61,26 +85,30 @@ func newDatanodeEventSource(eventsFile string, timeBetweenBlocks time.Duration,
 		return nil, fmt.Errorf("failed to create socket client for address %s: %w", address, err)
 	}
 
-	logEventToConsole, err := stream.NewLogEventToConsoleFn(logFormat)
-	if err != nil {
-		return nil, err
+	// logging every event slows the replay down too much for load testing
+	logEventToConsole := func(e *eventspb.BusEvent) {}
+	if logFormat != "none" {
+		if logEventToConsole, err = stream.NewLogEventToConsoleFn(logFormat); err != nil {
+			return nil, err
+		}
 	}
 
 	return &dataNodeEventSource{
 		socketClient:      sc,
 		eventsFile:        eventsFile,
-		timeBetweenBlocks: timeBetweenBlocks,
+		eventRange:        eventRange,
+		pace:              newPacer(pacing),
+		batching:          batching,
 		logEventToConsole: logEventToConsole,
 	}, nil
 }
 
 func (e dataNodeEventSource) sendEvents() error {
-	fi, err := os.Open(e.eventsFile)
-	defer fi.Close()
-
+	fi, err := eventfile.Open(e.eventsFile)
 	if err != nil {
-		return fmt.Errorf("unable to open file %s: %w", e.eventsFile, err)
+		return err
 	}
+	defer fi.Close()
 
-	return sendAllEvents(e.socketClient.send, fi, 0, e.timeBetweenBlocks, e.logEventToConsole)
+	return sendAllEvents(e.socketClient.send, fi, e.eventRange, e.batching, e.pace, e.logEventToConsole)
 }
@@ -1,28 +1,90 @@
 package eventsource
 
 import (
-	"encoding/binary"
 	"fmt"
 	"io"
-	"os"
 	"time"
 
 	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
-
-	"google.golang.org/protobuf/proto"
+	"code.vegaprotocol.io/vegatools/eventfile"
 )
 
-func sendAllEvents(sendEvents func([]*eventspb.BusEvent) error, evtFile *os.File,
-	batchSize int, timeBetweenBlocks time.Duration, logEventToConsole func(e *eventspb.BusEvent),
+// Range restricts the blocks sent from an events file. Zero values leave that end of the range open.
+type Range struct {
+	FromHeight uint64
+	ToHeight   uint64
+	FromTime   time.Time
+	ToTime     time.Time
+}
+
+// seek moves the reader to the start of the range.
+func (r Range) seek(evtFile *eventfile.Reader) error {
+	var err error
+	switch {
+	case r.FromHeight > 0:
+		err = evtFile.SeekHeight(r.FromHeight)
+	case !r.FromTime.IsZero():
+		err = evtFile.SeekTime(r.FromTime)
+	}
+
+	if err == io.EOF {
+		return fmt.Errorf("start of range not found in events file")
+	}
+	return err
+}
+
+// pastEnd returns true if the block of an event is beyond the end of the range, as far as the event tells.
+func (r Range) pastEnd(event *eventspb.BusEvent) bool {
+	if r.ToHeight > 0 {
+		if height, ok := eventfile.BlockHeight(event); ok && height > r.ToHeight {
+			return true
+		}
+	}
+	if !r.ToTime.IsZero() {
+		if t, ok := blockTime(event); ok && t > r.ToTime.UnixNano() {
+			return true
+		}
+	}
+	return false
+}
+
+// blockTime returns the time of the block of an event if it carries it, from the BEGIN_BLOCK or, for files written
+// before blocks had one, the TIME_UPDATE.
+func blockTime(event *eventspb.BusEvent) (int64, bool) {
+	if bb := event.GetBeginBlock(); bb != nil {
+		return bb.Timestamp, true
+	}
+	if tu := event.GetTimeUpdate(); tu != nil {
+		return tu.Timestamp, true
+	}
+	return 0, false
+}
+
+// Batching controls how events are grouped into the batches sent to the data node.
+type Batching struct {
+	// Size is the number of events in a batch, 1 or less sends every event on its own
+	Size int
+	// FlushOnBlock sends the pending batch whenever a new block starts, so no batch spans two blocks
+	FlushOnBlock bool
+}
+
+func sendAllEvents(sendEvents func([]*eventspb.BusEvent) error, evtFile *eventfile.Reader, eventRange Range,
+	batching Batching, pace *pacer, logEventToConsole func(e *eventspb.BusEvent),
 ) error {
-	sizeBytes := make([]byte, 4)
-	msgBytes := make([]byte, 0, 10000)
-	batch := make([]*eventspb.BusEvent, 0, batchSize)
-	var offset int64 = 0
+	if batching.Size < 1 {
+		batching.Size = 1
+	}
+	batch := make([]*eventspb.BusEvent, 0, batching.Size)
 	currentBlock := ""
+	// lastBlock is set when the current block turns out to be past the end of the range part way through it
+	lastBlock := false
+
+	if err := eventRange.seek(evtFile); err != nil {
+		return err
+	}
 
 	for {
-		read, err := evtFile.ReadAt(sizeBytes, offset)
+		event, err := evtFile.Next()
 
 		if err == io.EOF {
 			// Nothing more to read, sendEvents any pending messages and return
@@ -30,40 +92,38 @@ func sendAllEvents(sendEvents func([]*eventspb.BusEvent) error, evtFile *os.File
 		}
 
 		if err != nil {
-			return fmt.Errorf("error whilst reading message size from events file:%w", err)
-		}
-
-		offset += int64(read)
-		msgSize := binary.BigEndian.Uint32(sizeBytes)
-		msgBytes = msgBytes[:msgSize]
-		read, err = evtFile.ReadAt(msgBytes, offset)
-		if err != nil {
-			return fmt.Errorf("error whilst reading message bytes from events file:%w", err)
-		}
-
-		offset += int64(read)
-
-		event := &eventspb.BusEvent{}
-		err = proto.Unmarshal(msgBytes, event)
-		if err != nil {
-			return fmt.Errorf("failed to unmarshal bus event: %w", err)
+			return err
 		}
 
 		if event.Block != currentBlock {
-			err = sendBatch(sendEvents, batch)
-			batch = batch[:0]
-			if err != nil {
-				return err
+			// The range is only cut between blocks so that data node always receives whole blocks
+			if lastBlock || eventRange.pastEnd(event) {
+				return sendBatch(sendEvents, batch)
 			}
-			time.Sleep(timeBetweenBlocks)
+
+			if batching.FlushOnBlock {
+				err = sendBatch(sendEvents, batch)
+				batch = batch[:0]
+				if err != nil {
+					return err
+				}
+			}
+			pace.blockStart()
 			currentBlock = event.Block
+		} else if eventRange.pastEnd(event) {
+			// The time of the block was only known once part of it was sent, finish it and stop
+			lastBlock = true
+		}
+
+		if tu := event.GetTimeUpdate(); tu != nil {
+			pace.timeUpdate(tu.Timestamp)
 		}
 
 		batch = append(batch, event)
 
 		logEventToConsole(event)
 
-		if len(batch) >= batchSize {
+		if len(batch) >= batching.Size {
 			err = sendBatch(sendEvents, batch)
 			batch = batch[:0]
 			if err != nil {
@@ -0,0 +1,144 @@
+package eventsource
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
+	"code.vegaprotocol.io/vegatools/eventfile"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// block returns the events of a block at height, one second of vega time after the previous one. Blocks written
+// before BEGIN_BLOCK existed start with an event that carries no time.
+func block(height uint64, withBeginBlock bool) []*eventspb.BusEvent {
+	blockID := fmt.Sprintf("block-%d", height)
+	timestamp := int64(height) * int64(time.Second)
+	event := func(seq int, e *eventspb.BusEvent) *eventspb.BusEvent {
+		e.Id, e.Block = fmt.Sprintf("%d-%d", height, seq), blockID
+		return e
+	}
+
+	first := event(0, &eventspb.BusEvent{Type: eventspb.BusEventType_BUS_EVENT_TYPE_PARTY})
+	if withBeginBlock {
+		first = event(0, &eventspb.BusEvent{Type: eventspb.BusEventType_BUS_EVENT_TYPE_BEGIN_BLOCK, Event: &eventspb.BusEvent_BeginBlock{
+			BeginBlock: &eventspb.BeginBlock{Height: height, Timestamp: timestamp},
+		}})
+	}
+	return []*eventspb.BusEvent{
+		first,
+		event(1, &eventspb.BusEvent{Type: eventspb.BusEventType_BUS_EVENT_TYPE_TIME_UPDATE, Event: &eventspb.BusEvent_TimeUpdate{
+			TimeUpdate: &eventspb.TimeUpdate{Timestamp: timestamp},
+		}}),
+		event(2, &eventspb.BusEvent{Type: eventspb.BusEventType_BUS_EVENT_TYPE_END_BLOCK, Event: &eventspb.BusEvent_EndBlock{
+			EndBlock: &eventspb.EndBlock{Height: height},
+		}}),
+	}
+}
+
+func writeBlocks(t *testing.T, from, to uint64, withBeginBlock bool) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "events.evt")
+	w, err := eventfile.Create(path, eventfile.WriterOpts{})
+	require.NoError(t, err)
+	for h := from; h <= to; h++ {
+		for _, e := range block(h, withBeginBlock) {
+			require.NoError(t, w.Write(e))
+		}
+	}
+	require.NoError(t, w.Close())
+	return path
+}
+
+// send replays the file at path and returns the batches sent.
+func send(t *testing.T, path string, eventRange Range, batching Batching) [][]string {
+	t.Helper()
+	r, err := eventfile.Open(path)
+	require.NoError(t, err)
+	defer r.Close()
+
+	batches := [][]string{}
+	sendEvents := func(events []*eventspb.BusEvent) error {
+		ids := []string{}
+		for _, e := range events {
+			ids = append(ids, e.Id)
+		}
+		batches = append(batches, ids)
+		return nil
+	}
+	require.NoError(t, sendAllEvents(sendEvents, r, eventRange, batching, newPacer(Pacing{}), func(*eventspb.BusEvent) {}))
+	return batches
+}
+
+func TestSendHeightRange(t *testing.T) {
+	path := writeBlocks(t, 1, 5, true)
+
+	batches := send(t, path, Range{FromHeight: 2, ToHeight: 3}, Batching{Size: 100})
+	assert.Equal(t, [][]string{{"2-0", "2-1", "2-2", "3-0", "3-1", "3-2"}}, batches)
+}
+
+func TestSendTimeRangeEndsBetweenBlocks(t *testing.T) {
+	for _, withBeginBlock := range []bool{true, false} {
+		t.Run(fmt.Sprintf("begin block %v", withBeginBlock), func(t *testing.T) {
+			path := writeBlocks(t, 1, 5, withBeginBlock)
+
+			// without a BEGIN_BLOCK the time of block 4 is only known from its second event, the block is
+			// finished rather than cut after its first event
+			batches := send(t, path, Range{ToTime: time.Unix(3, 500)}, Batching{Size: 2})
+			expected := [][]string{{"1-0", "1-1"}, {"1-2", "2-0"}, {"2-1", "2-2"}, {"3-0", "3-1"}, {"3-2"}}
+			if !withBeginBlock {
+				expected = [][]string{{"1-0", "1-1"}, {"1-2", "2-0"}, {"2-1", "2-2"}, {"3-0", "3-1"}, {"3-2", "4-0"}, {"4-1", "4-2"}}
+			}
+			assert.Equal(t, expected, batches)
+		})
+	}
+}
+
+func TestSendFlushOnBlock(t *testing.T) {
+	path := writeBlocks(t, 1, 3, true)
+
+	batches := send(t, path, Range{ToHeight: 2}, Batching{Size: 100, FlushOnBlock: true})
+	assert.Equal(t, [][]string{{"1-0", "1-1", "1-2"}, {"2-0", "2-1", "2-2"}}, batches)
+}
+
+func TestSendBatchSize(t *testing.T) {
+	path := writeBlocks(t, 1, 2, true)
+
+	for _, size := range []int{-1, 0, 1} {
+		batches := send(t, path, Range{}, Batching{Size: size})
+		assert.Equal(t, [][]string{{"1-0"}, {"1-1"}, {"1-2"}, {"2-0"}, {"2-1"}, {"2-2"}}, batches)
+	}
+
+	batches := send(t, path, Range{}, Batching{Size: 4})
+	assert.Equal(t, [][]string{{"1-0", "1-1", "1-2", "2-0"}, {"2-1", "2-2"}}, batches)
+}
+
+func TestSendStopsOnError(t *testing.T) {
+	r, err := eventfile.Open(writeBlocks(t, 1, 3, true))
+	require.NoError(t, err)
+	defer r.Close()
+
+	sent := 0
+	failure := errors.New("connection closed")
+	sendEvents := func(events []*eventspb.BusEvent) error {
+		sent++
+		return failure
+	}
+	err = sendAllEvents(sendEvents, r, Range{}, Batching{Size: 2}, newPacer(Pacing{}), func(*eventspb.BusEvent) {})
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, 1, sent)
+}
+
+func TestThroughput(t *testing.T) {
+	tp := newThroughput()
+	tp.add(3, 300)
+	tp.add(2, 100)
+	assert.Equal(t, uint64(5), tp.events.Load())
+	assert.Equal(t, uint64(400), tp.bytes.Load())
+	assert.Equal(t, uint64(2), tp.batches.Load())
+}
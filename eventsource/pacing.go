@@ -0,0 +1,61 @@
+package eventsource
+
+import (
+	"time"
+)
+
+// Pacing controls the timing of a replay.
+type Pacing struct {
+	// TimeBetweenBlocks is the fixed wait before each block when RealTime is not set
+	TimeBetweenBlocks time.Duration
+	// RealTime follows the original block timing taken from the TIME_UPDATE events
+	RealTime bool
+	// Speed multiplies the original timing, 0 sends blocks as fast as possible
+	Speed float64
+	// Paused starts the replay paused, waiting for a resume or step command
+	Paused bool
+	// ControlStdin reads pause, resume, step and speed commands from stdin
+	ControlStdin bool
+	// ControlListen is the address of an HTTP endpoint accepting the same commands, empty to disable it
+	ControlListen string
+}
+
+// pacer waits between the blocks of a replay.
+type pacer struct {
+	opts Pacing
+	ctl  *control
+
+	// vega and wall clock time of the last time update sent
+	lastVegaTime int64
+	lastSent     time.Time
+}
+
+func newPacer(opts Pacing) *pacer {
+	return &pacer{opts: opts, ctl: newControl(opts.Speed, opts.Paused)}
+}
+
+// blockStart is called before the first event of each block is sent.
+func (p *pacer) blockStart() {
+	if p.ctl.wait() {
+		// the pause is not part of the original timing
+		p.lastSent = time.Now()
+	}
+	if !p.opts.RealTime {
+		time.Sleep(p.opts.TimeBetweenBlocks)
+	}
+}
+
+// timeUpdate is called before a TIME_UPDATE event is sent and waits for the original time between blocks, scaled
+// by the current speed, to pass.
+func (p *pacer) timeUpdate(vegaTime int64) {
+	if !p.opts.RealTime {
+		return
+	}
+
+	if speed := p.ctl.getSpeed(); p.lastVegaTime > 0 && speed > 0 && vegaTime > p.lastVegaTime {
+		wait := time.Duration(float64(vegaTime-p.lastVegaTime) / speed)
+		time.Sleep(time.Until(p.lastSent.Add(wait)))
+	}
+	p.lastVegaTime = vegaTime
+	p.lastSent = time.Now()
+}
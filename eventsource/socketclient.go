@@ -21,6 +21,7 @@ import (
 type socketClient struct {
 	address string
 	sock    protocol.Socket
+	sent    *throughput
 }
 
 func pipeEventToString(pe mangos.PipeEvent) string {
@@ -50,6 +51,7 @@ func newSocketClient(address string) (*socketClient, error) {
 	s := &socketClient{
 		address: address,
 		sock:    sock,
+		sent:    newThroughput(),
 	}
 
 	return s, nil
@@ -71,6 +73,9 @@ func (s *socketClient) close() {
 }
 
 func (s *socketClient) send(events []*eventspb.BusEvent) error {
+	bytes := 0
+	defer func() { s.sent.add(len(events), bytes) }()
+
 	for _, event := range events {
 		msg, err := proto.Marshal(event)
 		if err != nil {
@@ -81,6 +86,7 @@ func (s *socketClient) send(events []*eventspb.BusEvent) error {
 		if err != nil {
 			return fmt.Errorf("failed to send event: %w", err)
 		}
+		bytes += len(msg)
 	}
 	return nil
 }
@@ -0,0 +1,53 @@
+package eventsource
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// throughput counts the events and bytes sent to the data node.
+type throughput struct {
+	events  atomic.Uint64
+	bytes   atomic.Uint64
+	batches atomic.Uint64
+	started time.Time
+}
+
+func newThroughput() *throughput {
+	return &throughput{started: time.Now()}
+}
+
+func (t *throughput) add(events, bytes int) {
+	t.events.Add(uint64(events))
+	t.bytes.Add(uint64(bytes))
+	t.batches.Add(1)
+}
+
+// report prints the send rate over each interval until done is closed.
+func (t *throughput) report(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lastEvents, lastBytes, last := uint64(0), uint64(0), time.Now()
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			events, bytes := t.events.Load(), t.bytes.Load()
+			secs := now.Sub(last).Seconds()
+			fmt.Printf("sent %d events, %.0f events/s, %.0f bytes/s\n",
+				events, float64(events-lastEvents)/secs, float64(bytes-lastBytes)/secs)
+			lastEvents, lastBytes, last = events, bytes, now
+		}
+	}
+}
+
+// summary prints the totals and average rates since the replay started.
+func (t *throughput) summary() {
+	events, bytes, batches := t.events.Load(), t.bytes.Load(), t.batches.Load()
+	secs := time.Since(t.started).Seconds()
+	fmt.Printf("sent %d events in %d batches, %d bytes in %.1fs, %.0f events/s, %.0f bytes/s\n",
+		events, batches, bytes, secs, float64(events)/secs, float64(bytes)/secs)
+}
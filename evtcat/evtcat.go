@@ -0,0 +1,135 @@
+package evtcat
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
+	"code.vegaprotocol.io/vega/vegatools/stream"
+	"code.vegaprotocol.io/vegatools/eventfile"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Opts are the command line options passed to the sub command
+type Opts struct {
+	File      string
+	Format    string
+	Types     []string
+	Party     string
+	Market    string
+	FromBlock uint64
+	ToBlock   uint64
+	Stats     bool
+}
+
+type typeStats struct {
+	name   string
+	count  uint64
+	bytes  uint64
+	blocks map[uint64]struct{}
+}
+
+// Run is the main function of `evtcat` package
+func Run(opts Opts) error {
+	types, err := eventfile.ParseTypes(opts.Types)
+	if err != nil {
+		return err
+	}
+	filter := eventfile.Filter{
+		Types:      types,
+		Party:      opts.Party,
+		Market:     opts.Market,
+		FromHeight: opts.FromBlock,
+		ToHeight:   opts.ToBlock,
+	}
+
+	printEvent := func(e *eventspb.BusEvent) {}
+	if opts.Format != "none" {
+		if printEvent, err = stream.NewLogEventToConsoleFn(opts.Format); err != nil {
+			return err
+		}
+	}
+
+	evtFile, err := eventfile.Open(opts.File)
+	if err != nil {
+		return err
+	}
+	defer evtFile.Close()
+
+	if opts.FromBlock > 0 {
+		if err := evtFile.SeekHeight(opts.FromBlock); err != nil {
+			if err == io.EOF {
+				return fmt.Errorf("block %d not found in %s", opts.FromBlock, opts.File)
+			}
+			return err
+		}
+	}
+
+	var (
+		stats                 = map[eventspb.BusEventType]*typeStats{}
+		firstBlock, lastBlock uint64
+		totalEvents           uint64
+		totalBytes            uint64
+	)
+	for {
+		e, err := evtFile.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if filter.PastEnd(e) {
+			break
+		}
+		if !filter.Match(e) {
+			continue
+		}
+
+		printEvent(e)
+
+		if !opts.Stats {
+			continue
+		}
+		height, _ := eventfile.BlockHeight(e)
+		if totalEvents == 0 {
+			firstBlock = height
+		}
+		lastBlock = height
+
+		s, ok := stats[e.Type]
+		if !ok {
+			s = &typeStats{name: e.Type.String(), blocks: map[uint64]struct{}{}}
+			stats[e.Type] = s
+		}
+		size := uint64(proto.Size(e))
+		s.count++
+		s.bytes += size
+		s.blocks[height] = struct{}{}
+		totalEvents++
+		totalBytes += size
+	}
+
+	if opts.Stats {
+		printStats(stats, firstBlock, lastBlock, totalEvents, totalBytes)
+	}
+	return nil
+}
+
+func printStats(stats map[eventspb.BusEventType]*typeStats, firstBlock, lastBlock, totalEvents, totalBytes uint64) {
+	sorted := make([]*typeStats, 0, len(stats))
+	for _, s := range stats {
+		sorted = append(sorted, s)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].bytes > sorted[j].bytes })
+
+	fmt.Printf("blocks %d-%d, %d events, %d bytes\n", firstBlock, lastBlock, totalEvents, totalBytes)
+	fmt.Println("---------------------------------------------------------------------------------------")
+	fmt.Printf("%-50s %10s %14s %10s\n", "Event type", "Count", "Bytes", "Blocks")
+	fmt.Println("---------------------------------------------------------------------------------------")
+	for _, s := range sorted {
+		fmt.Printf("%-50s %10d %14d %10d\n", s.name, s.count, s.bytes, len(s.blocks))
+	}
+}
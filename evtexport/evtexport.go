@@ -0,0 +1,225 @@
+package evtexport
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
+	"code.vegaprotocol.io/vegatools/eventfile"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Opts are the command line options passed to the sub command
+type Opts struct {
+	File      string
+	OutDir    string
+	Format    string
+	Types     []string
+	FromBlock uint64
+	ToBlock   uint64
+}
+
+// typeTable is the table of one event type, created when the first event of the type is read.
+type typeTable struct {
+	path    string
+	table   table
+	payload protoreflect.FieldDescriptor
+	columns []column
+	row     []*string
+	rows    uint64
+}
+
+// Run is the main function of `evtexport` package
+func Run(opts Opts) error {
+	types, err := eventfile.ParseTypes(opts.Types)
+	if err != nil {
+		return err
+	}
+	filter := eventfile.Filter{
+		Types:      types,
+		FromHeight: opts.FromBlock,
+		ToHeight:   opts.ToBlock,
+	}
+
+	if err := os.MkdirAll(opts.OutDir, os.ModePerm); err != nil {
+		return fmt.Errorf("unable to create directory %s: %w", opts.OutDir, err)
+	}
+
+	in, err := eventfile.Open(opts.File)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if opts.FromBlock > 0 {
+		if err := in.SeekHeight(opts.FromBlock); err != nil {
+			if err == io.EOF {
+				return fmt.Errorf("block %d not found in %s", opts.FromBlock, opts.File)
+			}
+			return err
+		}
+	}
+
+	tables := map[eventspb.BusEventType]*typeTable{}
+	defer func() {
+		for _, t := range tables {
+			t.table.close()
+		}
+	}()
+
+	var (
+		vegaTime int64
+		// buffered is the size of the rows held in memory by all the tables
+		buffered int64
+	)
+	for {
+		e, err := in.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if filter.PastEnd(e) {
+			break
+		}
+
+		// the times of blocks are read even when they are not exported so every row has the time of its block, from
+		// its BEGIN_BLOCK event or, in files written before it existed, from its TIME_UPDATE event
+		if bb := e.GetBeginBlock(); bb != nil {
+			vegaTime = bb.Timestamp
+		}
+		if tu := e.GetTimeUpdate(); tu != nil {
+			vegaTime = tu.Timestamp
+		}
+		if !filter.Match(e) {
+			continue
+		}
+
+		t, ok := tables[e.Type]
+		if !ok {
+			if t, err = newTypeTable(opts, e); err != nil {
+				return err
+			}
+			if t == nil {
+				continue
+			}
+			tables[e.Type] = t
+		}
+		before := t.table.buffered()
+		if err := t.write(e, vegaTime); err != nil {
+			return fmt.Errorf("failed to write %s: %w", t.path, err)
+		}
+		if buffered += t.table.buffered() - before; buffered >= maxBufferedBytes {
+			if err := flushTables(tables); err != nil {
+				return err
+			}
+			buffered = 0
+		}
+	}
+
+	for typ, t := range tables {
+		delete(tables, typ)
+		if err := t.table.close(); err != nil {
+			return fmt.Errorf("failed to close %s: %w", t.path, err)
+		}
+		fmt.Printf("%d rows written to %s\n", t.rows, t.path)
+	}
+	return nil
+}
+
+// flushTables writes out the rows held in memory by every table.
+func flushTables(tables map[eventspb.BusEventType]*typeTable) error {
+	for _, t := range tables {
+		if err := t.table.flush(); err != nil {
+			return fmt.Errorf("failed to write %s: %w", t.path, err)
+		}
+	}
+	return nil
+}
+
+// newTypeTable creates the table for the type of e from the descriptor of its payload. Nil is returned for events
+// without a payload.
+func newTypeTable(opts Opts, e *eventspb.BusEvent) (*typeTable, error) {
+	m := e.ProtoReflect()
+	oneof := m.Descriptor().Oneofs().ByName("event")
+	if oneof == nil {
+		return nil, nil
+	}
+	payload := m.WhichOneof(oneof)
+	if payload == nil {
+		return nil, nil
+	}
+
+	columns := append([]column{}, eventColumns...)
+	if payload.Kind() == protoreflect.MessageKind {
+		columns = append(columns, flatten(rowDescriptor(payload.Message()), "", nil)...)
+	} else {
+		columns = append(columns, column{name: string(payload.Name()), path: []protoreflect.FieldDescriptor{payload}, parquet: parquetType(payload)})
+	}
+
+	name := strings.ToLower(strings.TrimPrefix(e.Type.String(), "BUS_EVENT_TYPE_"))
+	path := filepath.Join(opts.OutDir, name+"."+opts.Format)
+	t, err := createTable(path, opts.Format, columns)
+	if err != nil {
+		return nil, err
+	}
+
+	return &typeTable{
+		path:    path,
+		table:   t,
+		payload: payload,
+		columns: columns,
+		row:     make([]*string, len(columns)),
+	}, nil
+}
+
+func (t *typeTable) write(e *eventspb.BusEvent, vegaTime int64) error {
+	var block, seq *string
+	if height, ok := eventfile.BlockHeight(e); ok {
+		s := strconv.FormatUint(height, 10)
+		block = &s
+	}
+	if i := strings.IndexByte(e.Id, '-'); i >= 0 {
+		s := e.Id[i+1:]
+		seq = &s
+	}
+	t.row[0], t.row[1] = block, seq
+	t.row[2] = nil
+	if vegaTime > 0 {
+		s := strconv.FormatInt(vegaTime, 10)
+		t.row[2] = &s
+	}
+	t.row[3] = nil
+	if len(e.TxHash) > 0 {
+		s := e.TxHash
+		t.row[3] = &s
+	}
+
+	m := e.ProtoReflect()
+	fields := t.columns[len(eventColumns):]
+	if t.payload.Kind() != protoreflect.MessageKind {
+		t.row[len(eventColumns)] = fields[0].value(m)
+		return t.writeRow()
+	}
+
+	for _, r := range rowMessages(m.Get(t.payload).Message()) {
+		for i, c := range fields {
+			t.row[len(eventColumns)+i] = c.value(r)
+		}
+		if err := t.writeRow(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *typeTable) writeRow() error {
+	t.rows++
+	return t.table.write(t.row)
+}
@@ -0,0 +1,178 @@
+package evtexport
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"code.vegaprotocol.io/vega/protos/vega"
+	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
+	"code.vegaprotocol.io/vegatools/eventfile"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeEvents writes blocks 1 to 3, each with a time update and an order.
+func writeEvents(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "events.evt")
+	w, err := eventfile.Create(path, eventfile.WriterOpts{})
+	require.NoError(t, err)
+	for h := uint64(1); h <= 3; h++ {
+		events := []*eventspb.BusEvent{
+			{Type: eventspb.BusEventType_BUS_EVENT_TYPE_TIME_UPDATE, Event: &eventspb.BusEvent_TimeUpdate{
+				TimeUpdate: &eventspb.TimeUpdate{Timestamp: int64(h) * 1000},
+			}},
+			{Type: eventspb.BusEventType_BUS_EVENT_TYPE_ORDER, TxHash: fmt.Sprintf("tx%d", h), Event: &eventspb.BusEvent_Order{
+				Order: &vega.Order{Id: fmt.Sprintf("o%d", h), Price: "10", Size: h, Status: vega.Order_STATUS_ACTIVE},
+			}},
+		}
+		for i, e := range events {
+			e.Id, e.Block = fmt.Sprintf("%d-%d", h, i), fmt.Sprintf("block-%d", h)
+			require.NoError(t, w.Write(e))
+		}
+	}
+	require.NoError(t, w.Close())
+	return path
+}
+
+// readCSV returns the rows of a CSV table as maps of column name to value.
+func readCSV(t *testing.T, path string) []map[string]string {
+	t.Helper()
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	require.NoError(t, err)
+	require.NotEmpty(t, records)
+
+	rows := []map[string]string{}
+	for _, record := range records[1:] {
+		row := map[string]string{}
+		for i, name := range records[0] {
+			row[name] = record[i]
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func TestExportCSV(t *testing.T) {
+	outDir := t.TempDir()
+	require.NoError(t, Run(Opts{File: writeEvents(t), OutDir: outDir, Format: FormatCSV, Types: []string{"order"}, FromBlock: 2}))
+
+	// time updates were not exported but give every row the time of its block
+	_, err := os.Stat(filepath.Join(outDir, "time_update.csv"))
+	assert.True(t, os.IsNotExist(err))
+
+	orders := readCSV(t, filepath.Join(outDir, "order.csv"))
+	require.Len(t, orders, 2)
+	assert.Equal(t, "2", orders[0]["block"])
+	assert.Equal(t, "1", orders[0]["seq"])
+	assert.Equal(t, "2000", orders[0]["vega_time"])
+	assert.Equal(t, "tx2", orders[0]["tx_hash"])
+	assert.Equal(t, "o2", orders[0]["id"])
+	assert.Equal(t, "2", orders[0]["size"])
+	assert.Equal(t, "STATUS_ACTIVE", orders[0]["status"])
+	assert.Equal(t, "o3", orders[1]["id"])
+
+}
+
+func TestExportTimeOfBlockStart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.evt")
+	w, err := eventfile.Create(path, eventfile.WriterOpts{})
+	require.NoError(t, err)
+	for h := uint64(1); h <= 2; h++ {
+		events := []*eventspb.BusEvent{
+			{Type: eventspb.BusEventType_BUS_EVENT_TYPE_BEGIN_BLOCK, Event: &eventspb.BusEvent_BeginBlock{
+				BeginBlock: &eventspb.BeginBlock{Height: h, Timestamp: int64(h) * 1000},
+			}},
+			{Type: eventspb.BusEventType_BUS_EVENT_TYPE_TIME_UPDATE, Event: &eventspb.BusEvent_TimeUpdate{
+				TimeUpdate: &eventspb.TimeUpdate{Timestamp: int64(h) * 1000},
+			}},
+		}
+		for i, e := range events {
+			e.Id, e.Block = fmt.Sprintf("%d-%d", h, i), fmt.Sprintf("block-%d", h)
+			require.NoError(t, w.Write(e))
+		}
+	}
+	require.NoError(t, w.Close())
+
+	outDir := t.TempDir()
+	require.NoError(t, Run(Opts{File: path, OutDir: outDir, Format: FormatCSV, Types: []string{"begin_block"}}))
+
+	// the events before the time update of a block have its time too
+	blocks := readCSV(t, filepath.Join(outDir, "begin_block.csv"))
+	require.Len(t, blocks, 2)
+	assert.Equal(t, "1000", blocks[0]["vega_time"])
+	assert.Equal(t, "2000", blocks[1]["vega_time"])
+}
+
+func TestExportBlockRange(t *testing.T) {
+	outDir := t.TempDir()
+	require.NoError(t, Run(Opts{File: writeEvents(t), OutDir: outDir, Format: FormatCSV, Types: []string{"order"}, ToBlock: 1}))
+	orders := readCSV(t, filepath.Join(outDir, "order.csv"))
+	require.Len(t, orders, 1)
+	assert.Equal(t, "o1", orders[0]["id"])
+
+	err := Run(Opts{File: writeEvents(t), OutDir: outDir, Format: FormatCSV, FromBlock: 4})
+	assert.ErrorContains(t, err, "block 4 not found")
+}
+
+func TestExportParquet(t *testing.T) {
+	outDir := t.TempDir()
+	require.NoError(t, Run(Opts{File: writeEvents(t), OutDir: outDir, Format: FormatParquet, Types: []string{"order"}}))
+
+	b, err := os.ReadFile(filepath.Join(outDir, "order.parquet"))
+	require.NoError(t, err)
+	require.Greater(t, len(b), 8)
+	assert.Equal(t, "PAR1", string(b[:4]))
+	assert.Equal(t, "PAR1", string(b[len(b)-4:]))
+}
+
+func TestParquetTableFlush(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "t.parquet"))
+	require.NoError(t, err)
+	table, err := newParquetTable(f, []column{{name: "id", parquet: parquetString}})
+	require.NoError(t, err)
+
+	id := "o1"
+	require.NoError(t, table.write([]*string{&id}))
+	assert.Positive(t, table.buffered())
+	require.NoError(t, table.flush())
+	assert.Zero(t, table.buffered())
+	require.NoError(t, table.close())
+}
+
+func TestUnsupportedFormat(t *testing.T) {
+	err := Run(Opts{File: writeEvents(t), OutDir: t.TempDir(), Format: "xlsx"})
+	assert.ErrorContains(t, err, `unsupported format "xlsx", allowed values: parquet, csv`)
+}
+
+func TestFlattenNestedMessages(t *testing.T) {
+	market := &vega.Market{Id: "m1", TradableInstrument: &vega.TradableInstrument{
+		Instrument: &vega.Instrument{Id: "i1", Product: &vega.Instrument_Future{Future: &vega.Future{SettlementAsset: "usd"}}},
+	}}
+	columns := map[string]column{}
+	for _, c := range flatten(market.ProtoReflect().Descriptor(), "", nil) {
+		columns[c.name] = c
+	}
+
+	value := func(name string) *string {
+		c, ok := columns[name]
+		require.True(t, ok, name)
+		return c.value(market.ProtoReflect())
+	}
+	assert.Equal(t, "m1", *value("id"))
+	assert.Equal(t, "i1", *value("tradable_instrument_instrument_id"))
+	// messages deeper than maxDepth are written as JSON, and are missing when not set
+	assert.True(t, columns["tradable_instrument_instrument_future"].json)
+	assert.JSONEq(t, `{"settlementAsset":"usd"}`, *value("tradable_instrument_instrument_future"))
+	assert.Nil(t, value("tradable_instrument_instrument_perpetual"))
+
+	market.TradableInstrument = nil
+	assert.Nil(t, value("tradable_instrument_instrument_id"))
+}
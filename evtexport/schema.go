@@ -0,0 +1,192 @@
+package evtexport
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// maxDepth is how deep nested messages are flattened into columns, deeper messages are written as JSON.
+const maxDepth = 3
+
+// column is a single field of an event, reached from the root message of a row through path.
+type column struct {
+	name string
+	path []protoreflect.FieldDescriptor
+	// parquet is the parquet schema of the column, without its name
+	parquet string
+	// json is set for lists, maps and messages that are written as JSON
+	json bool
+}
+
+const (
+	parquetString = "type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"
+	parquetInt64  = "type=INT64, repetitiontype=OPTIONAL"
+	parquetUint64 = "type=INT64, convertedtype=UINT_64, repetitiontype=OPTIONAL"
+)
+
+// eventColumns are written for every event type ahead of the fields of the event.
+var eventColumns = []column{
+	{name: "block", parquet: parquetUint64},
+	{name: "seq", parquet: parquetUint64},
+	{name: "vega_time", parquet: parquetInt64},
+	{name: "tx_hash", parquet: parquetString},
+}
+
+// rowMessages returns the messages that each become a row of the table. An event that only wraps a list of
+// messages, such as ledger movements, becomes a row per element.
+func rowMessages(payload protoreflect.Message) []protoreflect.Message {
+	fields := payload.Descriptor().Fields()
+	if fields.Len() != 1 || !fields.Get(0).IsList() || fields.Get(0).Kind() != protoreflect.MessageKind {
+		return []protoreflect.Message{payload}
+	}
+
+	l := payload.Get(fields.Get(0)).List()
+	rows := make([]protoreflect.Message, 0, l.Len())
+	for i := 0; i < l.Len(); i++ {
+		rows = append(rows, l.Get(i).Message())
+	}
+	return rows
+}
+
+// rowDescriptor returns the descriptor of the messages returned by rowMessages.
+func rowDescriptor(payload protoreflect.MessageDescriptor) protoreflect.MessageDescriptor {
+	fields := payload.Fields()
+	if fields.Len() != 1 || !fields.Get(0).IsList() || fields.Get(0).Kind() != protoreflect.MessageKind {
+		return payload
+	}
+	return fields.Get(0).Message()
+}
+
+// flatten returns a column for every field of md, nested messages are flattened into their fields up to maxDepth.
+func flatten(md protoreflect.MessageDescriptor, prefix string, path []protoreflect.FieldDescriptor) []column {
+	columns := []column{}
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		name := prefix + string(fd.Name())
+		fdPath := append(append([]protoreflect.FieldDescriptor{}, path...), fd)
+
+		switch {
+		case fd.IsList() || fd.IsMap():
+			columns = append(columns, column{name: name, path: fdPath, parquet: parquetString, json: true})
+		case fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind:
+			if len(fdPath) < maxDepth {
+				columns = append(columns, flatten(fd.Message(), name+"_", fdPath)...)
+			} else {
+				columns = append(columns, column{name: name, path: fdPath, parquet: parquetString, json: true})
+			}
+		default:
+			columns = append(columns, column{name: name, path: fdPath, parquet: parquetType(fd)})
+		}
+	}
+	return columns
+}
+
+func parquetType(fd protoreflect.FieldDescriptor) string {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return "type=BOOLEAN, repetitiontype=OPTIONAL"
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return "type=INT32, repetitiontype=OPTIONAL"
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return parquetInt64
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return parquetUint64
+	case protoreflect.FloatKind:
+		return "type=FLOAT, repetitiontype=OPTIONAL"
+	case protoreflect.DoubleKind:
+		return "type=DOUBLE, repetitiontype=OPTIONAL"
+	default:
+		// strings, enum names and hex encoded bytes
+		return parquetString
+	}
+}
+
+// value returns the column of a row as a string, or nil if the field, or a message holding it, is not set.
+func (c column) value(m protoreflect.Message) *string {
+	for _, fd := range c.path[:len(c.path)-1] {
+		if !m.Has(fd) {
+			return nil
+		}
+		m = m.Get(fd).Message()
+	}
+
+	fd := c.path[len(c.path)-1]
+	if fd.HasPresence() && !m.Has(fd) {
+		return nil
+	}
+
+	var s string
+	if c.json {
+		s = jsonValue(fd, m.Get(fd))
+	} else {
+		s = scalarString(fd, m.Get(fd))
+	}
+	return &s
+}
+
+func scalarString(fd protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return strconv.Itoa(int(v.Enum()))
+	case protoreflect.BytesKind:
+		return hex.EncodeToString(v.Bytes())
+	case protoreflect.FloatKind:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 32)
+	case protoreflect.DoubleKind:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	default:
+		return v.String()
+	}
+}
+
+func jsonValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	switch {
+	case fd.IsList():
+		l := v.List()
+		items := make([]string, 0, l.Len())
+		for i := 0; i < l.Len(); i++ {
+			items = append(items, elementJSON(fd, l.Get(i)))
+		}
+		return "[" + strings.Join(items, ",") + "]"
+	case fd.IsMap():
+		items := []string{}
+		v.Map().Range(func(k protoreflect.MapKey, mv protoreflect.Value) bool {
+			key, _ := json.Marshal(k.String())
+			items = append(items, string(key)+":"+elementJSON(fd.MapValue(), mv))
+			return true
+		})
+		return "{" + strings.Join(items, ",") + "}"
+	default:
+		return elementJSON(fd, v)
+	}
+}
+
+// elementJSON encodes a single message or scalar value.
+func elementJSON(fd protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	var (
+		b   []byte
+		err error
+	)
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		b, err = protojson.Marshal(v.Message().Interface())
+	case protoreflect.StringKind, protoreflect.EnumKind, protoreflect.BytesKind:
+		b, err = json.Marshal(scalarString(fd, v))
+	default:
+		return scalarString(fd, v)
+	}
+	if err != nil {
+		return "null"
+	}
+	return string(b)
+}
@@ -0,0 +1,146 @@
+package evtexport
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"os"
+
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// Supported export formats.
+const (
+	FormatParquet = "parquet"
+	FormatCSV     = "csv"
+)
+
+const (
+	// parquetRowGroupSize bounds the rows of a single table buffered in memory before they are written out.
+	parquetRowGroupSize = 32 * 1024 * 1024
+	// maxBufferedBytes bounds the rows buffered by all the tables together, once reached every table writes out its
+	// row group.
+	maxBufferedBytes = 256 * 1024 * 1024
+)
+
+// table is the output file of a single event type, rows hold a string, or nil, for every column.
+type table interface {
+	write(row []*string) error
+	// buffered returns the size of the rows held in memory and not yet written out.
+	buffered() int64
+	// flush writes out the rows held in memory.
+	flush() error
+	close() error
+}
+
+func createTable(path, format string, columns []column) (table, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create file %s: %w", path, err)
+	}
+
+	switch format {
+	case FormatCSV:
+		return newCSVTable(f, columns)
+	case FormatParquet:
+		return newParquetTable(f, columns)
+	default:
+		f.Close()
+		return nil, fmt.Errorf("unsupported format %q, allowed values: parquet, csv", format)
+	}
+}
+
+type csvTable struct {
+	file   *os.File
+	buf    *bufio.Writer
+	w      *csv.Writer
+	record []string
+}
+
+func newCSVTable(f *os.File, columns []column) (*csvTable, error) {
+	t := &csvTable{file: f, buf: bufio.NewWriter(f), record: make([]string, len(columns))}
+	t.w = csv.NewWriter(t.buf)
+
+	for i, c := range columns {
+		t.record[i] = c.name
+	}
+	if err := t.w.Write(t.record); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to write header: %w", err)
+	}
+	return t, nil
+}
+
+func (t *csvTable) write(row []*string) error {
+	for i, v := range row {
+		t.record[i] = ""
+		if v != nil {
+			t.record[i] = *v
+		}
+	}
+	return t.w.Write(t.record)
+}
+
+// buffered returns 0 as rows are written through a fixed size buffer.
+func (t *csvTable) buffered() int64 {
+	return 0
+}
+
+func (t *csvTable) flush() error {
+	return nil
+}
+
+func (t *csvTable) close() error {
+	t.w.Flush()
+	err := t.w.Error()
+	if ferr := t.buf.Flush(); err == nil {
+		err = ferr
+	}
+	if cerr := t.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+type parquetTable struct {
+	file *os.File
+	w    *writer.CSVWriter
+}
+
+func newParquetTable(f *os.File, columns []column) (*parquetTable, error) {
+	schema := make([]string, 0, len(columns))
+	for _, c := range columns {
+		schema = append(schema, fmt.Sprintf("name=%s, %s", c.name, c.parquet))
+	}
+
+	w, err := writer.NewCSVWriterFromWriter(schema, f, 1)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to create parquet writer: %w", err)
+	}
+	w.RowGroupSize = parquetRowGroupSize
+	w.CompressionType = parquet.CompressionCodec_ZSTD
+
+	return &parquetTable{file: f, w: w}, nil
+}
+
+func (t *parquetTable) write(row []*string) error {
+	return t.w.WriteString(row)
+}
+
+func (t *parquetTable) buffered() int64 {
+	return t.w.Size + t.w.ObjsSize
+}
+
+func (t *parquetTable) flush() error {
+	return t.w.Flush(true)
+}
+
+func (t *parquetTable) close() error {
+	err := t.w.WriteStop()
+	if cerr := t.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
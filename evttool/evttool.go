@@ -0,0 +1,107 @@
+package evttool
+
+import (
+	"fmt"
+	"io"
+
+	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
+	"code.vegaprotocol.io/vegatools/eventfile"
+)
+
+// blockRange is an inclusive range of block heights.
+type blockRange struct {
+	from, to uint64
+}
+
+func (r blockRange) String() string {
+	if r.from == r.to {
+		return fmt.Sprintf("%d", r.from)
+	}
+	return fmt.Sprintf("%d-%d", r.from, r.to)
+}
+
+// blockCheck tracks the sequence of blocks seen in an event file and records any that are missing or out of order.
+type blockCheck struct {
+	started    bool
+	current    uint64
+	first      uint64
+	last       uint64
+	blocks     uint64
+	gaps       []blockRange
+	outOfOrder []blockRange
+}
+
+// add records the block of an event, further events from the same block are ignored.
+func (c *blockCheck) add(height uint64) {
+	if c.started && height == c.current {
+		return
+	}
+
+	c.blocks++
+	if !c.started {
+		c.started = true
+		c.current, c.first, c.last = height, height, height
+		return
+	}
+
+	switch {
+	case height > c.last+1:
+		c.gaps = append(c.gaps, blockRange{from: c.last + 1, to: height - 1})
+	case height <= c.last:
+		c.outOfOrder = append(c.outOfOrder, blockRange{from: c.current, to: height})
+	}
+	c.current = height
+	if height > c.last {
+		c.last = height
+	}
+}
+
+func (c *blockCheck) contiguous() bool {
+	return len(c.gaps) == 0 && len(c.outOfOrder) == 0
+}
+
+func (c *blockCheck) print() {
+	if !c.started {
+		fmt.Println("no blocks found")
+		return
+	}
+
+	fmt.Printf("blocks %d-%d, %d blocks\n", c.first, c.last, c.blocks)
+	for _, g := range c.gaps {
+		fmt.Printf("missing blocks %s\n", g)
+	}
+	for _, o := range c.outOfOrder {
+		fmt.Printf("block %d follows block %d\n", o.to, o.from)
+	}
+	if c.contiguous() {
+		fmt.Println("blocks are contiguous")
+	}
+}
+
+// forEachEvent calls fn with every event in the file and the height of the block it belongs to. Returning io.EOF
+// from fn stops the iteration without an error.
+func forEachEvent(evtFile *eventfile.Reader, fn func(e *eventspb.BusEvent, height uint64) error) error {
+	var current uint64
+	for {
+		e, err := evtFile.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		height, ok := eventfile.BlockHeight(e)
+		if !ok {
+			height = current
+		}
+		current = height
+
+		if err := fn(e, height); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
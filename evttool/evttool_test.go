@@ -0,0 +1,153 @@
+package evttool
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"code.vegaprotocol.io/vega/protos/vega"
+	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
+	"code.vegaprotocol.io/vegatools/eventfile"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockEvents returns the events of the blocks from one height to another, inclusive: a time update and an order of
+// party-1 in odd blocks or party-2 in even ones.
+func blockEvents(from, to uint64) []*eventspb.BusEvent {
+	events := []*eventspb.BusEvent{}
+	for h := from; h <= to; h++ {
+		block := fmt.Sprintf("block-%d", h)
+		events = append(events,
+			&eventspb.BusEvent{Id: fmt.Sprintf("%d-0", h), Block: block, Type: eventspb.BusEventType_BUS_EVENT_TYPE_TIME_UPDATE, Event: &eventspb.BusEvent_TimeUpdate{
+				TimeUpdate: &eventspb.TimeUpdate{Timestamp: int64(h)},
+			}},
+			&eventspb.BusEvent{Id: fmt.Sprintf("%d-1", h), Block: block, Type: eventspb.BusEventType_BUS_EVENT_TYPE_ORDER, Event: &eventspb.BusEvent_Order{
+				Order: &vega.Order{Id: fmt.Sprintf("o%d", h), PartyId: fmt.Sprintf("party-%d", 2-h%2), MarketId: "m1"},
+			}},
+		)
+	}
+	return events
+}
+
+func writeEvents(t *testing.T, path string, events []*eventspb.BusEvent) string {
+	t.Helper()
+	w, err := eventfile.Create(path, eventfile.WriterOpts{})
+	require.NoError(t, err)
+	for _, e := range events {
+		require.NoError(t, w.Write(e))
+	}
+	require.NoError(t, w.Close())
+	return path
+}
+
+func readEventIDs(t *testing.T, path string) []string {
+	t.Helper()
+	r, err := eventfile.Open(path)
+	require.NoError(t, err)
+	defer r.Close()
+	ids := []string{}
+	require.NoError(t, forEachEvent(r, func(e *eventspb.BusEvent, _ uint64) error {
+		ids = append(ids, e.Id)
+		return nil
+	}))
+	return ids
+}
+
+func TestSlice(t *testing.T) {
+	dir := t.TempDir()
+	in := writeEvents(t, filepath.Join(dir, "in.evt"), blockEvents(1, 6))
+
+	out := filepath.Join(dir, "range.evt")
+	require.NoError(t, Slice(SliceOpts{File: in, Out: out, FromBlock: 2, ToBlock: 4}))
+	assert.Equal(t, []string{"2-0", "2-1", "3-0", "3-1", "4-0", "4-1"}, readEventIDs(t, out))
+
+	out = filepath.Join(dir, "party.evt")
+	require.NoError(t, Slice(SliceOpts{File: in, Out: out, Types: []string{"order"}, Party: "party-2"}))
+	assert.Equal(t, []string{"2-1", "4-1", "6-1"}, readEventIDs(t, out))
+
+	err := Slice(SliceOpts{File: in, Out: filepath.Join(dir, "none.evt"), FromBlock: 7})
+	assert.EqualError(t, err, fmt.Sprintf("block 7 not found in %s", in))
+	err = Slice(SliceOpts{File: in, Out: filepath.Join(dir, "none.evt"), Types: []string{"orders"}})
+	assert.EqualError(t, err, "unknown event type BUS_EVENT_TYPE_ORDERS")
+}
+
+func TestSplitByBlocks(t *testing.T) {
+	dir := t.TempDir()
+	in := writeEvents(t, filepath.Join(dir, "events.evt"), blockEvents(1, 5))
+	outDir := filepath.Join(dir, "out")
+
+	require.NoError(t, Split(SplitOpts{File: in, OutDir: outDir, Blocks: 2}))
+	assert.Equal(t, []string{"1-0", "1-1", "2-0", "2-1"}, readEventIDs(t, filepath.Join(outDir, "events-000000.evt")))
+	assert.Equal(t, []string{"5-0", "5-1"}, readEventIDs(t, filepath.Join(outDir, "events-000002.evt")))
+	assert.Len(t, readEventIDs(t, outDir), 10)
+}
+
+func TestSplitByType(t *testing.T) {
+	dir := t.TempDir()
+	in := writeEvents(t, filepath.Join(dir, "events.evt"), blockEvents(1, 2))
+	outDir := filepath.Join(dir, "out")
+
+	require.NoError(t, Split(SplitOpts{File: in, OutDir: outDir, ByType: true}))
+	assert.Equal(t, []string{"1-1", "2-1"}, readEventIDs(t, filepath.Join(outDir, "events-order.evt")))
+	assert.Equal(t, []string{"1-0", "2-0"}, readEventIDs(t, filepath.Join(outDir, "events-time_update.evt")))
+
+	assert.EqualError(t, Split(SplitOpts{File: in, OutDir: outDir}), "exactly one of blocks or by-type must be given")
+}
+
+func TestMergeOverlappingCaptures(t *testing.T) {
+	dir := t.TempDir()
+	// the second capture reconnected part way through block 3, the files are given out of order
+	first := writeEvents(t, filepath.Join(dir, "first.evt"), blockEvents(1, 3)[:5])
+	second := writeEvents(t, filepath.Join(dir, "second.evt"), blockEvents(2, 5))
+	out := filepath.Join(dir, "merged.evt")
+
+	require.NoError(t, Merge(MergeOpts{Files: []string{second, first}, Out: out}))
+	assert.Equal(t, eventIDs(blockEvents(1, 5)), readEventIDs(t, out))
+	require.NoError(t, Validate(ValidateOpts{File: out}))
+}
+
+func TestMergeRejectsDifferentBlocks(t *testing.T) {
+	dir := t.TempDir()
+	first := writeEvents(t, filepath.Join(dir, "first.evt"), blockEvents(1, 2))
+	forked := blockEvents(2, 3)
+	for _, e := range forked {
+		e.Block = "fork-" + e.Block
+	}
+	second := writeEvents(t, filepath.Join(dir, "second.evt"), forked)
+
+	err := Merge(MergeOpts{Files: []string{first, second}, Out: filepath.Join(dir, "merged.evt")})
+	assert.EqualError(t, err, fmt.Sprintf("block 2 in %s does not match the block already merged (fork-block-2 != block-2)", second))
+}
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	gap := append(blockEvents(1, 2), blockEvents(4, 4)...)
+	path := writeEvents(t, filepath.Join(dir, "gap.evt"), gap)
+	assert.EqualError(t, Validate(ValidateOpts{File: path}), path+" is not a contiguous sequence of blocks")
+
+	duplicate := append(blockEvents(1, 2), blockEvents(2, 2)[1])
+	path = writeEvents(t, filepath.Join(dir, "duplicate.evt"), duplicate)
+	assert.EqualError(t, Validate(ValidateOpts{File: path}), path+" is not a contiguous sequence of blocks")
+}
+
+func TestBlockCheck(t *testing.T) {
+	var c blockCheck
+	for _, h := range []uint64{3, 3, 4, 7, 5} {
+		c.add(h)
+	}
+	assert.Equal(t, uint64(4), c.blocks)
+	assert.Equal(t, []blockRange{{from: 5, to: 6}}, c.gaps)
+	assert.Equal(t, []blockRange{{from: 7, to: 5}}, c.outOfOrder)
+	assert.False(t, c.contiguous())
+	assert.Equal(t, "5-6", c.gaps[0].String())
+}
+
+func eventIDs(events []*eventspb.BusEvent) []string {
+	ids := make([]string, 0, len(events))
+	for _, e := range events {
+		ids = append(ids, e.Id)
+	}
+	return ids
+}
@@ -0,0 +1,107 @@
+package evttool
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
+	"code.vegaprotocol.io/vegatools/eventfile"
+)
+
+// MergeOpts are the command line options passed to the merge sub command
+type MergeOpts struct {
+	Files       []string
+	Out         string
+	Compression string
+}
+
+type mergeInput struct {
+	path       string
+	firstBlock uint64
+}
+
+// Merge concatenates events files in block order. Blocks already written from an earlier file are skipped and events
+// of a block split across files are de-duplicated by ID, so the captures of reconnecting runs can be joined up.
+func Merge(opts MergeOpts) error {
+	inputs := make([]mergeInput, 0, len(opts.Files))
+	for _, path := range opts.Files {
+		first, err := firstBlock(path)
+		if err != nil {
+			return err
+		}
+		inputs = append(inputs, mergeInput{path: path, firstBlock: first})
+	}
+	sort.SliceStable(inputs, func(i, j int) bool { return inputs[i].firstBlock < inputs[j].firstBlock })
+
+	out, err := eventfile.Create(opts.Out, eventfile.WriterOpts{Compression: opts.Compression})
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	var (
+		check      blockCheck
+		started    bool
+		lastHeight uint64
+		blockHash  string
+		blockIDs   map[string]struct{}
+		written    uint64
+		skipped    uint64
+	)
+	for _, input := range inputs {
+		in, err := eventfile.Open(input.path)
+		if err != nil {
+			return err
+		}
+
+		err = forEachEvent(in, func(e *eventspb.BusEvent, height uint64) error {
+			switch {
+			case started && height < lastHeight:
+				skipped++
+				return nil
+			case !started || height > lastHeight:
+				started, lastHeight, blockHash = true, height, e.Block
+				blockIDs = map[string]struct{}{}
+			case e.Block != blockHash:
+				return fmt.Errorf("block %d in %s does not match the block already merged (%s != %s)", height, input.path, e.Block, blockHash)
+			}
+
+			if _, ok := blockIDs[e.Id]; ok {
+				skipped++
+				return nil
+			}
+			blockIDs[e.Id] = struct{}{}
+
+			check.add(height)
+			written++
+			return out.Write(e)
+		})
+		in.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("%d events written to %s, %d duplicate events skipped\n", written, out.Path(), skipped)
+	check.print()
+	return nil
+}
+
+func firstBlock(path string) (uint64, error) {
+	in, err := eventfile.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	e, err := in.Next()
+	if err == io.EOF {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	height, _ := eventfile.BlockHeight(e)
+	return height, nil
+}
@@ -0,0 +1,37 @@
+package evttool
+
+import (
+	"fmt"
+
+	"code.vegaprotocol.io/vegatools/eventfile"
+)
+
+// RepairOpts are the command line options passed to the repair sub command
+type RepairOpts struct {
+	File string
+}
+
+// Repair removes corrupt and partially written records from an events file so that it can be read to the end.
+func Repair(opts RepairOpts) error {
+	res, err := eventfile.Repair(opts.File)
+	if err != nil {
+		return err
+	}
+
+	if !res.Changed() {
+		fmt.Printf("%s has no corrupt records\n", opts.File)
+		return nil
+	}
+	if res.Dropped > 0 {
+		fmt.Printf("dropped %d corrupt records\n", res.Dropped)
+	}
+	if res.TruncatedAt >= 0 {
+		fmt.Printf("removed incomplete record at offset %d\n", res.TruncatedAt)
+	}
+	if res.Rewritten {
+		fmt.Printf("rewrote %s\n", opts.File)
+	} else {
+		fmt.Printf("truncated %s\n", opts.File)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package evttool
+
+import (
+	"fmt"
+	"io"
+
+	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
+	"code.vegaprotocol.io/vegatools/eventfile"
+)
+
+// SliceOpts are the command line options passed to the slice sub command
+type SliceOpts struct {
+	File        string
+	Out         string
+	Compression string
+	FromBlock   uint64
+	ToBlock     uint64
+	Types       []string
+	Party       string
+	Market      string
+}
+
+// Slice copies the events matching the options into a new events file.
+func Slice(opts SliceOpts) error {
+	types, err := eventfile.ParseTypes(opts.Types)
+	if err != nil {
+		return err
+	}
+	filter := eventfile.Filter{
+		Types:      types,
+		Party:      opts.Party,
+		Market:     opts.Market,
+		FromHeight: opts.FromBlock,
+		ToHeight:   opts.ToBlock,
+	}
+
+	in, err := eventfile.Open(opts.File)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if opts.FromBlock > 0 {
+		if err := in.SeekHeight(opts.FromBlock); err != nil {
+			if err == io.EOF {
+				return fmt.Errorf("block %d not found in %s", opts.FromBlock, opts.File)
+			}
+			return err
+		}
+	}
+
+	out, err := eventfile.Create(opts.Out, eventfile.WriterOpts{Compression: opts.Compression})
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	var (
+		check  blockCheck
+		events uint64
+	)
+	err = forEachEvent(in, func(e *eventspb.BusEvent, height uint64) error {
+		if filter.PastEnd(e) {
+			return io.EOF
+		}
+		if !filter.Match(e) {
+			return nil
+		}
+		check.add(height)
+		events++
+		return out.Write(e)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d events written to %s\n", events, out.Path())
+	check.print()
+	return nil
+}
@@ -0,0 +1,86 @@
+package evttool
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
+	"code.vegaprotocol.io/vegatools/eventfile"
+)
+
+// SplitOpts are the command line options passed to the split sub command
+type SplitOpts struct {
+	File        string
+	OutDir      string
+	Compression string
+	Blocks      uint64
+	ByType      bool
+}
+
+// Split divides an events file into files holding a fixed number of blocks each, or into one file per event type.
+func Split(opts SplitOpts) error {
+	if (opts.Blocks > 0) == opts.ByType {
+		return errors.New("exactly one of blocks or by-type must be given")
+	}
+
+	if err := os.MkdirAll(opts.OutDir, 0o755); err != nil {
+		return fmt.Errorf("unable to create directory %s: %w", opts.OutDir, err)
+	}
+
+	in, err := eventfile.Open(opts.File)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	name := filepath.Base(opts.File)
+	if i := strings.Index(name, ".evt"); i > 0 {
+		name = name[:i]
+	}
+
+	if opts.Blocks > 0 {
+		out, err := eventfile.Create(filepath.Join(opts.OutDir, name+".evt"), eventfile.WriterOpts{
+			Compression: opts.Compression,
+			MaxBlocks:   opts.Blocks,
+		})
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+
+		return forEachEvent(in, func(e *eventspb.BusEvent, _ uint64) error {
+			return out.Write(e)
+		})
+	}
+
+	outs := map[eventspb.BusEventType]*eventfile.Writer{}
+	defer func() {
+		for _, out := range outs {
+			out.Close()
+		}
+	}()
+
+	err = forEachEvent(in, func(e *eventspb.BusEvent, _ uint64) error {
+		out, ok := outs[e.Type]
+		if !ok {
+			typeName := strings.ToLower(strings.TrimPrefix(e.Type.String(), "BUS_EVENT_TYPE_"))
+			path := filepath.Join(opts.OutDir, fmt.Sprintf("%s-%s.evt", name, typeName))
+			if out, err = eventfile.Create(path, eventfile.WriterOpts{Compression: opts.Compression}); err != nil {
+				return err
+			}
+			outs[e.Type] = out
+		}
+		return out.Write(e)
+	})
+	if err != nil {
+		return err
+	}
+
+	for t, out := range outs {
+		fmt.Printf("%s written to %s\n", t, out.Path())
+	}
+	return nil
+}
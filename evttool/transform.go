@@ -0,0 +1,267 @@
+package evttool
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
+	"code.vegaprotocol.io/vegatools/eventfile"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// TransformOpts are the command line options passed to the transform sub command
+type TransformOpts struct {
+	File        string
+	Out         string
+	Compression string
+	// Pseudonymise replaces party IDs and public keys with a keyed hash of them, Salt is the key
+	Pseudonymise bool
+	Salt         string
+	DropTypes    []string
+	// MarketMap maps market IDs to the IDs they are replaced with
+	MarketMap map[string]string
+}
+
+// partyFields are the names of the fields holding a party ID or public key.
+var partyFields = map[string]struct{}{
+	"party":        {},
+	"party_id":     {},
+	"parties":      {},
+	"party_ids":    {},
+	"pub_key":      {},
+	"vega_pub_key": {},
+	"new_pub_key":  {},
+	"old_pub_key":  {},
+	"owner":        {},
+	"proposer":     {},
+	"buyer":        {},
+	"seller":       {},
+	"from":         {},
+	"to":           {},
+	"submitter":    {},
+	"delegator":    {},
+	"referrer":     {},
+	"referee":      {},
+}
+
+// transform rewrites an event in place, returning false if the event is to be dropped.
+type transform func(e *eventspb.BusEvent) bool
+
+// Transform rewrites an events file through the transforms selected in the options: dropping event types, remapping
+// market IDs and pseudonymising parties. Party IDs and public keys are collected from the fields naming them in a first
+// pass over the file, every field holding one of them is then replaced with an HMAC-SHA256 of the ID keyed with the
+// salt. The same salt always gives the same pseudonyms, so files transformed separately can still be compared. Market
+// IDs are only remapped in fields holding exactly the old ID, and each field is replaced at most once, so the result
+// does not depend on the order of the mappings.
+func Transform(opts TransformOpts) error {
+	if opts.Pseudonymise && len(opts.Salt) == 0 {
+		return fmt.Errorf("a salt is required to pseudonymise parties")
+	}
+
+	var transforms []transform
+	if len(opts.DropTypes) > 0 {
+		types, err := eventfile.ParseTypes(opts.DropTypes)
+		if err != nil {
+			return err
+		}
+		transforms = append(transforms, dropTypes(types))
+	}
+
+	replacements := map[string]string{}
+	for from, to := range opts.MarketMap {
+		replacements[from] = to
+	}
+	if opts.Pseudonymise {
+		parties, err := collectParties(opts.File)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("pseudonymising %d parties\n", len(parties))
+		for _, p := range parties {
+			if _, ok := replacements[p]; !ok {
+				replacements[p] = pseudonym(opts.Salt, p)
+			}
+		}
+	}
+	if len(replacements) > 0 {
+		transforms = append(transforms, replaceIDs(replacements))
+	}
+
+	if len(transforms) == 0 {
+		return fmt.Errorf("no transforms selected")
+	}
+
+	in, err := eventfile.Open(opts.File)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := eventfile.Create(opts.Out, eventfile.WriterOpts{Compression: opts.Compression})
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	var written, dropped uint64
+	err = forEachEvent(in, func(e *eventspb.BusEvent, height uint64) error {
+		for _, t := range transforms {
+			if !t(e) {
+				dropped++
+				return nil
+			}
+		}
+		written++
+		return out.Write(e)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d events written to %s, %d dropped\n", written, out.Path(), dropped)
+	return nil
+}
+
+func dropTypes(types []eventspb.BusEventType) transform {
+	drop := map[eventspb.BusEventType]struct{}{}
+	for _, t := range types {
+		drop[t] = struct{}{}
+	}
+	return func(e *eventspb.BusEvent) bool {
+		_, ok := drop[e.Type]
+		return !ok
+	}
+}
+
+// replaceIDs replaces every string in the event, other than its ID and block hash, that is one of the IDs mapped.
+// Only whole strings are matched so that a mapping never rewrites part of an unrelated value or the result of another
+// mapping.
+func replaceIDs(ids map[string]string) transform {
+	return func(e *eventspb.BusEvent) bool {
+		id, block := e.Id, e.Block
+		walkStrings(e.ProtoReflect(), func(_ protoreflect.FieldDescriptor, s string) string {
+			if to, ok := ids[s]; ok {
+				return to
+			}
+			return s
+		})
+		e.Id, e.Block = id, block
+		return true
+	}
+}
+
+// collectParties returns the party IDs and public keys referenced by the events in the file.
+func collectParties(file string) ([]string, error) {
+	in, err := eventfile.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	seen := map[string]struct{}{}
+	parties := []string{}
+	err = forEachEvent(in, func(e *eventspb.BusEvent, height uint64) error {
+		walkStrings(e.ProtoReflect(), func(fd protoreflect.FieldDescriptor, s string) string {
+			if _, ok := partyFields[string(fd.Name())]; !ok || !isKey(s) {
+				return s
+			}
+			if _, ok := seen[s]; !ok {
+				seen[s] = struct{}{}
+				parties = append(parties, s)
+			}
+			return s
+		})
+		return nil
+	})
+	return parties, err
+}
+
+// isKey returns true for a 64 character hex string, leaving out placeholders such as "network" or "*".
+func isKey(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+func pseudonym(salt, id string) string {
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(id))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// walkStrings replaces every string field, list element, map key and map value of m, at any depth, with the result
+// of fn.
+func walkStrings(m protoreflect.Message, fn func(fd protoreflect.FieldDescriptor, s string) string) {
+	type edit struct {
+		fd protoreflect.FieldDescriptor
+		v  protoreflect.Value
+	}
+	// fields are only set once the range is complete
+	var edits []edit
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsList():
+			l := v.List()
+			for i := 0; i < l.Len(); i++ {
+				if nv, ok := walkValue(fd, l.Get(i), fn); ok {
+					l.Set(i, nv)
+				}
+			}
+		case fd.IsMap():
+			walkMap(fd, v.Map(), fn)
+		default:
+			if nv, ok := walkValue(fd, v, fn); ok {
+				edits = append(edits, edit{fd: fd, v: nv})
+			}
+		}
+		return true
+	})
+	for _, e := range edits {
+		m.Set(e.fd, e.v)
+	}
+}
+
+// walkValue returns the new value of a string, or walks a message in place.
+func walkValue(fd protoreflect.FieldDescriptor, v protoreflect.Value, fn func(fd protoreflect.FieldDescriptor, s string) string) (protoreflect.Value, bool) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		if s := fn(fd, v.String()); s != v.String() {
+			return protoreflect.ValueOfString(s), true
+		}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		walkStrings(v.Message(), fn)
+	}
+	return v, false
+}
+
+func walkMap(fd protoreflect.FieldDescriptor, mp protoreflect.Map, fn func(fd protoreflect.FieldDescriptor, s string) string) {
+	type entry struct {
+		old, key protoreflect.MapKey
+		v        protoreflect.Value
+	}
+	var changed []entry
+	mp.Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+		key, keyChanged := k, false
+		if fd.MapKey().Kind() == protoreflect.StringKind {
+			if s := fn(fd, k.String()); s != k.String() {
+				key, keyChanged = protoreflect.ValueOfString(s).MapKey(), true
+			}
+		}
+		nv, valueChanged := walkValue(fd.MapValue(), v, fn)
+		if keyChanged || valueChanged {
+			changed = append(changed, entry{old: k, key: key, v: nv})
+		}
+		return true
+	})
+	for _, c := range changed {
+		mp.Clear(c.old)
+	}
+	for _, c := range changed {
+		mp.Set(c.key, c.v)
+	}
+}
@@ -0,0 +1,100 @@
+package evttool
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"code.vegaprotocol.io/vega/protos/vega"
+	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
+	"code.vegaprotocol.io/vegatools/eventfile"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	alice = strings.Repeat("a1", 32)
+	bob   = strings.Repeat("b2", 32)
+)
+
+func orderEvent(id, market, party, reference string) *eventspb.BusEvent {
+	return &eventspb.BusEvent{
+		Id:    "1-" + id,
+		Block: "block",
+		Type:  eventspb.BusEventType_BUS_EVENT_TYPE_ORDER,
+		Event: &eventspb.BusEvent_Order{Order: &vega.Order{Id: id, MarketId: market, PartyId: party, Reference: reference}},
+	}
+}
+
+func transformEvents(t *testing.T, opts TransformOpts, events ...*eventspb.BusEvent) []*eventspb.BusEvent {
+	t.Helper()
+	dir := t.TempDir()
+	opts.File, opts.Out = filepath.Join(dir, "in.evt"), filepath.Join(dir, "out.evt")
+
+	w, err := eventfile.Create(opts.File, eventfile.WriterOpts{})
+	require.NoError(t, err)
+	for _, e := range events {
+		require.NoError(t, w.Write(e))
+	}
+	require.NoError(t, w.Close())
+
+	require.NoError(t, Transform(opts))
+
+	r, err := eventfile.Open(opts.Out)
+	require.NoError(t, err)
+	defer r.Close()
+	out := []*eventspb.BusEvent{}
+	require.NoError(t, forEachEvent(r, func(e *eventspb.BusEvent, _ uint64) error {
+		out = append(out, e)
+		return nil
+	}))
+	return out
+}
+
+func TestTransformMarketMapMatchesWholeIDs(t *testing.T) {
+	opts := TransformOpts{MarketMap: map[string]string{"m1": "m2", "m2": "m1", "m1-spot": "m3"}}
+	for i := 0; i < 10; i++ {
+		out := transformEvents(t, opts,
+			orderEvent("o1", "m1", alice, "m1 order"),
+			orderEvent("o2", "m2", alice, ""),
+			orderEvent("o3", "m1-spot", alice, "m1"),
+		)
+		require.Len(t, out, 3)
+		assert.Equal(t, "m2", out[0].GetOrder().MarketId)
+		assert.Equal(t, "m1 order", out[0].GetOrder().Reference)
+		assert.Equal(t, "m1", out[1].GetOrder().MarketId)
+		assert.Equal(t, "m3", out[2].GetOrder().MarketId)
+		assert.Equal(t, "m2", out[2].GetOrder().Reference)
+	}
+}
+
+func TestTransformPseudonymise(t *testing.T) {
+	opts := TransformOpts{Pseudonymise: true, Salt: "salt"}
+	out := transformEvents(t, opts,
+		orderEvent("o1", "m1", alice, "for "+bob),
+		orderEvent("o2", "m1", bob, alice),
+	)
+	require.Len(t, out, 2)
+	assert.Equal(t, pseudonym("salt", alice), out[0].GetOrder().PartyId)
+	assert.Equal(t, "for "+bob, out[0].GetOrder().Reference)
+	assert.Equal(t, pseudonym("salt", bob), out[1].GetOrder().PartyId)
+	assert.Equal(t, pseudonym("salt", alice), out[1].GetOrder().Reference)
+	assert.Equal(t, "1-o1", out[0].Id)
+}
+
+func TestTransformDropTypes(t *testing.T) {
+	opts := TransformOpts{DropTypes: []string{"BUS_EVENT_TYPE_ORDER"}}
+	out := transformEvents(t, opts,
+		orderEvent("o1", "m1", alice, ""),
+		&eventspb.BusEvent{Id: "1-p", Block: "block", Type: eventspb.BusEventType_BUS_EVENT_TYPE_PARTY,
+			Event: &eventspb.BusEvent_Party{Party: &vega.Party{Id: alice}}},
+	)
+	require.Len(t, out, 1)
+	assert.Equal(t, "1-p", out[0].Id)
+}
+
+func TestTransformNeedsSaltAndTransforms(t *testing.T) {
+	assert.Error(t, Transform(TransformOpts{Pseudonymise: true}))
+	assert.Error(t, Transform(TransformOpts{}))
+}
@@ -0,0 +1,52 @@
+package evttool
+
+import (
+	"fmt"
+
+	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
+	"code.vegaprotocol.io/vegatools/eventfile"
+)
+
+// ValidateOpts are the command line options passed to the validate sub command
+type ValidateOpts struct {
+	File string
+}
+
+// Validate checks that an events file holds a contiguous sequence of blocks without duplicated events.
+func Validate(opts ValidateOpts) error {
+	in, err := eventfile.Open(opts.File)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	var (
+		check      blockCheck
+		blockIDs   = map[string]struct{}{}
+		duplicates uint64
+	)
+	err = forEachEvent(in, func(e *eventspb.BusEvent, height uint64) error {
+		if !check.started || height != check.current {
+			blockIDs = map[string]struct{}{}
+		}
+		check.add(height)
+
+		if _, ok := blockIDs[e.Id]; ok {
+			duplicates++
+		}
+		blockIDs[e.Id] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	check.print()
+	if duplicates > 0 {
+		fmt.Printf("%d duplicate events\n", duplicates)
+	}
+	if !check.contiguous() || duplicates > 0 {
+		return fmt.Errorf("%s is not a contiguous sequence of blocks", opts.File)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package evttool
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"code.vegaprotocol.io/vegatools/eventfile"
+)
+
+// VerifyOpts are the command line options passed to the verify sub command
+type VerifyOpts struct {
+	File string
+}
+
+// Verify reads every record of an events file, checking its checksum and that the blocks are contiguous. Corrupt
+// records are reported and skipped, reading stops at the first record that cannot be read in full.
+func Verify(opts VerifyOpts) error {
+	in, err := eventfile.Open(opts.File)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	var (
+		check     blockCheck
+		events    uint64
+		corrupt   uint64
+		truncated bool
+	)
+	for {
+		e, err := in.Next()
+		if err == io.EOF {
+			break
+		}
+
+		var recErr *eventfile.RecordError
+		if errors.As(err, &recErr) {
+			fmt.Println(recErr)
+			if recErr.Recoverable {
+				corrupt++
+				continue
+			}
+			truncated = true
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		events++
+		if height, ok := eventfile.BlockHeight(e); ok {
+			check.add(height)
+		}
+	}
+
+	fmt.Printf("%d events read, %d corrupt records\n", events, corrupt)
+	if truncated {
+		fmt.Println("file ends with an incomplete record")
+	}
+	check.print()
+
+	if corrupt > 0 || truncated || !check.contiguous() {
+		return fmt.Errorf("%s failed verification", opts.File)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Serve exposes the metrics in registry on address at /metrics. The address is bound before returning so that a
+// port already in use is reported straight away, after which requests are served in the background.
+func Serve(address string, registry *prometheus.Registry) error {
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("unable to listen for metrics on %s: %w", address, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry}))
+	go http.Serve(l, mux)
+	return nil
+}
@@ -0,0 +1,129 @@
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Output formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// Writer writes result records, which are structs, in a machine readable format. JSON records are written one per
+// line. CSV records have a column for each field that is not a slice, map or nested struct, named after its json tag,
+// and a header row is written before the first record and again whenever the type of record changes.
+type Writer struct {
+	json   *json.Encoder
+	csv    *csv.Writer
+	header reflect.Type
+}
+
+// NewWriter returns a Writer for format, or nil for the human readable text format which each tool prints itself.
+func NewWriter(w io.Writer, format string) (*Writer, error) {
+	switch format {
+	case FormatText, "":
+		return nil, nil
+	case FormatJSON:
+		return &Writer{json: json.NewEncoder(w)}, nil
+	case FormatCSV:
+		return &Writer{csv: csv.NewWriter(w)}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %s, must be one of text, json or csv", format)
+	}
+}
+
+// Write writes a record and flushes it so it can be read straight away.
+func (w *Writer) Write(record interface{}) error {
+	if w.json != nil {
+		return w.json.Encode(record)
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(record))
+	if v.Type() != w.header {
+		w.header = v.Type()
+		if err := w.csv.Write(columns(v.Type())); err != nil {
+			return err
+		}
+	}
+	if err := w.csv.Write(values(v)); err != nil {
+		return err
+	}
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// fieldName returns the CSV column name of a field, or false if the field is not written to CSV.
+func fieldName(f reflect.StructField) (string, bool) {
+	if !f.IsExported() {
+		return "", false
+	}
+	switch f.Type.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Ptr, reflect.Interface:
+		return "", false
+	case reflect.Struct:
+		if f.Type != timeType {
+			return "", false
+		}
+	}
+
+	name := f.Name
+	if tag, ok := f.Tag.Lookup("json"); ok {
+		tag, _, _ = strings.Cut(tag, ",")
+		if tag == "-" {
+			return "", false
+		}
+		if len(tag) > 0 {
+			name = tag
+		}
+	}
+	return name, true
+}
+
+func columns(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		if name, ok := fieldName(t.Field(i)); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func values(v reflect.Value) []string {
+	var record []string
+	for i := 0; i < v.NumField(); i++ {
+		if _, ok := fieldName(v.Type().Field(i)); !ok {
+			continue
+		}
+		record = append(record, format(v.Field(i)))
+	}
+	return record
+}
+
+func format(v reflect.Value) string {
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339Nano)
+	}
+
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
@@ -0,0 +1,64 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type bucket struct {
+	Time    time.Time `json:"time"`
+	Events  uint64    `json:"events"`
+	Rate    float64   `json:"rate"`
+	Name    string
+	Ignored string   `json:"-"`
+	Types   []string `json:"types,omitempty"`
+}
+
+type final struct {
+	Buckets int `json:"buckets"`
+}
+
+func TestWriteJSON(t *testing.T) {
+	buf := bytes.Buffer{}
+	w, err := NewWriter(&buf, FormatJSON)
+	require.NoError(t, err)
+
+	require.NoError(t, w.Write(bucket{Events: 3, Types: []string{"ORDER"}}))
+	require.NoError(t, w.Write(&final{Buckets: 2}))
+	assert.Equal(t, ""+
+		`{"time":"0001-01-01T00:00:00Z","events":3,"rate":0,"Name":"","types":["ORDER"]}`+"\n"+
+		`{"buckets":2}`+"\n", buf.String())
+}
+
+func TestWriteCSV(t *testing.T) {
+	buf := bytes.Buffer{}
+	w, err := NewWriter(&buf, FormatCSV)
+	require.NoError(t, err)
+
+	at := time.Date(2024, 1, 2, 3, 4, 5, 600, time.UTC)
+	require.NoError(t, w.Write(bucket{Time: at, Events: 3, Rate: 1.25, Name: "a,b", Ignored: "x", Types: []string{"ORDER"}}))
+	require.NoError(t, w.Write(&bucket{Events: 4}))
+	// a header is written again when the type of record changes
+	require.NoError(t, w.Write(final{Buckets: 2}))
+	assert.Equal(t, ""+
+		"time,events,rate,Name\n"+
+		"2024-01-02T03:04:05.0000006Z,3,1.25,\"a,b\"\n"+
+		",4,0,\n"+
+		"buckets\n"+
+		"2\n", buf.String())
+}
+
+func TestNewWriter(t *testing.T) {
+	for _, format := range []string{"", FormatText} {
+		w, err := NewWriter(&bytes.Buffer{}, format)
+		require.NoError(t, err)
+		assert.Nil(t, w)
+	}
+
+	_, err := NewWriter(&bytes.Buffer{}, "xml")
+	assert.EqualError(t, err, "unknown output format xml, must be one of text, json or csv")
+}
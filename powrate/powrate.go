@@ -3,10 +3,12 @@ package powrate
 import (
 	"fmt"
 	"math/rand"
+	"os"
 	"sync"
 	"time"
 
 	vgcrypto "code.vegaprotocol.io/vega/libs/crypto"
+	"code.vegaprotocol.io/vegatools/output"
 )
 
 // Opts are the command line options passed to the sub command
@@ -17,6 +19,25 @@ type Opts struct {
 	SecondsPerBlock   int
 	TxPerBlock        int
 	DefaultDifficulty int
+	// Output is the format results are written in, text, json or csv
+	Output string
+}
+
+// DifficultyResult is written for every difficulty tested with --output json or csv.
+type DifficultyResult struct {
+	Difficulty   int `json:"difficulty"`
+	Count        int `json:"count"`
+	OpsPerSecond int `json:"ops_per_second"`
+	TestSeconds  int `json:"test_seconds"`
+}
+
+// SummaryResult is written once every difficulty has been tested. MaxPoWCalcs is only set when Found is true.
+type SummaryResult struct {
+	SecondsPerBlock   int  `json:"seconds_per_block"`
+	DefaultDifficulty int  `json:"default_difficulty"`
+	TxPerBlock        int  `json:"tx_per_block"`
+	Found             bool `json:"found"`
+	MaxPoWCalcs       int  `json:"max_pow_calcs"`
 }
 
 func getRandomBlockHash() string {
@@ -73,14 +94,23 @@ func Run(opts Opts) error {
 		return fmt.Errorf("default difficulty value must be between the min and max testable values")
 	}
 
-	fmt.Printf("Vega PoW Benchmark (Difficulty %d-%d, %d seconds per level)\n", opts.MinPoWLevel, opts.MaxPoWLevel, opts.TestSeconds)
-	fmt.Println("|  PoW Difficulty  | Total PoW Count | PoW Operations Per Second |")
-	fmt.Println("------------------------------------------------------------------")
+	out, err := output.NewWriter(os.Stdout, opts.Output)
+	if err != nil {
+		return err
+	}
+
+	if out == nil {
+		fmt.Printf("Vega PoW Benchmark (Difficulty %d-%d, %d seconds per level)\n", opts.MinPoWLevel, opts.MaxPoWLevel, opts.TestSeconds)
+		fmt.Println("|  PoW Difficulty  | Total PoW Count | PoW Operations Per Second |")
+		fmt.Println("------------------------------------------------------------------")
+	}
 
 	var waitGroup sync.WaitGroup
 	var powTimes map[int]float32 = make(map[int]float32)
 	for difficulty := opts.MinPoWLevel; difficulty <= opts.MaxPoWLevel; difficulty++ {
-		fmt.Printf("|  %-3d             |", difficulty)
+		if out == nil {
+			fmt.Printf("|  %-3d             |", difficulty)
+		}
 		work := make(chan int, 100)
 		stop := make(chan int)
 		timeout := make(chan int)
@@ -100,12 +130,26 @@ func Run(opts Opts) error {
 			}
 		}
 		waitGroup.Wait()
-		fmt.Printf(" %-16d| %24d  |\n", operationsCount, operationsCount/opts.TestSeconds)
+		if out != nil {
+			result := DifficultyResult{
+				Difficulty:   difficulty,
+				Count:        operationsCount,
+				OpsPerSecond: operationsCount / opts.TestSeconds,
+				TestSeconds:  opts.TestSeconds,
+			}
+			if err := out.Write(result); err != nil {
+				return fmt.Errorf("failed to write result: %w", err)
+			}
+		} else {
+			fmt.Printf(" %-16d| %24d  |\n", operationsCount, operationsCount/opts.TestSeconds)
+		}
 
 		// Store the time per calc for use later
 		powTimes[difficulty] = float32(opts.TestSeconds) / float32(operationsCount)
 	}
-	fmt.Println("------------------------------------------------------------------")
+	if out == nil {
+		fmt.Println("------------------------------------------------------------------")
+	}
 
 	// Loop through all the timings starting at the default difficulty and add up the time
 	totalTime := float32(0.0)
@@ -124,6 +168,19 @@ func Run(opts Opts) error {
 			break
 		}
 	}
+	if out != nil {
+		summary := SummaryResult{
+			SecondsPerBlock:   opts.SecondsPerBlock,
+			DefaultDifficulty: opts.DefaultDifficulty,
+			TxPerBlock:        opts.TxPerBlock,
+			Found:             foundValue,
+		}
+		if foundValue {
+			summary.MaxPoWCalcs = powCalcs
+		}
+		return out.Write(summary)
+	}
+
 	if foundValue {
 		fmt.Printf("For an average blocktime of %d seconds\n", opts.SecondsPerBlock)
 		fmt.Printf("and a starting difficulty of %d\n", opts.DefaultDifficulty)
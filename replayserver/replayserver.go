@@ -0,0 +1,227 @@
+package replayserver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	dnapi "code.vegaprotocol.io/vega/protos/data-node/api/v2"
+	apipb "code.vegaprotocol.io/vega/protos/vega/api/v1"
+	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
+	"code.vegaprotocol.io/vega/vegatools/stream"
+	"code.vegaprotocol.io/vegatools/eventfile"
+
+	"google.golang.org/grpc"
+)
+
+// Opts are the command line options passed to the sub command
+type Opts struct {
+	File      string
+	Address   string
+	FromBlock uint64
+	ToBlock   uint64
+	// Speed multiplies the original time between blocks, 0 sends events as fast as the client reads them
+	Speed float64
+}
+
+// Run is the main function of `replayserver` package
+func Run(opts Opts) error {
+	if opts.Speed < 0 {
+		return fmt.Errorf("speed must not be negative")
+	}
+
+	// check the file can be read before accepting subscriptions
+	in, err := eventfile.Open(opts.File)
+	if err != nil {
+		return err
+	}
+	in.Close()
+
+	lis, err := net.Listen("tcp", opts.Address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", opts.Address, err)
+	}
+
+	srv := &server{opts: opts}
+	s := grpc.NewServer()
+	apipb.RegisterCoreServiceServer(s, &coreService{server: srv})
+	dnapi.RegisterTradingDataServiceServer(s, &tradingDataService{server: srv})
+
+	fmt.Printf("serving events from %s on %s\n", opts.File, lis.Addr())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.Serve(lis)
+		cancel()
+	}()
+
+	stream.WaitSig(ctx, cancel)
+	s.Stop()
+	return <-errCh
+}
+
+// subscription is an ObserveEventBus request, common to the core and data node APIs.
+type subscription struct {
+	filter    eventfile.Filter
+	batchSize int
+}
+
+func newSubscription(types []eventspb.BusEventType, market, party string, batchSize int64) subscription {
+	sub := subscription{
+		filter:    eventfile.Filter{Market: market, Party: party},
+		batchSize: int(batchSize),
+	}
+	for _, t := range types {
+		if t == eventspb.BusEventType_BUS_EVENT_TYPE_ALL {
+			sub.filter.Types = nil
+			break
+		}
+		sub.filter.Types = append(sub.filter.Types, t)
+	}
+	return sub
+}
+
+type server struct {
+	opts Opts
+}
+
+// replay sends the events of the file matching the subscription. With a batch size set the client acknowledges every
+// batch, as it does with a node, and may change the batch size when it does.
+func (s *server) replay(ctx context.Context, sub subscription,
+	send func([]*eventspb.BusEvent) error, ack func() (int64, error),
+) error {
+	in, err := eventfile.Open(s.opts.File)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if s.opts.FromBlock > 0 {
+		if err := in.SeekHeight(s.opts.FromBlock); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	sub.filter.FromHeight, sub.filter.ToHeight = s.opts.FromBlock, s.opts.ToBlock
+
+	batch := []*eventspb.BusEvent{}
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := send(batch); err != nil {
+			return err
+		}
+		batch = batch[:0]
+		if sub.batchSize <= 0 {
+			return nil
+		}
+		size, err := ack()
+		if err != nil {
+			return err
+		}
+		if size > 0 {
+			sub.batchSize = int(size)
+		}
+		return nil
+	}
+
+	var (
+		lastVegaTime int64
+		lastSent     time.Time
+	)
+	for {
+		e, err := in.Next()
+		if err == io.EOF || (err == nil && sub.filter.PastEnd(e)) {
+			return flush()
+		}
+		if err != nil {
+			return err
+		}
+
+		if tu := e.GetTimeUpdate(); tu != nil && s.opts.Speed > 0 {
+			// send what is pending before waiting for the next block
+			if err := flush(); err != nil {
+				return err
+			}
+			if lastVegaTime > 0 && tu.Timestamp > lastVegaTime {
+				wait := time.Duration(float64(tu.Timestamp-lastVegaTime) / s.opts.Speed)
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(time.Until(lastSent.Add(wait))):
+				}
+			}
+			lastVegaTime, lastSent = tu.Timestamp, time.Now()
+		}
+
+		if !sub.filter.Match(e) {
+			continue
+		}
+		batch = append(batch, e)
+		if len(batch) >= sub.batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// coreService serves the event bus stream of a core node.
+type coreService struct {
+	apipb.UnimplementedCoreServiceServer
+	server *server
+}
+
+func (c *coreService) ObserveEventBus(stream apipb.CoreService_ObserveEventBusServer) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	sub := newSubscription(req.GetType(), req.GetMarketId(), req.GetPartyId(), req.GetBatchSize())
+	return c.server.replay(stream.Context(), sub,
+		func(events []*eventspb.BusEvent) error {
+			return stream.Send(&apipb.ObserveEventBusResponse{Events: events})
+		},
+		func() (int64, error) {
+			req, err := stream.Recv()
+			if err != nil {
+				return 0, err
+			}
+			return req.GetBatchSize(), nil
+		},
+	)
+}
+
+// tradingDataService serves the event bus stream of a data node.
+type tradingDataService struct {
+	dnapi.UnimplementedTradingDataServiceServer
+	server *server
+}
+
+func (t *tradingDataService) ObserveEventBus(stream dnapi.TradingDataService_ObserveEventBusServer) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	sub := newSubscription(req.GetType(), req.GetMarketId(), req.GetPartyId(), req.GetBatchSize())
+	return t.server.replay(stream.Context(), sub,
+		func(events []*eventspb.BusEvent) error {
+			return stream.Send(&dnapi.ObserveEventBusResponse{Events: events})
+		},
+		func() (int64, error) {
+			req, err := stream.Recv()
+			if err != nil {
+				return 0, err
+			}
+			return req.GetBatchSize(), nil
+		},
+	)
+}
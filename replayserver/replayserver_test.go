@@ -0,0 +1,93 @@
+package replayserver
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"code.vegaprotocol.io/vega/protos/vega"
+	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
+	"code.vegaprotocol.io/vegatools/eventfile"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeEvents writes blocks 1 to 4, each with a time update and an order of party-1 or party-2 in turn.
+func writeEvents(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "events.evt")
+	w, err := eventfile.Create(path, eventfile.WriterOpts{})
+	require.NoError(t, err)
+	for h := uint64(1); h <= 4; h++ {
+		block := fmt.Sprintf("block-%d", h)
+		events := []*eventspb.BusEvent{
+			{Type: eventspb.BusEventType_BUS_EVENT_TYPE_TIME_UPDATE, Event: &eventspb.BusEvent_TimeUpdate{
+				TimeUpdate: &eventspb.TimeUpdate{Timestamp: int64(h)},
+			}},
+			{Type: eventspb.BusEventType_BUS_EVENT_TYPE_ORDER, Event: &eventspb.BusEvent_Order{
+				Order: &vega.Order{Id: fmt.Sprintf("o%d", h), PartyId: fmt.Sprintf("party-%d", 2-h%2)},
+			}},
+		}
+		for i, e := range events {
+			e.Id, e.Block = fmt.Sprintf("%d-%d", h, i), block
+			require.NoError(t, w.Write(e))
+		}
+	}
+	require.NoError(t, w.Close())
+	return path
+}
+
+// replay runs a subscription and returns the IDs of the batches sent. Every acknowledgement returns the next of
+// acks as the new batch size.
+func replay(t *testing.T, opts Opts, sub subscription, acks ...int64) [][]string {
+	t.Helper()
+	batches := [][]string{}
+	send := func(events []*eventspb.BusEvent) error {
+		ids := []string{}
+		for _, e := range events {
+			ids = append(ids, e.Id)
+		}
+		batches = append(batches, ids)
+		return nil
+	}
+	ack := func() (int64, error) {
+		if len(acks) == 0 {
+			return 0, nil
+		}
+		size := acks[0]
+		acks = acks[1:]
+		return size, nil
+	}
+	require.NoError(t, (&server{opts: opts}).replay(context.Background(), sub, send, ack))
+	return batches
+}
+
+func TestReplayBlockRange(t *testing.T) {
+	opts := Opts{File: writeEvents(t), FromBlock: 2, ToBlock: 3}
+	batches := replay(t, opts, newSubscription(nil, "", "", 0))
+	assert.Equal(t, [][]string{{"2-0"}, {"2-1"}, {"3-0"}, {"3-1"}}, batches)
+
+	opts.FromBlock = 5
+	assert.Empty(t, replay(t, opts, newSubscription(nil, "", "", 0)))
+}
+
+func TestReplayFilters(t *testing.T) {
+	path := writeEvents(t)
+	orders := []eventspb.BusEventType{eventspb.BusEventType_BUS_EVENT_TYPE_ORDER}
+
+	batches := replay(t, Opts{File: path}, newSubscription(orders, "", "", 0))
+	assert.Equal(t, [][]string{{"1-1"}, {"2-1"}, {"3-1"}, {"4-1"}}, batches)
+
+	batches = replay(t, Opts{File: path}, newSubscription(nil, "", "party-2", 0))
+	assert.Equal(t, [][]string{{"2-1"}, {"4-1"}}, batches)
+
+	all := []eventspb.BusEventType{eventspb.BusEventType_BUS_EVENT_TYPE_ORDER, eventspb.BusEventType_BUS_EVENT_TYPE_ALL}
+	assert.Len(t, replay(t, Opts{File: path}, newSubscription(all, "", "", 0)), 8)
+}
+
+func TestReplayBatchesChangedOnAck(t *testing.T) {
+	batches := replay(t, Opts{File: writeEvents(t)}, newSubscription(nil, "", "", 3), 2)
+	assert.Equal(t, [][]string{{"1-0", "1-1", "2-0"}, {"2-1", "3-0"}, {"3-1", "4-0"}, {"4-1"}}, batches)
+}
@@ -0,0 +1,155 @@
+package streamcheck
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
+	"code.vegaprotocol.io/vegatools/eventfile"
+)
+
+// Stats are the totals seen by a Checker.
+type Stats struct {
+	Events        uint64
+	Blocks        uint64
+	MissingBlocks uint64
+	MissingEvents uint64
+	Problems      uint64
+}
+
+// Checker validates the bus events received from a stream: every block must start with a BEGIN_BLOCK and finish with
+// an END_BLOCK of the same height, blocks must follow each other without gaps and, when the stream is not filtered,
+// the sequence numbers of the events in a block must be contiguous. Problems are passed to the report function as
+// they are found.
+type Checker struct {
+	mu       sync.Mutex
+	report   func(problem string)
+	sequence bool
+	stats    Stats
+
+	started bool
+	height  uint64
+	ended   bool
+	lastSeq uint64
+	haveSeq bool
+}
+
+// NewChecker returns a Checker that reports problems to report. checkSequence must only be set when the stream
+// carries every event, filtering by type, party or market leaves gaps in the sequence numbers.
+func NewChecker(checkSequence bool, report func(problem string)) *Checker {
+	return &Checker{report: report, sequence: checkSequence}
+}
+
+// Wrap returns an event handler that checks each event before passing it on to handle.
+func (c *Checker) Wrap(handle func(e *eventspb.BusEvent)) func(e *eventspb.BusEvent) {
+	return func(e *eventspb.BusEvent) {
+		c.Check(e)
+		handle(e)
+	}
+}
+
+// Stats returns the totals seen so far.
+func (c *Checker) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// Check validates the next event of the stream.
+func (c *Checker) Check(e *eventspb.BusEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.Events++
+
+	height, ok := eventfile.BlockHeight(e)
+	if !ok {
+		c.problem("event %s has no block height", e.Id)
+		return
+	}
+
+	switch e.Type {
+	case eventspb.BusEventType_BUS_EVENT_TYPE_BEGIN_BLOCK:
+		c.newBlock(height)
+	case eventspb.BusEventType_BUS_EVENT_TYPE_END_BLOCK:
+		if !c.started || height != c.height {
+			c.newBlock(height)
+			c.missingBegin(height)
+		} else if c.ended {
+			c.problem("block %d has more than one END_BLOCK", height)
+		}
+		c.ended = true
+	default:
+		if !c.started || height != c.height {
+			c.newBlock(height)
+			c.missingBegin(height)
+		}
+	}
+
+	c.checkSequence(e, height)
+}
+
+// newBlock records the start of a block and checks it follows the previous one.
+func (c *Checker) newBlock(height uint64) {
+	if c.started {
+		switch {
+		case height > c.height+1:
+			c.stats.MissingBlocks += height - c.height - 1
+			c.problem("missing blocks %d-%d", c.height+1, height-1)
+		case height <= c.height:
+			c.problem("block %d received after block %d", height, c.height)
+		}
+		if !c.ended {
+			c.problem("block %d has no END_BLOCK", c.height)
+		}
+	}
+
+	c.stats.Blocks++
+	c.started = true
+	c.height = height
+	c.ended = false
+	c.haveSeq = false
+}
+
+// missingBegin reports a block that did not start with a BEGIN_BLOCK, unless it is the first block received as
+// streams are usually joined part way through a block.
+func (c *Checker) missingBegin(height uint64) {
+	if c.stats.Blocks > 1 {
+		c.problem("block %d has no BEGIN_BLOCK", height)
+	}
+}
+
+// checkSequence checks the sequence number of the event ID follows that of the previous event in the block.
+func (c *Checker) checkSequence(e *eventspb.BusEvent, height uint64) {
+	if !c.sequence {
+		return
+	}
+
+	i := strings.IndexByte(e.Id, '-')
+	if i < 0 {
+		return
+	}
+	seq, err := strconv.ParseUint(e.Id[i+1:], 10, 64)
+	if err != nil {
+		return
+	}
+
+	if c.haveSeq {
+		switch {
+		case seq > c.lastSeq+1:
+			c.stats.MissingEvents += seq - c.lastSeq - 1
+			c.problem("missing events %d-%d in block %d", c.lastSeq+1, seq-1, height)
+		case seq <= c.lastSeq:
+			c.problem("event %s received after event %d-%d", e.Id, height, c.lastSeq)
+		}
+	}
+	c.lastSeq, c.haveSeq = seq, true
+}
+
+func (c *Checker) problem(format string, args ...interface{}) {
+	c.stats.Problems++
+	if c.report != nil {
+		c.report(fmt.Sprintf(format, args...))
+	}
+}
@@ -0,0 +1,94 @@
+package streamcheck
+
+import (
+	"fmt"
+	"testing"
+
+	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	begin = eventspb.BusEventType_BUS_EVENT_TYPE_BEGIN_BLOCK
+	end   = eventspb.BusEventType_BUS_EVENT_TYPE_END_BLOCK
+	order = eventspb.BusEventType_BUS_EVENT_TYPE_ORDER
+)
+
+func event(height, seq uint64, t eventspb.BusEventType) *eventspb.BusEvent {
+	return &eventspb.BusEvent{Id: fmt.Sprintf("%d-%d", height, seq), Type: t}
+}
+
+// block returns the events of a complete block.
+func block(height uint64) []*eventspb.BusEvent {
+	return []*eventspb.BusEvent{event(height, 0, begin), event(height, 1, order), event(height, 2, end)}
+}
+
+// check runs the events through a checker and returns the problems reported and its stats.
+func check(checkSequence bool, events ...[]*eventspb.BusEvent) ([]string, Stats) {
+	problems := []string{}
+	c := NewChecker(checkSequence, func(problem string) { problems = append(problems, problem) })
+	for _, es := range events {
+		for _, e := range es {
+			c.Check(e)
+		}
+	}
+	return problems, c.Stats()
+}
+
+func TestCompleteStream(t *testing.T) {
+	// streams are joined part way through a block
+	joined := []*eventspb.BusEvent{event(1, 5, order), event(1, 6, end)}
+
+	problems, stats := check(true, joined, block(2), block(3))
+	assert.Empty(t, problems)
+	assert.Equal(t, Stats{Events: 8, Blocks: 3}, stats)
+}
+
+func TestMissingBlocks(t *testing.T) {
+	problems, stats := check(true, block(1), block(4))
+	assert.Equal(t, []string{"missing blocks 2-3"}, problems)
+	assert.Equal(t, uint64(2), stats.MissingBlocks)
+	assert.Equal(t, uint64(1), stats.Problems)
+}
+
+func TestIncompleteBlocks(t *testing.T) {
+	problems, _ := check(true,
+		[]*eventspb.BusEvent{event(1, 0, begin), event(1, 1, order)},
+		[]*eventspb.BusEvent{event(2, 0, begin), event(2, 1, end), event(2, 2, end)},
+		[]*eventspb.BusEvent{event(3, 0, order), event(3, 1, end)},
+	)
+	assert.Equal(t, []string{
+		"block 1 has no END_BLOCK",
+		"block 2 has more than one END_BLOCK",
+		"block 3 has no BEGIN_BLOCK",
+	}, problems)
+}
+
+func TestBlocksOutOfOrder(t *testing.T) {
+	problems, _ := check(true, block(2), block(1))
+	assert.Equal(t, []string{"block 1 received after block 2"}, problems)
+}
+
+func TestMissingEvents(t *testing.T) {
+	events := []*eventspb.BusEvent{event(1, 0, begin), event(1, 3, order), event(1, 4, end)}
+
+	problems, stats := check(true, events)
+	assert.Equal(t, []string{"missing events 1-2 in block 1"}, problems)
+	assert.Equal(t, uint64(2), stats.MissingEvents)
+
+	// a filtered stream has gaps in the sequence numbers
+	problems, stats = check(false, events)
+	assert.Empty(t, problems)
+	assert.Zero(t, stats.MissingEvents)
+}
+
+func TestRepeatedEvents(t *testing.T) {
+	problems, _ := check(true, []*eventspb.BusEvent{event(1, 0, begin), event(1, 1, order), event(1, 1, order), event(1, 2, end)})
+	assert.Equal(t, []string{"event 1-1 received after event 1-1"}, problems)
+}
+
+func TestEventWithoutHeight(t *testing.T) {
+	problems, _ := check(true, []*eventspb.BusEvent{{Id: "bad", Type: order}})
+	assert.Equal(t, []string{"event bad has no block height"}, problems)
+}
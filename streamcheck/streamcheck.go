@@ -0,0 +1,76 @@
+package streamcheck
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"code.vegaprotocol.io/vega/vegatools/stream"
+)
+
+// Opts are the command line options passed to the sub command
+type Opts struct {
+	ServerAddr     string
+	Reconnect      bool
+	Bell           bool
+	ExitOnError    bool
+	ReportInterval time.Duration
+}
+
+// Run is the main function of `streamcheck` package
+func Run(opts Opts) error {
+	if len(opts.ServerAddr) <= 0 {
+		return fmt.Errorf("error: missing grpc server address")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var failed bool
+	checker := NewChecker(true, func(problem string) {
+		fmt.Printf("%s %s\n", time.Now().Format(time.RFC3339), problem)
+		if opts.Bell {
+			fmt.Print("\a")
+		}
+		if opts.ExitOnError && !failed {
+			failed = true
+			cancel()
+		}
+	})
+
+	wg := sync.WaitGroup{}
+	if err := stream.ReadEvents(ctx, cancel, &wg, 0, "", "", opts.ServerAddr, checker.Check, opts.Reconnect, nil); err != nil {
+		return fmt.Errorf("error reading events: %v", err)
+	}
+
+	if opts.ReportInterval > 0 {
+		go func() {
+			ticker := time.NewTicker(opts.ReportInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					printStats(checker.Stats())
+				}
+			}
+		}()
+	}
+
+	stream.WaitSig(ctx, cancel)
+	wg.Wait()
+
+	stats := checker.Stats()
+	printStats(stats)
+	if stats.Problems > 0 {
+		return fmt.Errorf("%d problems found in the event stream", stats.Problems)
+	}
+	return nil
+}
+
+func printStats(s Stats) {
+	fmt.Printf("%s %d events, %d blocks, %d missing blocks, %d missing events, %d problems\n",
+		time.Now().Format(time.RFC3339), s.Events, s.Blocks, s.MissingBlocks, s.MissingEvents, s.Problems)
+}
@@ -0,0 +1,37 @@
+package streamlatency
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// latencyMetrics are the Prometheus metrics exported with --metrics-listen, labelled by the address of each server.
+type latencyMetrics struct {
+	height *prometheus.GaugeVec
+	lag    *prometheus.GaugeVec
+	skew   *prometheus.HistogramVec
+}
+
+func newLatencyMetrics(registry *prometheus.Registry) *latencyMetrics {
+	buckets := make([]float64, 0, len(histogramBuckets))
+	for _, ms := range histogramBuckets {
+		buckets = append(buckets, float64(ms)/1000)
+	}
+
+	m := &latencyMetrics{
+		height: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "vega_streamlatency_block_height",
+			Help: "Height of the last END_BLOCK sent by the server",
+		}, []string{"endpoint"}),
+		lag: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "vega_streamlatency_block_height_lag",
+			Help: "Number of blocks the server is behind the highest block sent by any server",
+		}, []string{"endpoint"}),
+		skew: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "vega_streamlatency_skew_seconds",
+			Help:    "Time after the earliest server that the server sent the END_BLOCK of a block",
+			Buckets: buckets,
+		}, []string{"endpoint"}),
+	}
+	registry.MustRegister(m.height, m.lag, m.skew)
+	return m
+}
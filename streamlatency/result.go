@@ -0,0 +1,47 @@
+package streamlatency
+
+import "time"
+
+// BlockResult is written for each server for every block with --output json or csv. The percentiles are over the
+// window of recent blocks.
+type BlockResult struct {
+	Time     time.Time `json:"time"`
+	Height   uint64    `json:"height"`
+	Endpoint string    `json:"endpoint"`
+	SkewMs   int64     `json:"skew_ms"`
+	P50Ms    int64     `json:"p50_ms"`
+	P95Ms    int64     `json:"p95_ms"`
+	P99Ms    int64     `json:"p99_ms"`
+	MaxMs    int64     `json:"max_ms"`
+}
+
+// EndpointResult is written for each server on exit. The histogram is only written as JSON.
+type EndpointResult struct {
+	Endpoint  string            `json:"endpoint"`
+	Blocks    uint64            `json:"blocks"`
+	First     uint64            `json:"first"`
+	P50Ms     int64             `json:"p50_ms"`
+	P95Ms     int64             `json:"p95_ms"`
+	P99Ms     int64             `json:"p99_ms"`
+	MaxMs     int64             `json:"max_ms"`
+	Histogram []HistogramBucket `json:"histogram,omitempty"`
+}
+
+// HistogramBucket is the number of blocks with a skew of at most UpToMs, or above the last bucket if UpToMs is -1.
+type HistogramBucket struct {
+	UpToMs int64  `json:"up_to_ms"`
+	Blocks uint64 `json:"blocks"`
+}
+
+func (e *endpoint) result() EndpointResult {
+	p50, p95, p99, max := e.percentiles()
+	r := EndpointResult{Endpoint: e.addr, Blocks: e.blocks, First: e.first, P50Ms: p50, P95Ms: p95, P99Ms: p99, MaxMs: max}
+	for i, count := range e.histogram {
+		upTo := int64(-1)
+		if i < len(histogramBuckets) {
+			upTo = histogramBuckets[i]
+		}
+		r.Histogram = append(r.Histogram, HistogramBucket{UpToMs: upTo, Blocks: count})
+	}
+	return r
+}
@@ -2,94 +2,339 @@ package streamlatency
 
 import (
 	"context"
+	"encoding/csv"
 	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	eventspb "code.vegaprotocol.io/vega/protos/vega/events/v1"
 	"code.vegaprotocol.io/vega/vegatools/stream"
+	"code.vegaprotocol.io/vegatools/metrics"
+	"code.vegaprotocol.io/vegatools/output"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // Opts are the command line options passed to the sub command
 type Opts struct {
-	ServerAddr1 string
-	ServerAddr2 string
+	ServerAddrs []string
 	ReportMode  bool
+	// Window is the number of recent blocks the percentiles are calculated over
+	Window int
+	// Duration stops the command after this long, 0 runs until interrupted
+	Duration time.Duration
+	// CSVFile receives the skew of every endpoint for every block
+	CSVFile string
+	// MetricsListen is the address Prometheus metrics are served on, nothing is printed when it is set
+	MetricsListen string
+	// Output is the format results are written in, text, json or csv
+	Output string
 }
 
 const colorRed = "\033[0;31m"
 const colorGreen = "\033[0;32m"
+const colorReset = "\033[0m"
+
+// histogramBuckets are the upper bounds, in milliseconds, of the buckets of the latency histogram printed on exit.
+var histogramBuckets = []int64{0, 1, 2, 5, 10, 20, 50, 100, 200, 500, 1000, 2000, 5000}
+
+// endpoint holds the skew of a server relative to the earliest server for recent blocks.
+type endpoint struct {
+	addr      string
+	window    []int64
+	next      int
+	full      bool
+	histogram []uint64
+	blocks    uint64
+	first     uint64
+	height    uint64
+}
+
+func newEndpoint(addr string, window int) *endpoint {
+	return &endpoint{
+		addr:      addr,
+		window:    make([]int64, window),
+		histogram: make([]uint64, len(histogramBuckets)+1),
+	}
+}
+
+func (e *endpoint) add(skew int64) {
+	e.window[e.next] = skew
+	e.next = (e.next + 1) % len(e.window)
+	e.full = e.full || e.next == 0
+	e.blocks++
+	if skew == 0 {
+		e.first++
+	}
+
+	i := sort.Search(len(histogramBuckets), func(i int) bool { return histogramBuckets[i] >= skew })
+	e.histogram[i]++
+}
 
-// Run is the main function of `eventrate` package
+// percentiles returns the p50, p95, p99 and max skew over the window.
+func (e *endpoint) percentiles() (int64, int64, int64, int64) {
+	n := e.next
+	if e.full {
+		n = len(e.window)
+	}
+	if n == 0 {
+		return 0, 0, 0, 0
+	}
+
+	sorted := append([]int64{}, e.window[:n]...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	at := func(p float64) int64 {
+		return sorted[int(p*float64(n-1)+0.5)]
+	}
+	return at(0.50), at(0.95), at(0.99), sorted[n-1]
+}
+
+// tracker matches the END_BLOCK events of each server by height.
+type tracker struct {
+	mu        sync.Mutex
+	opts      Opts
+	endpoints []*endpoint
+	arrivals  map[uint64][]time.Time
+	highest   uint64
+	csv       *csv.Writer
+	metrics   *latencyMetrics
+	out       *output.Writer
+}
+
+// Run is the main function of `streamlatency` package
 func Run(opts Opts) error {
+	if len(opts.ServerAddrs) < 2 {
+		return fmt.Errorf("error: at least two grpc server addresses are required")
+	}
+	if opts.Window <= 0 {
+		return fmt.Errorf("error: window must be at least one block")
+	}
+
+	t := &tracker{opts: opts, arrivals: map[uint64][]time.Time{}}
+	for _, addr := range opts.ServerAddrs {
+		t.endpoints = append(t.endpoints, newEndpoint(addr, opts.Window))
+	}
+
+	out, err := output.NewWriter(os.Stdout, opts.Output)
+	if err != nil {
+		return err
+	}
+	t.out = out
+
+	if len(opts.MetricsListen) > 0 {
+		registry := prometheus.NewRegistry()
+		t.metrics = newLatencyMetrics(registry)
+		if err := metrics.Serve(opts.MetricsListen, registry); err != nil {
+			return err
+		}
+	}
+
+	if len(opts.CSVFile) > 0 {
+		f, err := os.Create(opts.CSVFile)
+		if err != nil {
+			return fmt.Errorf("unable to create file %s: %w", opts.CSVFile, err)
+		}
+		defer f.Close()
+		t.csv = csv.NewWriter(f)
+		defer t.csv.Flush()
+		if err := t.csv.Write(append([]string{"height"}, opts.ServerAddrs...)); err != nil {
+			return fmt.Errorf("failed to write to %s: %w", opts.CSVFile, err)
+		}
+	}
+
 	var (
-		// Allow up to 100 blocks of historic timings
-		serverTimings1 []int64 = make([]int64, 100)
-		serverTimings2 []int64 = make([]int64, 100)
-		lastHeight1    uint64
-		lastHeight2    uint64
-		mu             sync.Mutex
+		ctx    context.Context
+		cancel context.CancelFunc
 	)
+	if opts.Duration > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), opts.Duration)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	defer cancel()
+	wg := sync.WaitGroup{}
+
+	types := []string{"BUS_EVENT_TYPE_END_BLOCK"}
 
-	handleEvent1 := func(e *eventspb.BusEvent) {
-		mu.Lock()
-		switch e.Type {
-		case eventspb.BusEventType_BUS_EVENT_TYPE_END_BLOCK:
-			eb := e.GetEndBlock()
-			lastHeight1 = eb.Height
-			now := time.Now().UnixMilli()
-			offset := lastHeight1 % 100
-			// If we already have a non zero value in the other list we can compare
-			if serverTimings2[offset] != 0 {
-				fmt.Printf("%s%s is behind %s by %d milliseconds    \r", colorRed, opts.ServerAddr1, opts.ServerAddr2, now-serverTimings2[offset])
-				if opts.ReportMode {
-					fmt.Println()
-				}
-				serverTimings2[offset] = 0
-			} else {
-				serverTimings1[offset] = now
+	// Connect to every event stream and start processing the incoming events
+	for i, addr := range opts.ServerAddrs {
+		i := i
+		handleEvent := func(e *eventspb.BusEvent) {
+			if eb := e.GetEndBlock(); eb != nil {
+				t.arrived(i, eb.Height, time.Now())
 			}
 		}
-		mu.Unlock()
-	}
-
-	handleEvent2 := func(e *eventspb.BusEvent) {
-		mu.Lock()
-		switch e.Type {
-		case eventspb.BusEventType_BUS_EVENT_TYPE_END_BLOCK:
-			eb := e.GetEndBlock()
-			lastHeight2 = eb.Height
-			now := time.Now().UnixMilli()
-			offset := lastHeight2 % 100
-			// If we already have a non zero value in the other list we can compare
-			if serverTimings1[offset] != 0 {
-				fmt.Printf("%s%s is behind %s by %d milliseconds    \r", colorGreen, opts.ServerAddr2, opts.ServerAddr1, now-serverTimings1[offset])
-				if opts.ReportMode {
-					fmt.Println()
-				}
-				serverTimings1[offset] = 0
-			} else {
-				serverTimings2[offset] = now
+		if err := stream.ReadEvents(ctx, cancel, &wg, 0, "", "", addr, handleEvent, true, types); err != nil {
+			return fmt.Errorf("error reading events from %s: %v", addr, err)
+		}
+	}
+
+	stream.WaitSig(ctx, cancel)
+	wg.Wait()
+
+	if t.metrics != nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.out != nil {
+		for _, e := range t.endpoints {
+			if err := t.out.Write(e.result()); err != nil {
+				return fmt.Errorf("failed to write result: %w", err)
 			}
 		}
-		mu.Unlock()
+		return nil
 	}
+	fmt.Println()
+	t.printHistogram()
+	return nil
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-	wg := sync.WaitGroup{}
+// arrived records the time a server sent the END_BLOCK of a block. Once every server has sent it the skew of each
+// one relative to the earliest is recorded.
+func (t *tracker) arrived(server int, height uint64, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 
-	types := []string{"BUS_EVENT_TYPE_END_BLOCK"}
+	times, ok := t.arrivals[height]
+	if !ok {
+		times = make([]time.Time, len(t.endpoints))
+		t.arrivals[height] = times
+	}
+	times[server] = at
+	if height > t.highest {
+		t.highest = height
+		t.prune()
+	}
+	if height > t.endpoints[server].height {
+		t.endpoints[server].height = height
+	}
+	t.updateMetrics(server)
+
+	earliest := times[0]
+	for _, a := range times {
+		if a.IsZero() {
+			return
+		}
+		if a.Before(earliest) {
+			earliest = a
+		}
+	}
+	delete(t.arrivals, height)
+
+	skews := make([]int64, len(times))
+	for i, a := range times {
+		skews[i] = a.Sub(earliest).Milliseconds()
+		t.endpoints[i].add(skews[i])
+		if t.metrics != nil {
+			t.metrics.skew.WithLabelValues(t.endpoints[i].addr).Observe(float64(skews[i]) / 1000)
+		}
+	}
+	t.printBlock(height, skews)
+	t.writeCSV(height, skews)
+}
+
+// prune drops blocks that a server has not sent within the window, e.g. because it is down or has fallen behind.
+func (t *tracker) prune() {
+	for height := range t.arrivals {
+		if height+uint64(t.opts.Window) < t.highest {
+			delete(t.arrivals, height)
+		}
+	}
+}
+
+// updateMetrics sets the height of server and the lag of every server behind the highest block seen.
+func (t *tracker) updateMetrics(server int) {
+	if t.metrics == nil {
+		return
+	}
+	t.metrics.height.WithLabelValues(t.endpoints[server].addr).Set(float64(t.endpoints[server].height))
+	for _, e := range t.endpoints {
+		if e.height > 0 {
+			t.metrics.lag.WithLabelValues(e.addr).Set(float64(t.highest - e.height))
+		}
+	}
+}
 
-	// Connect to the 2 event streams and start processing the incoming events
-	if err := stream.ReadEvents(ctx, cancel, &wg, 0, "", "", opts.ServerAddr1, handleEvent1, true, types); err != nil {
-		return fmt.Errorf("error reading events from stream 1: %v", err)
+func (t *tracker) printBlock(height uint64, skews []int64) {
+	if t.metrics != nil {
+		return
+	}
+	if t.out != nil {
+		t.writeBlock(height, skews)
+		return
+	}
+	end := "\r"
+	if t.opts.ReportMode {
+		end = "\n"
 	}
 
-	if err := stream.ReadEvents(ctx, cancel, &wg, 0, "", "", opts.ServerAddr2, handleEvent2, true, types); err != nil {
-		return fmt.Errorf("error reading events from stream 2: %v", err)
+	fmt.Printf("block %d", height)
+	for i, e := range t.endpoints {
+		p50, p95, p99, max := e.percentiles()
+		colour := colorRed
+		if skews[i] == 0 {
+			colour = colorGreen
+		}
+		fmt.Printf(" | %s%s +%dms%s p50:%d p95:%d p99:%d max:%d", colour, e.addr, skews[i], colorReset, p50, p95, p99, max)
 	}
+	fmt.Print("    " + end)
+}
 
-	for {
+func (t *tracker) writeBlock(height uint64, skews []int64) {
+	now := time.Now()
+	for i, e := range t.endpoints {
+		p50, p95, p99, max := e.percentiles()
+		result := BlockResult{
+			Time:     now,
+			Height:   height,
+			Endpoint: e.addr,
+			SkewMs:   skews[i],
+			P50Ms:    p50,
+			P95Ms:    p95,
+			P99Ms:    p99,
+			MaxMs:    max,
+		}
+		if err := t.out.Write(result); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write result: %v\n", err)
+			return
+		}
+	}
+}
+
+func (t *tracker) writeCSV(height uint64, skews []int64) {
+	if t.csv == nil {
+		return
+	}
+	record := make([]string, 0, len(skews)+1)
+	record = append(record, strconv.FormatUint(height, 10))
+	for _, s := range skews {
+		record = append(record, strconv.FormatInt(s, 10))
+	}
+	if err := t.csv.Write(record); err != nil {
+		fmt.Printf("failed to write to %s: %v\n", t.opts.CSVFile, err)
+	}
+}
+
+func (t *tracker) printHistogram() {
+	for _, e := range t.endpoints {
+		p50, p95, p99, max := e.percentiles()
+		fmt.Printf("%s: %d blocks, first for %d, window p50:%dms p95:%dms p99:%dms max:%dms\n",
+			e.addr, e.blocks, e.first, p50, p95, p99, max)
+		if e.blocks == 0 {
+			continue
+		}
+
+		for i, count := range e.histogram {
+			label := fmt.Sprintf(">%dms", histogramBuckets[len(histogramBuckets)-1])
+			if i < len(histogramBuckets) {
+				label = fmt.Sprintf("<=%dms", histogramBuckets[i])
+			}
+			fmt.Printf("  %8s %8d %s\n", label, count, strings.Repeat("#", int(count*50/e.blocks)))
+		}
 	}
 }
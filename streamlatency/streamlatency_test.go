@@ -0,0 +1,94 @@
+package streamlatency
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"code.vegaprotocol.io/vegatools/output"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTracker(t *testing.T, window int, addrs ...string) (*tracker, *bytes.Buffer, *bytes.Buffer) {
+	t.Helper()
+	records, csvFile := &bytes.Buffer{}, &bytes.Buffer{}
+	out, err := output.NewWriter(records, output.FormatCSV)
+	require.NoError(t, err)
+
+	tr := &tracker{opts: Opts{ServerAddrs: addrs, Window: window}, arrivals: map[uint64][]time.Time{}, out: out, csv: csv.NewWriter(csvFile)}
+	for _, addr := range addrs {
+		tr.endpoints = append(tr.endpoints, newEndpoint(addr, window))
+	}
+	return tr, records, csvFile
+}
+
+func TestSkewOfEveryEndpoint(t *testing.T) {
+	tr, _, csvFile := newTestTracker(t, 10, "a", "b", "c")
+	start := time.Unix(100, 0)
+
+	tr.arrived(1, 7, start.Add(5*time.Millisecond))
+	tr.arrived(0, 7, start.Add(20*time.Millisecond))
+	assert.Len(t, tr.arrivals, 1, "the skews are only known once every server sent the block")
+	tr.arrived(2, 7, start)
+	assert.Empty(t, tr.arrivals)
+
+	tr.csv.Flush()
+	assert.Equal(t, "7,20,5,0\n", csvFile.String())
+	assert.Equal(t, uint64(1), tr.endpoints[2].first)
+	assert.Equal(t, uint64(0), tr.endpoints[0].first)
+	for _, e := range tr.endpoints {
+		assert.Equal(t, uint64(1), e.blocks)
+		assert.Equal(t, uint64(7), e.height)
+	}
+}
+
+func TestBlocksMissedByAServerArePruned(t *testing.T) {
+	tr, _, _ := newTestTracker(t, 2, "a", "b")
+	start := time.Unix(100, 0)
+
+	// b never sends block 1
+	tr.arrived(0, 1, start)
+	for h := uint64(2); h <= 4; h++ {
+		tr.arrived(0, h, start)
+		tr.arrived(1, h, start)
+	}
+	assert.Empty(t, tr.arrivals)
+	assert.Equal(t, uint64(3), tr.endpoints[1].blocks)
+}
+
+func TestPercentilesOverTheWindow(t *testing.T) {
+	e := newEndpoint("a", 4)
+	p50, p95, p99, max := e.percentiles()
+	assert.Equal(t, []int64{0, 0, 0, 0}, []int64{p50, p95, p99, max})
+
+	for _, skew := range []int64{1000, 4, 1, 3, 2} {
+		e.add(skew)
+	}
+	// the oldest skew has left the window
+	p50, p95, p99, max = e.percentiles()
+	assert.Equal(t, []int64{3, 4, 4, 4}, []int64{p50, p95, p99, max})
+
+	r := e.result()
+	assert.Equal(t, uint64(5), r.Blocks)
+	assert.Len(t, r.Histogram, len(histogramBuckets)+1)
+	assert.Equal(t, HistogramBucket{UpToMs: 1, Blocks: 1}, r.Histogram[1])
+	assert.Equal(t, HistogramBucket{UpToMs: 5, Blocks: 2}, r.Histogram[3])
+	assert.Equal(t, HistogramBucket{UpToMs: 1000, Blocks: 1}, r.Histogram[10])
+	assert.Equal(t, HistogramBucket{UpToMs: -1, Blocks: 0}, r.Histogram[len(histogramBuckets)])
+}
+
+func TestBlockRecords(t *testing.T) {
+	tr, records, _ := newTestTracker(t, 10, "a", "b")
+	start := time.Unix(100, 0)
+	tr.arrived(0, 3, start.Add(2*time.Millisecond))
+	tr.arrived(1, 3, start)
+
+	lines := bytes.Split(bytes.TrimSpace(records.Bytes()), []byte("\n"))
+	require.Len(t, lines, 3)
+	assert.Equal(t, "time,height,endpoint,skew_ms,p50_ms,p95_ms,p99_ms,max_ms", string(lines[0]))
+	assert.Contains(t, string(lines[1]), ",3,a,2,2,2,2,2")
+	assert.Contains(t, string(lines[2]), ",3,b,0,0,0,0,0")
+}